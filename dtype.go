@@ -17,26 +17,36 @@ type DType struct {
 }
 
 /*
- * // Custom-made Float16 as not exist in Go
- * // Ref: https://github.com/golang/go/issues/32022
- * type GoFloat16 = int16 // not implemented yet
  * type GoComplexHalf = interface{} // not implemented yet!
  *  */
 
+// GoFloat16 holds the raw bits of an IEEE 754 half-precision value. Go has no
+// native float16 type, values of Half tensors should be read after conversion
+// to Float.
+// Ref: https://github.com/golang/go/issues/32022
+type GoFloat16 uint16
+
+// GoBFloat16 holds the raw bits of a bfloat16 (brain floating point) value:
+// the upper 16 bits of a float32. Go has no native bfloat16 type, values of
+// BFloat16 tensors should be read after conversion to Float.
+type GoBFloat16 uint16
+
 // TODO: double check these Torch DType to Go type
 var (
-	Uint8 DType = DType{reflect.TypeOf(uint8(1))} // 0
-	Int8  DType = DType{reflect.TypeOf(int8(1))}  // 1
-	Int16 DType = DType{reflect.TypeOf(int16(1))} // 2
-	Int   DType = DType{reflect.TypeOf(int32(1))} // 3
-	Int64 DType = DType{reflect.TypeOf(int64(1))} // 4
-	// Half       DType   = DType{reflect.TypeOf(GoFloat16(1))}     // 5
-	Float  DType = DType{reflect.TypeOf(float32(1))} // 6
-	Double DType = DType{reflect.TypeOf(float64(1))} // 7
+	Uint8  DType = DType{reflect.TypeOf(uint8(1))}     // 0
+	Int8   DType = DType{reflect.TypeOf(int8(1))}      // 1
+	Int16  DType = DType{reflect.TypeOf(int16(1))}     // 2
+	Int    DType = DType{reflect.TypeOf(int32(1))}     // 3
+	Int64  DType = DType{reflect.TypeOf(int64(1))}     // 4
+	Half   DType = DType{reflect.TypeOf(GoFloat16(1))} // 5
+	Float  DType = DType{reflect.TypeOf(float32(1))}   // 6
+	Double DType = DType{reflect.TypeOf(float64(1))}   // 7
 	// ComplexHalf DType  = DType{reflect.TypeOf(GoComplexHalf(1))} // 8
 	// ComplexFloat DType  = DType{reflect.TypeOf(complex64(1))}  // 9
 	// ComplexDouble DType = DType{reflect.TypeOf(complex128(1))} // 10
 	Bool DType = DType{reflect.TypeOf(true)} // 11
+	// QInt8 ... QInt32 // 12-14
+	BFloat16 DType = DType{reflect.TypeOf(GoBFloat16(1))} // 15
 )
 
 var dtypeGoType = map[DType]reflect.Type{
@@ -48,6 +58,9 @@ var dtypeGoType = map[DType]reflect.Type{
 	Float:  reflect.TypeOf(float32(1)),
 	Double: reflect.TypeOf(float64(1)),
 	Bool:   reflect.TypeOf(true),
+
+	Half:     reflect.TypeOf(GoFloat16(1)),
+	BFloat16: reflect.TypeOf(GoBFloat16(1)),
 }
 
 // ToDType infers and returns supported equivalent DType from given Go type
@@ -90,6 +103,9 @@ var dtypeCInt = map[DType]CInt{
 	Float:  6,
 	Double: 7,
 	Bool:   11,
+
+	Half:     5,
+	BFloat16: 15,
 }
 
 func DType2CInt(dt DType) (retVal CInt, err error) {
@@ -140,6 +156,9 @@ var dtypeSize = map[DType]uint{
 	Float:  4,
 	Double: 8,
 	Bool:   1,
+
+	Half:     2,
+	BFloat16: 2,
 }
 
 // DTypeSize returns DType size in Bytes
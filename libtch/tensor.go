@@ -19,6 +19,7 @@ type Cscalar = C.scalar
 type Coptimizer = C.optimizer
 type Civalue = C.ivalue
 type Cmodule = C.module
+type Cgenerator = C.generator
 
 type NamedCtensor struct {
 	Name    string
@@ -35,6 +36,48 @@ func AtNewTensor() Ctensor {
 	return C.at_new_tensor()
 }
 
+// void at_manual_seed(int64_t);
+func AtManualSeed(seed int64) {
+	cseed := *(*C.int64_t)(unsafe.Pointer(&seed))
+	C.at_manual_seed(cseed)
+}
+
+// generator at_generator_new(int64_t seed);
+func AtGeneratorNew(seed int64) Cgenerator {
+	cseed := *(*C.int64_t)(unsafe.Pointer(&seed))
+	return C.at_generator_new(cseed)
+}
+
+// void at_generator_manual_seed(generator, int64_t seed);
+func AtGeneratorManualSeed(g Cgenerator, seed int64) {
+	cseed := *(*C.int64_t)(unsafe.Pointer(&seed))
+	C.at_generator_manual_seed(g, cseed)
+}
+
+// void at_generator_free(generator);
+func AtGeneratorFree(g Cgenerator) {
+	C.at_generator_free(g)
+}
+
+// tensor at_randn_generator(int64_t *size_data, int size_len, generator, int kind, int device);
+func AtRandnGenerator(sizeData []int64, sizeLen int, g Cgenerator, kind int32, device int32) Ctensor {
+	csizeDataPtr := (*C.int64_t)(unsafe.Pointer(&sizeData[0]))
+	csizeLen := *(*C.int)(unsafe.Pointer(&sizeLen))
+	ckind := *(*C.int)(unsafe.Pointer(&kind))
+	cdevice := *(*C.int)(unsafe.Pointer(&device))
+	return C.at_randn_generator(csizeDataPtr, csizeLen, g, ckind, cdevice)
+}
+
+// tensor at_bernoulli_generator(int64_t *size_data, int size_len, double p, generator, int kind, int device);
+func AtBernoulliGenerator(sizeData []int64, sizeLen int, p float64, g Cgenerator, kind int32, device int32) Ctensor {
+	csizeDataPtr := (*C.int64_t)(unsafe.Pointer(&sizeData[0]))
+	csizeLen := *(*C.int)(unsafe.Pointer(&sizeLen))
+	cp := *(*C.double)(unsafe.Pointer(&p))
+	ckind := *(*C.int)(unsafe.Pointer(&kind))
+	cdevice := *(*C.int)(unsafe.Pointer(&device))
+	return C.at_bernoulli_generator(csizeDataPtr, csizeLen, cp, g, ckind, cdevice)
+}
+
 // tensor at_new_tensor();
 func NewTensor() Ctensor {
 	return C.at_new_tensor()
@@ -151,6 +194,12 @@ func AtIsSparse(ts Ctensor) bool {
 	return *(*bool)(unsafe.Pointer(&retVal))
 }
 
+// int at_is_pinned(tensor);
+func AtIsPinned(ts Ctensor) bool {
+	retVal := C.at_is_pinned(ts)
+	return *(*bool)(unsafe.Pointer(&retVal))
+}
+
 // void at_backward(tensor, int, int);
 func AtBackward(ts Ctensor, keepGraph int, createGraph int) {
 	ckeepGraph := *(*C.int)(unsafe.Pointer(&keepGraph))
@@ -199,6 +248,11 @@ func AtCopy_(dst Ctensor, src Ctensor) {
 	C.at_copy_(dst, src)
 }
 
+// void at_set_data(tensor t, tensor data);
+func AtSetData(ts Ctensor, data Ctensor) {
+	C.at_set_data(ts, data)
+}
+
 // void at_save(tensor, char *filename);
 func AtSave(ts Ctensor, path string) {
 	cstringPtr := C.CString(path)
@@ -235,6 +289,29 @@ func AtSaveMulti(tensors []Ctensor, tensor_names []string, ntensors int, filenam
 	C.at_save_multi(&ctensors[0], cnamesPtr[0], cntensors, cfilename)
 }
 
+// void at_save_pickle(tensor *tensors, char **tensor_names, int ntensors, char *filename);
+func AtSavePickle(tensors []Ctensor, names []string, filename string) {
+	ntensors := len(tensors)
+	ctensors := make([]C.tensor, ntensors)
+	cnames := make([]*C.char, ntensors)
+	for i := 0; i < ntensors; i++ {
+		ctensors[i] = (C.tensor)(tensors[i])
+		cnames[i] = C.CString(names[i])
+		defer C.free(unsafe.Pointer(cnames[i]))
+	}
+	var (
+		ctensorsPtr *C.tensor
+		cnamesPtr   **C.char
+	)
+	if ntensors > 0 {
+		ctensorsPtr, cnamesPtr = &ctensors[0], &cnames[0]
+	}
+	cfilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cfilename))
+
+	C.at_save_pickle(ctensorsPtr, cnamesPtr, C.int(ntensors), cfilename)
+}
+
 // void at_save_multi(tensor *tensors, char **tensor_names, int ntensors, char *filename);
 func AtSaveMultiNew(tensors []Ctensor, names []string, filename string) {
 	// NOTE. namedTensors is slice of tensors which wrap Ctensor pointer.
@@ -338,6 +415,17 @@ func AtToString(ts Ctensor, lineSize int64) string {
 	return goString
 }
 
+// char *at_autograd_graph(tensor);
+func AtAutogradGraph(ts Ctensor) string {
+	charPtr := C.at_autograd_graph(ts)
+	if charPtr == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(charPtr))
+
+	return C.GoString(charPtr)
+}
+
 // void at_free(tensor);
 func AtFree(ts Ctensor) {
 	C.at_free(ts)
@@ -401,6 +489,21 @@ func AtoSgd(learningRate, momentum, dampening, weightDecay float64, nesterov int
 	return C.ato_sgd(clearningRate, cmomentum, cdampening, cweightDecay, cnesterov)
 }
 
+/*
+ * optimizer ato_adagrad(double learning_rate,
+ *                       double lr_decay,
+ *                       double weight_decay,
+ *                       double eps);
+ *  */
+func AtoAdagrad(learningRate, lrDecay, weightDecay, eps float64) Coptimizer {
+	clearningRate := *(*C.double)(unsafe.Pointer(&learningRate))
+	clrDecay := *(*C.double)(unsafe.Pointer(&lrDecay))
+	cweightDecay := *(*C.double)(unsafe.Pointer(&weightDecay))
+	ceps := *(*C.double)(unsafe.Pointer(&eps))
+
+	return C.ato_adagrad(clearningRate, clrDecay, cweightDecay, ceps)
+}
+
 // NOTE. Backward compat for param group not updated (#261)
 // void ato_add_parameters(optimizer, tensor *, int ntensors);
 func AtoAddParametersOld(coptimizer Coptimizer, tensors []Ctensor, ntensors int) {
@@ -416,20 +519,11 @@ func AtoAddParametersOld(coptimizer Coptimizer, tensors []Ctensor, ntensors int)
 	C.ato_add_parameters_old(coptimizer, &ctensors[0], cntensors)
 }
 
-// NOTE. This function is not working correctly. Need to update!!!
-// DO NOT USE!!!!!
-// TODO. updated
-func AtoAddParameters(coptimizer Coptimizer, tensors []Ctensor, ntensors int) {
-
-	var ctensors []C.tensor
-	for i := 0; i < len(tensors); i++ {
-		ctensors = append(ctensors, (C.tensor)(tensors[i]))
-	}
+// void ato_add_parameters(optimizer, tensor, size_t group);
+func AtoAddParameters(coptimizer Coptimizer, tensor Ctensor, group uint) {
+	cgroup := *(*C.size_t)(unsafe.Pointer(&group))
 
-	cntensors := *(*C.size_t)(unsafe.Pointer(&ntensors))
-
-	// Just give pointer to the first element of ctensors slice
-	C.ato_add_parameters(coptimizer, ctensors[0], cntensors)
+	C.ato_add_parameters(coptimizer, tensor, cgroup)
 }
 
 // void ato_set_learning_rate(optimizer, double learning_rate);
@@ -438,6 +532,14 @@ func AtoSetLearningRate(coptimizer Coptimizer, learningRate float64) {
 	C.ato_set_learning_rate(coptimizer, clearningRate)
 }
 
+// void ato_set_learning_rate_group(optimizer, size_t group, double learning_rate);
+func AtoSetLearningRateGroup(coptimizer Coptimizer, group uint, learningRate float64) {
+	cgroup := *(*C.size_t)(unsafe.Pointer(&group))
+	clearningRate := *(*C.double)(unsafe.Pointer(&learningRate))
+
+	C.ato_set_learning_rate_group(coptimizer, cgroup, clearningRate)
+}
+
 // void ato_set_momentum(optimizer, double momentum);
 func AtoSetMomentum(coptimizer Coptimizer, momentum float64) {
 	cmomentum := *(*C.double)(unsafe.Pointer(&momentum))
@@ -457,6 +559,20 @@ func AtoStep(coptimizer Coptimizer) {
 	C.ato_step(coptimizer)
 }
 
+// void ato_save(optimizer, char *filename);
+func AtoSave(coptimizer Coptimizer, path string) {
+	cstringPtr := C.CString(path)
+	defer C.free(unsafe.Pointer(cstringPtr))
+	C.ato_save(coptimizer, cstringPtr)
+}
+
+// void ato_load(optimizer, char *filename);
+func AtoLoad(coptimizer Coptimizer, path string) {
+	cstringPtr := C.CString(path)
+	defer C.free(unsafe.Pointer(cstringPtr))
+	C.ato_load(coptimizer, cstringPtr)
+}
+
 // void ato_free(optimizer);
 func AtoFree(coptimizer Coptimizer) {
 	C.ato_free(coptimizer)
@@ -3,8 +3,12 @@
 #include<torch/torch.h>
 #include<ATen/autocast_mode.h>
 #include<torch/script.h>
+#include<torch/csrc/jit/serialization/pickle.h>
 #include<stdexcept>
 #include<vector>
+#include<unordered_map>
+#include<fstream>
+#include<ATen/CPUGeneratorImpl.h>
 #include "torch_api.h"
 
 #define STB_IMAGE_IMPLEMENTATION
@@ -38,6 +42,45 @@ at::Device device_of_int(int d) {
     if (d < 0) return at::Device(at::kCPU);
     return at::Device(at::kCUDA, /*index=*/d);
 }
+generator at_generator_new(int64_t seed) {
+  PROTECT(
+    return new at::Generator(at::detail::createCPUGenerator(seed));
+  )
+  return nullptr;
+}
+
+void at_generator_manual_seed(generator g, int64_t seed) {
+  PROTECT(
+    std::lock_guard<std::mutex> lock(g->mutex());
+    g->set_current_seed(seed);
+  )
+}
+
+void at_generator_free(generator g) {
+  delete(g);
+}
+
+tensor at_randn_generator(int64_t *size_data, int size_len, generator g,
+                          int kind, int device) {
+  PROTECT(
+    auto t = torch::randn(torch::IntArrayRef(size_data, size_len), *g,
+                          at::device(at::kCPU).dtype(at::ScalarType(kind)));
+    return new torch::Tensor(t.to(device_of_int(device)));
+  )
+  return nullptr;
+}
+
+tensor at_bernoulli_generator(int64_t *size_data, int size_len, double p,
+                              generator g, int kind, int device) {
+  PROTECT(
+    auto t = torch::empty(torch::IntArrayRef(size_data, size_len),
+                          at::device(at::kCPU).dtype(at::ScalarType(kind)));
+    t.bernoulli_(p, *g);
+    return new torch::Tensor(t.to(device_of_int(device)));
+  )
+  return nullptr;
+}
+
 tensor at_new_tensor() {
   PROTECT(
     return new torch::Tensor();
@@ -111,6 +154,11 @@ int at_is_sparse(tensor t) {
   return -1;
 }
 
+int at_is_pinned(tensor t) {
+  PROTECT(return t->is_pinned();)
+  return -1;
+}
+
 size_t at_dim(tensor t) {
   PROTECT(return t->dim();)
   return -1;
@@ -272,12 +320,53 @@ char *at_to_string(tensor t, int line_size) {
   return nullptr;
 }
 
+char *at_autograd_graph(tensor t) {
+  PROTECT(
+    std::ostringstream oss;
+    std::unordered_map<torch::autograd::Node*, int64_t> ids;
+    std::vector<torch::autograd::Node*> stack;
+    auto root = t->grad_fn();
+    if (root) {
+      ids[root.get()] = 0;
+      oss << "N 0 " << root->name() << "\n";
+      stack.push_back(root.get());
+    }
+    while (!stack.empty()) {
+      auto node = stack.back();
+      stack.pop_back();
+      for (auto &edge : node->next_edges()) {
+        auto next = edge.function.get();
+        if (!next) continue;
+        int64_t id;
+        auto it = ids.find(next);
+        if (it == ids.end()) {
+          id = ids.size();
+          ids[next] = id;
+          oss << "N " << id << " " << next->name() << "\n";
+          stack.push_back(next);
+        } else {
+          id = it->second;
+        }
+        oss << "E " << id << " " << ids[node] << "\n";
+      }
+    }
+    return strdup(oss.str().c_str());
+  )
+  return nullptr;
+}
+
 void at_copy_(tensor dst, tensor src) {
   PROTECT(
     dst->copy_(*src);
   )
 }
 
+void at_set_data(tensor t, tensor data) {
+  PROTECT(
+    t->set_data(*data);
+  )
+}
+
 void at_save(tensor t, char *filename) {
   PROTECT(torch::save(*t, filename);)
 }
@@ -291,6 +380,18 @@ void at_save_multi(tensor *tensors, char **tensor_names, int ntensors, char *fil
   )
 }
 
+void at_save_pickle(tensor *tensors, char **tensor_names, int ntensors, char *filename) {
+  PROTECT(
+    c10::Dict<std::string, torch::Tensor> dict;
+    for (int i = 0; i < ntensors; ++i)
+      dict.insert(std::string(tensor_names[i]), *(tensors[i]));
+    std::vector<char> data = torch::jit::pickle_save(c10::IValue(dict));
+    std::ofstream fout(filename, std::ios::out | std::ios::binary);
+    fout.write(data.data(), data.size());
+    fout.close();
+  )
+}
+
 void at_load_multi(tensor *tensors, char **tensor_names, int ntensors, char *filename) {
   PROTECT(
     torch::serialize::InputArchive archive;
@@ -530,6 +631,21 @@ optimizer ato_sgd(double learning_rate,
   return nullptr;
 }
 
+optimizer ato_adagrad(double learning_rate,
+                      double lr_decay,
+                      double weight_decay,
+                      double eps) {
+  PROTECT(
+    auto options =
+      torch::optim::AdagradOptions(learning_rate)
+      .lr_decay(lr_decay)
+      .weight_decay(weight_decay)
+      .eps(eps);
+    return new torch::optim::Adagrad(vector<torch::Tensor>(), options);
+  )
+  return nullptr;
+}
+
 // NOTE. backward compat as param group (#261) not updated yet.
 void ato_add_parameters_old(optimizer t, tensor *tensors, int ntensors) {
   PROTECT(
@@ -569,6 +685,7 @@ void ato_set_learning_rate(optimizer t, double learning_rate) {
     set_lr<torch::optim::AdamWOptions>(t, learning_rate);
     set_lr<torch::optim::RMSpropOptions>(t, learning_rate);
     set_lr<torch::optim::SGDOptions>(t, learning_rate);
+    set_lr<torch::optim::AdagradOptions>(t, learning_rate);
   )
 }
 
@@ -587,6 +704,7 @@ void ato_set_learning_rate_group(optimizer t, size_t group, double learning_rate
     set_lr_group<torch::optim::AdamWOptions>(t, group, learning_rate);
     set_lr_group<torch::optim::RMSpropOptions>(t, group, learning_rate);
     set_lr_group<torch::optim::SGDOptions>(t, group, learning_rate);
+    set_lr_group<torch::optim::AdagradOptions>(t, group, learning_rate);
   )
 }
 
@@ -684,6 +802,7 @@ void ato_set_weight_decay(optimizer t, double weight_decay) {
     set_weight_decay<torch::optim::AdamWOptions>(t, weight_decay);
     set_weight_decay<torch::optim::RMSpropOptions>(t, weight_decay);
     set_weight_decay<torch::optim::SGDOptions>(t, weight_decay);
+    set_weight_decay<torch::optim::AdagradOptions>(t, weight_decay);
   )
 }
 
@@ -702,6 +821,7 @@ void ato_set_weight_decay_group(optimizer t, size_t group, double weight_decay)
     set_weight_decay_group<torch::optim::AdamWOptions>(t, group, weight_decay);
     set_weight_decay_group<torch::optim::RMSpropOptions>(t, group, weight_decay);
     set_weight_decay_group<torch::optim::SGDOptions>(t, group, weight_decay);
+    set_weight_decay_group<torch::optim::AdagradOptions>(t, group, weight_decay);
   )
 }
 
@@ -713,6 +833,14 @@ void ato_step(optimizer t) {
   PROTECT(t->step();)
 }
 
+void ato_save(optimizer t, char *filename) {
+  PROTECT(torch::save(*t, filename);)
+}
+
+void ato_load(optimizer t, char *filename) {
+  PROTECT(torch::load(*t, filename);)
+}
+
 void ato_free(optimizer t) {
   delete(t);
 }
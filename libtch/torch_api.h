@@ -11,6 +11,7 @@ typedef torch::Scalar *scalar;
 typedef torch::optim::Optimizer *optimizer;
 typedef torch::jit::script::Module *module;
 typedef torch::jit::IValue *ivalue;
+typedef at::Generator *generator;
 #define PROTECT(x)                                                             \
   try {                                                                        \
     x                                                                          \
@@ -23,10 +24,21 @@ typedef void *optimizer;
 typedef void *scalar;
 typedef void *module;
 typedef void *ivalue;
+typedef void *generator;
 #endif
 
 char *get_and_reset_last_err(); // thread-local
 void at_manual_seed(int64_t);
+
+// CPU random number generators. Random tensors are sampled on CPU then moved
+// to the target device so that a seed gives the same values on any device.
+generator at_generator_new(int64_t seed);
+void at_generator_manual_seed(generator, int64_t seed);
+void at_generator_free(generator);
+tensor at_randn_generator(int64_t *size_data, int size_len, generator,
+                          int kind, int device);
+tensor at_bernoulli_generator(int64_t *size_data, int size_len, double p,
+                              generator, int kind, int device);
 tensor at_new_tensor();
 tensor at_tensor_of_blob(void *data, int64_t *dims, size_t ndims,
                          int64_t *strides, size_t nstrides, int type,
@@ -41,6 +53,7 @@ void *at_data_ptr(tensor);
 int at_defined(tensor);
 int at_is_mkldnn(tensor);
 int at_is_sparse(tensor);
+int at_is_pinned(tensor);
 int at_device(tensor);
 size_t at_dim(tensor);
 void at_shape(tensor, int64_t *);
@@ -71,9 +84,13 @@ void at_set_int64_value_at_indexes(tensor, int *indexes, int indexes_len,
                                    int64_t v);
 
 void at_copy_(tensor dst, tensor src);
+void at_set_data(tensor t, tensor data);
 
 void at_print(tensor);
 char *at_to_string(tensor, int line_size);
+// Returns the autograd graph of a tensor: one "N <id> <name>" line per node and
+// one "E <from> <to>" line per edge, from an input node to its consumer.
+char *at_autograd_graph(tensor);
 void at_save(tensor, char *filename);
 tensor at_load(char *filename);
 tensor at_load_image(char *filename);
@@ -82,6 +99,10 @@ tensor at_resize_image(tensor, int w, int h);
 
 void at_save_multi(tensor *tensors, char **tensor_names, int ntensors,
                    char *filename);
+/* [at_save_pickle] saves named tensors as a dictionary readable by
+ * torch.load in Python. */
+void at_save_pickle(tensor *tensors, char **tensor_names, int ntensors,
+                    char *filename);
 /* [at_load_multi] takes as input an array of nullptr for [tensors]. */
 void at_load_multi(tensor *tensors, char **tensor_names, int ntensors,
                    char *filename);
@@ -116,6 +137,8 @@ optimizer ato_rms_prop(double learning_rate, double alpha, double eps,
                        double weight_decay, double momentum, int centered);
 optimizer ato_sgd(double learning_rate, double momentum, double dampening,
                   double weight_decay, int nesterov);
+optimizer ato_adagrad(double learning_rate, double lr_decay,
+                      double weight_decay, double eps);
 // NOTE. switch back as param group #261 not updated yet.
 // Backward compat
 void ato_add_parameters_old(optimizer, tensor *, int ntensors);
@@ -128,6 +151,8 @@ void ato_set_weight_decay(optimizer t, double weight_decay);
 void ato_set_weight_decay_group(optimizer t, size_t group, double weight_decay);
 void ato_zero_grad(optimizer);
 void ato_step(optimizer);
+void ato_save(optimizer, char *filename);
+void ato_load(optimizer, char *filename);
 void ato_free(optimizer);
 
 scalar ats_int(int64_t);
@@ -0,0 +1,76 @@
+package nn
+
+// Export of attention alignments for visualization.
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// alignmentCellSize is the size in pixels of an alignment cell in PNG
+// heatmaps.
+const alignmentCellSize = 8
+
+// SaveAlignment writes an attention alignment matrix of shape [tgt_len,
+// src_len], e.g. the weights of an `AttnDecoderStep` stacked over decoding
+// steps, to path.
+//
+// The format depends on the path extension: ".csv" writes one row per target
+// position with comma-separated weights, ".png" writes a grayscale heatmap
+// where brighter cells have higher weights (scaled to the maximum weight).
+func SaveAlignment(weights *ts.Tensor, path string) error {
+	size := weights.MustSize()
+	if len(size) != 2 {
+		return fmt.Errorf("SaveAlignment - Expected a [tgt_len, src_len] matrix, got shape %v\n", size)
+	}
+	tgtLen, srcLen := int(size[0]), int(size[1])
+	values := weights.Float64Values()
+
+	var buf bytes.Buffer
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		for i := 0; i < tgtLen; i++ {
+			row := make([]string, srcLen)
+			for j := range row {
+				row[j] = strconv.FormatFloat(values[i*srcLen+j], 'g', -1, 64)
+			}
+			buf.WriteString(strings.Join(row, ","))
+			buf.WriteString("\n")
+		}
+	case ".png":
+		max := 0.0
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+
+		img := image.NewGray(image.Rect(0, 0, srcLen*alignmentCellSize, tgtLen*alignmentCellSize))
+		for y := 0; y < tgtLen*alignmentCellSize; y++ {
+			for x := 0; x < srcLen*alignmentCellSize; x++ {
+				v := values[(y/alignmentCellSize)*srcLen+x/alignmentCellSize]
+				var level uint8
+				if max > 0 && v > 0 {
+					level = uint8(255 * v / max)
+				}
+				img.SetGray(x, y, color.Gray{Y: level})
+			}
+		}
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("SaveAlignment - Unsupported file extension %q. Expected \".csv\" or \".png\"\n", ext)
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,75 @@
+package nn_test
+
+import (
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestSaveAlignment(t *testing.T) {
+	// Diagonal (monotonic) alignment over 4 target and 4 source positions.
+	weights := ts.MustEye(4, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(0.85), true).MustAdd1(ts.FloatScalar(0.05), true)
+
+	dir, err := ioutil.TempDir("", "gotch-alignment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csvPath := filepath.Join(dir, "alignment.csv")
+	if err := nn.SaveAlignment(weights, csvPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(rows) != 4 {
+		t.Fatalf("Expected 4 rows, got %v\n", len(rows))
+	}
+	for i, row := range rows {
+		cells := strings.Split(row, ",")
+		if len(cells) != 4 {
+			t.Fatalf("Row %v - Expected 4 columns, got %v\n", i, len(cells))
+		}
+		for j, cell := range cells {
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == j && v < 0.89 || i != j && v > 0.06 {
+				t.Errorf("Expected high weights on the diagonal only, got %v at (%v, %v)\n", v, i, j)
+			}
+		}
+	}
+
+	pngPath := filepath.Join(dir, "alignment.png")
+	if err := nn.SaveAlignment(weights, pngPath); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(pngPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != bounds.Dy() || bounds.Dx() < 4 {
+		t.Errorf("Expected a square heatmap, got bounds %v\n", bounds)
+	}
+
+	if err := nn.SaveAlignment(weights, filepath.Join(dir, "alignment.txt")); err == nil {
+		t.Errorf("Expected an error for an unsupported extension\n")
+	}
+}
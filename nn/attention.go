@@ -0,0 +1,228 @@
+package nn
+
+// Attention helpers.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// CausalMask creates an additive attention mask of shape [seqLen, seqLen] for
+// autoregressive attention.
+//
+// Element (i, j) is 0 if j <= i and -inf otherwise so that, once added to the
+// attention scores, position i can only attend to positions up to i.
+func CausalMask(seqLen int64, device gotch.Device) *ts.Tensor {
+	future := ts.MustOnes([]int64{seqLen, seqLen}, gotch.Bool, device).MustTriu(1, true)
+	zeros := ts.MustZeros([]int64{seqLen, seqLen}, gotch.Float, device)
+	retVal := zeros.MustMaskedFill(future, ts.FloatScalar(math.Inf(-1)), true)
+	future.MustDrop()
+
+	return retVal
+}
+
+// MultiheadAttention is a multi-head scaled dot-product attention layer.
+//
+// Inputs are laid out as [batch_size, seq_len, embed_dim].
+//
+// Ref. Vaswani et al., "Attention Is All You Need", 2017. https://arxiv.org/abs/1706.03762
+type MultiheadAttention struct {
+	Q        *Linear
+	K        *Linear
+	V        *Linear
+	Out      *Linear
+	EmbedDim int64
+	NumHeads int64
+
+	headMask *ts.Tensor // [1, num_heads, 1, 1], nil if no head is masked
+}
+
+// NewMultiheadAttention creates a multi-head attention layer.
+//
+// embedDim should be divisible by numHeads.
+func NewMultiheadAttention(vs *Path, embedDim, numHeads int64) *MultiheadAttention {
+	if embedDim%numHeads != 0 {
+		log.Fatalf("NewMultiheadAttention - embedDim (%v) should be divisible by numHeads (%v)\n", embedDim, numHeads)
+	}
+
+	return &MultiheadAttention{
+		Q:        NewLinear(vs.Sub("q_proj"), embedDim, embedDim, DefaultLinearConfig()),
+		K:        NewLinear(vs.Sub("k_proj"), embedDim, embedDim, DefaultLinearConfig()),
+		V:        NewLinear(vs.Sub("v_proj"), embedDim, embedDim, DefaultLinearConfig()),
+		Out:      NewLinear(vs.Sub("out_proj"), embedDim, embedDim, DefaultLinearConfig()),
+		EmbedDim: embedDim,
+		NumHeads: numHeads,
+	}
+}
+
+// Forward computes attention of query over key/value.
+//
+// mask is an optional additive mask of shape [tgt_len, src_len] (e.g. see
+// `CausalMask`). It returns the output of shape [batch_size, tgt_len, embed_dim]
+// and the attention weights averaged over heads, of shape [batch_size, tgt_len, src_len].
+func (m *MultiheadAttention) Forward(query, key, value *ts.Tensor, mask *ts.Tensor) (output, weights *ts.Tensor) {
+	q := m.project(query, m.Q)
+	k := m.project(key, m.K)
+	v := m.project(value, m.V)
+
+	output, weights = m.attend(q, k, v, mask)
+
+	q.MustDrop()
+	k.MustDrop()
+	v.MustDrop()
+
+	return output, weights
+}
+
+// MaskHeads zeroes the output of the given heads, e.g. to analyse their
+// contribution or to prune them. It replaces the previously masked heads and
+// unmasks all heads if heads is empty. The attention weights returned by
+// `Forward` are still averaged over all heads.
+func (m *MultiheadAttention) MaskHeads(heads []int64) {
+	values := make([]float32, m.NumHeads)
+	for i := range values {
+		values[i] = 1
+	}
+	for _, h := range heads {
+		if h < 0 || h >= m.NumHeads {
+			log.Fatalf("MultiheadAttention.MaskHeads - Expected heads in [0, %v), got %v\n", m.NumHeads, heads)
+		}
+		values[h] = 0
+	}
+
+	if m.headMask != nil {
+		m.headMask.MustDrop()
+		m.headMask = nil
+	}
+	if len(heads) == 0 {
+		return
+	}
+
+	m.headMask = ts.MustOfSlice(values).MustView([]int64{1, m.NumHeads, 1, 1}, true).MustTotype(m.Q.Ws.DType(), true).MustTo(m.Q.Ws.MustDevice(), true)
+}
+
+// project applies a linear projection and splits heads: [batch_size,
+// seq_len, embed_dim] -> [batch_size, num_heads, seq_len, head_dim].
+func (m *MultiheadAttention) project(xs *ts.Tensor, l *Linear) *ts.Tensor {
+	size := xs.MustSize()
+	batchSize, seqLen := size[0], size[1]
+	headDim := m.EmbedDim / m.NumHeads
+
+	return l.Forward(xs).MustView([]int64{batchSize, seqLen, m.NumHeads, headDim}, true).MustTranspose(1, 2, true)
+}
+
+// attend computes scaled dot-product attention of projected queries, keys
+// and values then merges heads and applies the output projection.
+func (m *MultiheadAttention) attend(q, k, v *ts.Tensor, mask *ts.Tensor) (output, weights *ts.Tensor) {
+	size := q.MustSize()
+	batchSize, tgtLen, headDim := size[0], size[2], size[3]
+
+	kT := k.MustTranspose(2, 3, false)
+	scores := q.MustMatmul(kT, false).MustDiv1(ts.FloatScalar(math.Sqrt(float64(headDim))), true)
+	kT.MustDrop()
+
+	if mask != nil {
+		scores = scores.MustAdd(mask, true)
+	}
+
+	attnWeights := scores.MustSoftmax(-1, scores.DType(), true)
+	context := attnWeights.MustMatmul(v, false)
+	if m.headMask != nil {
+		context = context.MustMul(m.headMask, true)
+	}
+	merged := context.MustTranspose(1, 2, true).MustContiguous(true).MustView([]int64{batchSize, tgtLen, m.EmbedDim}, true)
+
+	output = m.Out.Forward(merged)
+	merged.MustDrop()
+
+	weights = attnWeights.MustMean1([]int64{1}, false, attnWeights.DType(), true)
+
+	return output, weights
+}
+
+// KVCache caches projected keys and values of past timesteps for incremental
+// (autoregressive) decoding with `MultiheadAttention.ForwardCached`.
+type KVCache struct {
+	Keys   *ts.Tensor // [batch_size, num_heads, seq_len, head_dim]
+	Values *ts.Tensor // [batch_size, num_heads, seq_len, head_dim]
+}
+
+// NewKVCache creates an empty KVCache.
+func NewKVCache() *KVCache {
+	return &KVCache{}
+}
+
+// Len returns the number of cached timesteps.
+func (c *KVCache) Len() int64 {
+	if c.Keys == nil {
+		return 0
+	}
+
+	return c.Keys.MustSize()[2]
+}
+
+// Drop frees cached keys and values, making the cache empty.
+func (c *KVCache) Drop() {
+	if c.Keys != nil {
+		c.Keys.MustDrop()
+		c.Values.MustDrop()
+	}
+	c.Keys = nil
+	c.Values = nil
+}
+
+// append adds new keys and values after the cached ones.
+func (c *KVCache) append(k, v *ts.Tensor) {
+	if c.Keys == nil {
+		c.Keys = k.MustShallowClone()
+		c.Values = v.MustShallowClone()
+		return
+	}
+
+	keys := ts.MustCat([]ts.Tensor{*c.Keys, *k}, 2)
+	values := ts.MustCat([]ts.Tensor{*c.Values, *v}, 2)
+	c.Drop()
+	c.Keys = keys
+	c.Values = values
+}
+
+// ForwardCached computes self-attention of new timesteps over themselves and
+// all the timesteps cached so far. Keys and values of new timesteps are
+// appended to the cache so that they are not recomputed at following steps.
+//
+// query has shape [batch_size, new_len, embed_dim]. mask is an optional
+// additive mask of shape [new_len, cache_len + new_len]. No mask is needed
+// when decoding one timestep at a time.
+func (m *MultiheadAttention) ForwardCached(query *ts.Tensor, cache *KVCache, mask *ts.Tensor) (output, weights *ts.Tensor) {
+	q := m.project(query, m.Q)
+	k := m.project(query, m.K)
+	v := m.project(query, m.V)
+
+	cache.append(k, v)
+	k.MustDrop()
+	v.MustDrop()
+
+	output, weights = m.attend(q, cache.Keys, cache.Values, mask)
+	q.MustDrop()
+
+	return output, weights
+}
+
+// AttentionEntropy computes the Shannon entropy (in nats) of attention weights
+// along dim, e.g. the last dimension of the weights returned by
+// `MultiheadAttention.Forward`.
+//
+// A uniform distribution over n positions has entropy log(n) whereas a one-hot
+// distribution has entropy 0. Zero weights contribute 0.
+func AttentionEntropy(weights *ts.Tensor, dim int64) *ts.Tensor {
+	logP := weights.MustClampMin(ts.FloatScalar(1e-12), false).MustLog(true)
+	pLogP := weights.MustMul(logP, false)
+	logP.MustDrop()
+
+	sum := pLogP.MustSum1([]int64{dim}, false, weights.DType(), true)
+
+	return sum.MustNeg(true)
+}
@@ -0,0 +1,167 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestCausalMask(t *testing.T) {
+	var seqLen int64 = 5
+
+	mask := nn.CausalMask(seqLen, gotch.CPU)
+
+	want := []int64{seqLen, seqLen}
+	got := mask.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected mask shape: %v\n", want)
+		t.Errorf("Got mask shape: %v\n", got)
+	}
+
+	vals := mask.Float64Values()
+	for i := int64(0); i < seqLen; i++ {
+		for j := int64(0); j < seqLen; j++ {
+			v := vals[i*seqLen+j]
+			switch {
+			case j <= i && v != 0:
+				t.Errorf("Expected position %v to attend to %v, got mask value %v\n", i, j, v)
+			case j > i && !math.IsInf(v, -1):
+				t.Errorf("Expected position %v not to attend to %v, got mask value %v\n", i, j, v)
+			}
+		}
+	}
+}
+
+func TestMultiheadAttentionForwardCached(t *testing.T) {
+	var (
+		batchDim int64 = 2
+		seqLen   int64 = 5
+		embedDim int64 = 8
+		numHeads int64 = 2
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	mha := nn.NewMultiheadAttention(vs.Root(), embedDim, numHeads)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, embedDim}, gotch.Float, gotch.CPU)
+	mask := nn.CausalMask(seqLen, gotch.CPU)
+	fullOutput, weights := mha.Forward(input, input, input, mask)
+
+	wantWeights := []int64{batchDim, seqLen, seqLen}
+	gotWeights := weights.MustSize()
+	if !reflect.DeepEqual(wantWeights, gotWeights) {
+		t.Errorf("Expected weights shape: %v\n", wantWeights)
+		t.Errorf("Got weights shape: %v\n", gotWeights)
+	}
+
+	cache := nn.NewKVCache()
+	var outputs []ts.Tensor
+	for i := int64(0); i < seqLen; i++ {
+		token := input.MustNarrow(1, i, 1, false)
+		output, _ := mha.ForwardCached(token, cache, nil)
+		outputs = append(outputs, *output)
+	}
+
+	if cache.Len() != seqLen {
+		t.Errorf("Expected %v cached timesteps, got %v\n", seqLen, cache.Len())
+	}
+
+	cachedOutput := ts.MustCat(outputs, 1)
+	if diff := maxAbsDiff(fullOutput, cachedOutput); diff > 1e-5 {
+		t.Errorf("Expected cached decoding to match full forward, got max difference %v\n", diff)
+	}
+}
+
+func TestMultiheadAttentionDouble(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	mha := nn.NewMultiheadAttention(vs.Root(), 8, 2)
+	vs.ToDType(gotch.Double)
+
+	input := ts.MustRandn([]int64{2, 5, 8}, gotch.Double, gotch.CPU)
+	output, weights := mha.Forward(input, input, input, nil)
+
+	if got := output.DType(); got != gotch.Double {
+		t.Errorf("Expected output dtype %v, got %v\n", gotch.Double, got)
+	}
+	if got := weights.DType(); got != gotch.Double {
+		t.Errorf("Expected weights dtype %v, got %v\n", gotch.Double, got)
+	}
+}
+
+func TestMultiheadAttentionMaskHeads(t *testing.T) {
+	var (
+		batchDim int64 = 2
+		seqLen   int64 = 5
+		embedDim int64 = 8
+		numHeads int64 = 4
+		masked   int64 = 2
+	)
+	headDim := embedDim / numHeads
+
+	vs := nn.NewVarStore(gotch.CPU)
+	mha := nn.NewMultiheadAttention(vs.Root(), embedDim, numHeads)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, embedDim}, gotch.Float, gotch.CPU)
+	fullOutput, _ := mha.Forward(input, input, input, nil)
+
+	mha.MaskHeads([]int64{masked})
+	maskedOutput, _ := mha.Forward(input, input, input, nil)
+
+	if diff := maxAbsDiff(fullOutput, maskedOutput); diff < 1e-4 {
+		t.Errorf("Expected masking a head to change the output, got max difference %v\n", diff)
+	}
+
+	// Without the masked head contribution to the output projection, the
+	// output is the one of the other heads alone.
+	ts.NoGrad(func() {
+		cols := mha.Out.Ws.MustNarrow(1, masked*headDim, headDim, false)
+		cols.MustZero_()
+		cols.MustDrop()
+	})
+	mha.MaskHeads(nil)
+	prunedOutput, _ := mha.Forward(input, input, input, nil)
+
+	if diff := maxAbsDiff(maskedOutput, prunedOutput); diff > 1e-5 {
+		t.Errorf("Expected masking to only remove the head contribution, got max difference %v\n", diff)
+	}
+}
+
+func TestAttentionEntropy(t *testing.T) {
+	var n int64 = 8
+
+	uniform := ts.MustOnes([]int64{2, 3, n}, gotch.Float, gotch.CPU).MustDiv1(ts.FloatScalar(float64(n)), true)
+	for _, v := range nn.AttentionEntropy(uniform, -1).Float64Values() {
+		if math.Abs(v-math.Log(float64(n))) > 1e-5 {
+			t.Errorf("Expected uniform entropy %v, got %v\n", math.Log(float64(n)), v)
+		}
+	}
+
+	oneHot := ts.MustZeros([]int64{2, n, 3}, gotch.Float, gotch.CPU)
+	ts.NoGrad(func() {
+		row := oneHot.MustNarrow(1, 2, 1, false)
+		row.MustFill_(ts.FloatScalar(1.0))
+		row.MustDrop()
+	})
+	entropy := nn.AttentionEntropy(oneHot, 1)
+	if got := entropy.MustSize(); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("Expected entropy shape [2 3], got %v\n", got)
+	}
+	for _, v := range entropy.Float64Values() {
+		if math.Abs(v) > 1e-6 {
+			t.Errorf("Expected one-hot entropy ~0, got %v\n", v)
+		}
+	}
+
+	// Usable on MultiheadAttention weights.
+	vs := nn.NewVarStore(gotch.CPU)
+	mha := nn.NewMultiheadAttention(vs.Root(), 8, 2)
+	xs := ts.MustRandn([]int64{2, 5, 8}, gotch.Float, gotch.CPU)
+	_, weights := mha.Forward(xs, xs, xs, nil)
+	if got := nn.AttentionEntropy(weights, -1).MustSize(); !reflect.DeepEqual(got, []int64{2, 5}) {
+		t.Errorf("Expected entropy shape [2 5], got %v\n", got)
+	}
+}
@@ -0,0 +1,91 @@
+package nn
+
+// An attentional decoder step for sequence-to-sequence models.
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// AttnDecoderStep is a single decoding step combining additive (Bahdanau)
+// attention over encoder outputs, an LSTM cell and an output projection.
+//
+// At each step, the previous hidden state attends over the encoder outputs.
+// The resulting context is concatenated to the input and fed to the LSTM cell,
+// then the new hidden state and the context are projected to output logits.
+//
+// Ref. Bahdanau et al., "Neural Machine Translation by Jointly Learning to
+// Align and Translate", 2014. https://arxiv.org/abs/1409.0473
+type AttnDecoderStep struct {
+	EncoderProj *Linear // encoder outputs -> attention space
+	DecoderProj *Linear // previous hidden state -> attention space
+	Score       *Linear // attention space -> scalar score
+	Cell        *LSTM
+	Out         *Linear // [hidden state, context] -> logits
+}
+
+// NewAttnDecoderStep creates a new AttnDecoderStep.
+//
+// inputDim is the size of the decoder input (e.g. a target embedding),
+// encoderDim the size of the encoder outputs, attnDim the size of the
+// attention space and outputDim the number of output logits.
+func NewAttnDecoderStep(vs *Path, inputDim, hiddenDim, encoderDim, attnDim, outputDim int64) *AttnDecoderStep {
+	return &AttnDecoderStep{
+		EncoderProj: NewLinear(vs.Sub("encoder_proj"), encoderDim, attnDim, DefaultLinearConfig()),
+		DecoderProj: NewLinear(vs.Sub("decoder_proj"), hiddenDim, attnDim, DefaultLinearConfig()),
+		Score:       NewLinear(vs.Sub("score"), attnDim, 1, DefaultLinearConfig()),
+		Cell:        NewLSTM(vs.Sub("cell"), inputDim+encoderDim, hiddenDim, DefaultRNNConfig()),
+		Out:         NewLinear(vs.Sub("out"), hiddenDim+encoderDim, outputDim, DefaultLinearConfig()),
+	}
+}
+
+// ZeroState returns the initial decoder state for a batch.
+func (d *AttnDecoderStep) ZeroState(batchDim int64) State {
+	return d.Cell.ZeroState(batchDim)
+}
+
+// Step decodes a single timestep.
+//
+// input has shape [batch_size, input_dim] and encoderOutputs has shape
+// [batch_size, src_len, encoder_dim]. It returns logits of shape
+// [batch_size, output_dim], the new state and the attention weights of shape
+// [batch_size, src_len].
+func (d *AttnDecoderStep) Step(input *ts.Tensor, state State, encoderOutputs *ts.Tensor) (logits *ts.Tensor, newState State, attnWeights *ts.Tensor) {
+	size := encoderOutputs.MustSize()
+	if len(size) != 3 {
+		log.Fatalf("AttnDecoderStep - Expected 3D encoder outputs, got shape %v\n", size)
+	}
+
+	// score(h, e) = v^T tanh(W_e e + W_h h)
+	h := state.(*LSTMState).H()
+	top := h.MustSelect(0, h.MustSize()[0]-1, false)
+	h.MustDrop()
+	decProj := d.DecoderProj.Forward(top).MustUnsqueeze(1, true)
+	top.MustDrop()
+	encProj := d.EncoderProj.Forward(encoderOutputs)
+	energy := encProj.MustAdd(decProj, true).MustTanh(true)
+	decProj.MustDrop()
+	scores := d.Score.Forward(energy).MustSqueeze1(2, true)
+	energy.MustDrop()
+	attnWeights = scores.MustSoftmax(1, encoderOutputs.DType(), true)
+
+	// context = sum_j a_j e_j
+	weights := attnWeights.MustUnsqueeze(1, false)
+	context := weights.MustBmm(encoderOutputs, true).MustSqueeze1(1, true)
+
+	cellInput := ts.MustCat([]ts.Tensor{*input, *context}, 1)
+	newState = d.Cell.Step(cellInput, state)
+	cellInput.MustDrop()
+
+	hNew := newState.(*LSTMState).H()
+	topNew := hNew.MustSelect(0, hNew.MustSize()[0]-1, false)
+	hNew.MustDrop()
+	outInput := ts.MustCat([]ts.Tensor{*topNew, *context}, 1)
+	topNew.MustDrop()
+	context.MustDrop()
+	logits = d.Out.Forward(outInput)
+	outInput.MustDrop()
+
+	return logits, newState, attnWeights
+}
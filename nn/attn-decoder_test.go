@@ -0,0 +1,51 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestAttnDecoderStep(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	dec := nn.NewAttnDecoderStep(vs.Root(), 3, 8, 6, 5, 10)
+
+	var batchDim, srcLen int64 = 2, 7
+	encoderOutputs := ts.MustRandn([]int64{batchDim, srcLen, 6}, gotch.Float, gotch.CPU)
+	input := ts.MustRandn([]int64{batchDim, 3}, gotch.Float, gotch.CPU)
+
+	state := dec.ZeroState(batchDim)
+	logits, newState, attnWeights := dec.Step(input, state, encoderOutputs)
+
+	if got, want := logits.MustSize(), []int64{batchDim, 10}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected logits shape %v, got %v\n", want, got)
+	}
+	if got, want := attnWeights.MustSize(), []int64{batchDim, srcLen}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected attention weights shape %v, got %v\n", want, got)
+	}
+	sums := attnWeights.MustSum1([]int64{1}, false, gotch.Double, false).Float64Values()
+	for i, s := range sums {
+		if math.Abs(s-1) > 1e-5 {
+			t.Errorf("Expected attention weights of sample %v to sum to 1, got %v\n", i, s)
+		}
+	}
+
+	// The state advances from zeros.
+	h := newState.(*nn.LSTMState).H()
+	if got, want := h.MustSize(), []int64{1, batchDim, 8}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected hidden state shape %v, got %v\n", want, got)
+	}
+	if m := h.MustAbs(false).MustMax(true).Float64Values()[0]; m == 0 {
+		t.Errorf("Expected the hidden state to change after a step\n")
+	}
+
+	// A second step attends with the new state.
+	_, _, attnWeights2 := dec.Step(input, newState, encoderOutputs)
+	if reflect.DeepEqual(attnWeights.Float64Values(), attnWeights2.Float64Values()) {
+		t.Errorf("Expected attention weights to depend on the decoder state\n")
+	}
+}
@@ -35,6 +35,11 @@ type BatchNorm struct {
 	Ws          *ts.Tensor
 	Bs          *ts.Tensor
 	Nd          uint
+
+	// Train is the mode used by `Forward`.
+	Train bool
+
+	frozenStats bool
 }
 
 // NewBatchNorm creates a new BatchNorm layer
@@ -45,6 +50,7 @@ func NewBatchNorm(vs *Path, nd uint, outDim int64, config *BatchNormConfig) *Bat
 		RunningVar:  vs.OnesNoTrain("running_var", []int64{outDim}),
 		Ws:          vs.NewVar("weight", []int64{outDim}, config.WsInit),
 		Bs:          vs.NewVar("bias", []int64{outDim}, config.BsInit),
+		Train:       true,
 	}
 }
 
@@ -72,8 +78,26 @@ func BatchNorm3D(vs *Path, outDim int64, config *BatchNormConfig) *BatchNorm {
 	return NewBatchNorm(vs, 3, outDim, config)
 }
 
-// Implement ModuleT interface for BatchNorm:
-// ==========================================
+// FreezeStats freezes the running statistics. The layer then normalizes with
+// its running mean and variance even when training, while weight and bias
+// are still learnt. This is useful when fine-tuning with small batches.
+func (bn *BatchNorm) FreezeStats() {
+	bn.frozenStats = true
+}
+
+// UnfreezeStats reverts `FreezeStats`.
+func (bn *BatchNorm) UnfreezeStats() {
+	bn.frozenStats = false
+}
+
+// Implement Module, ModuleT interfaces for BatchNorm:
+// ===================================================
+
+// Forward implements Module interface for BatchNorm. It uses the layer
+// `Train` flag as training mode.
+func (bn *BatchNorm) Forward(xs *ts.Tensor) *ts.Tensor {
+	return bn.ForwardT(xs, bn.Train)
+}
 
 func (bn *BatchNorm) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
 
@@ -87,6 +111,9 @@ func (bn *BatchNorm) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
 		log.Fatalf("Expected an input tensor with %v dims, got %v\n", bn.Nd+2, xs.MustSize())
 	}
 
-	return ts.MustBatchNorm(xs, bn.Ws, bn.Bs, bn.RunningMean, bn.RunningVar, train, bn.config.Momentum, bn.config.Eps, bn.config.CudnnEnable)
+	// Frozen running statistics are used as in eval mode.
+	useBatchStats := train && !bn.frozenStats
+
+	return ts.MustBatchNorm(xs, bn.Ws, bn.Bs, bn.RunningMean, bn.RunningVar, useBatchStats, bn.config.Momentum, bn.config.Eps, bn.config.CudnnEnable)
 
 }
@@ -0,0 +1,52 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestBatchNormFreezeStats(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	bn := nn.BatchNorm1D(vs.Root(), 4, nn.DefaultBatchNormConfig())
+
+	// Running statistics are updated when training.
+	xs := ts.MustRandn([]int64{8, 4}, gotch.Float, gotch.CPU)
+	meanBefore := bn.RunningMean.Float64Values()
+	bn.ForwardT(xs, true)
+	if reflect.DeepEqual(meanBefore, bn.RunningMean.Float64Values()) {
+		t.Errorf("Expected running mean to be updated before freezing\n")
+	}
+
+	bn.FreezeStats()
+
+	mean := bn.RunningMean.Float64Values()
+	variance := bn.RunningVar.Float64Values()
+	for i := 0; i < 3; i++ {
+		xs := ts.MustRandn([]int64{8, 4}, gotch.Float, gotch.CPU)
+		loss := bn.ForwardT(xs, true).MustSum(gotch.Float, true)
+		bn.Ws.ZeroGrad()
+		bn.Bs.ZeroGrad()
+		loss.MustBackward()
+
+		if got := bn.RunningMean.Float64Values(); !reflect.DeepEqual(mean, got) {
+			t.Errorf("Expected frozen running mean %v, got %v\n", mean, got)
+		}
+		if got := bn.RunningVar.Float64Values(); !reflect.DeepEqual(variance, got) {
+			t.Errorf("Expected frozen running var %v, got %v\n", variance, got)
+		}
+
+		for name, v := range map[string]*ts.Tensor{"weight": bn.Ws, "bias": bn.Bs} {
+			grad := v.MustGrad(false)
+			if !grad.MustDefined() {
+				t.Fatalf("Expected %v to receive gradients\n", name)
+			}
+			if grad.MustAbs(true).MustSum(gotch.Float, true).Float64Values()[0] == 0 {
+				t.Errorf("Expected non-zero %v gradients\n", name)
+			}
+		}
+	}
+}
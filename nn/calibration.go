@@ -0,0 +1,60 @@
+package nn
+
+// Confidence calibration with temperature scaling.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// SoftmaxWithTemperature divides logits by the temperature before applying
+// softmax along the given dimension.
+//
+// A temperature > 1 flattens the distribution while a temperature < 1 sharpens it.
+func SoftmaxWithTemperature(logits *ts.Tensor, temperature float64, dim int64) *ts.Tensor {
+	scaled := logits.MustDiv1(ts.FloatScalar(temperature), false)
+
+	return scaled.MustSoftmax(dim, gotch.Float, true)
+}
+
+// TemperatureCalibrate fits the temperature minimizing the cross-entropy of
+// validation logits (shape [batch_size, num_classes]) with respect to labels.
+//
+// Ref. Guo et al., "On Calibration of Modern Neural Networks", 2017. https://arxiv.org/abs/1706.04599
+func TemperatureCalibrate(logits, labels *ts.Tensor) float64 {
+	const (
+		steps = 200
+		lr    = 0.05
+	)
+
+	// Optimize log(temperature) so that temperature stays positive.
+	vs := NewVarStore(logits.MustDevice())
+	logT := vs.Root().Zeros("log_temperature", []int64{1})
+
+	opt, err := DefaultAdamConfig().Build(vs, lr)
+	if err != nil {
+		log.Fatalf("TemperatureCalibrate - error: %v\n", err)
+	}
+
+	xs := logits.MustDetach(false)
+	for i := 0; i < steps; i++ {
+		temperature := logT.MustExp(false)
+		scaled := xs.MustDiv(temperature, false)
+		loss := scaled.CrossEntropyForLogits(labels)
+		opt.BackwardStep(loss)
+
+		// NOTE. scaled is deleted by CrossEntropyForLogits.
+		temperature.MustDrop()
+		loss.MustDrop()
+	}
+	xs.MustDrop()
+
+	retVal := math.Exp(logT.Float64Values()[0])
+	opt.opt.Drop()
+	logT.MustDrop()
+
+	return retVal
+}
@@ -0,0 +1,41 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// entropy computes the mean Shannon entropy of probabilities along the last dim.
+func entropy(probs *ts.Tensor) float64 {
+	logProbs := probs.MustLog(false)
+	plogp := probs.MustMul(logProbs, false).MustSum1([]int64{-1}, false, gotch.Float, true)
+	retVal := -plogp.MustMean(gotch.Float, true).Float64Values()[0]
+	logProbs.MustDrop()
+
+	return retVal
+}
+
+func TestSoftmaxWithTemperature(t *testing.T) {
+	logits := ts.MustRandn([]int64{4, 10}, gotch.Float, gotch.CPU)
+
+	p1 := nn.SoftmaxWithTemperature(logits, 1.0, -1)
+	p2 := nn.SoftmaxWithTemperature(logits, 2.0, -1)
+
+	if entropy(p2) <= entropy(p1) {
+		t.Errorf("Expected temperature > 1 to increase entropy, got %v (T=2) <= %v (T=1)\n", entropy(p2), entropy(p1))
+	}
+}
+
+func TestTemperatureCalibrate(t *testing.T) {
+	// Over-confident logits: predictions are right half of the time only.
+	logits := ts.MustOfSlice([]float32{10, 0, 10, 0, 0, 10, 0, 10}).MustView([]int64{4, 2}, true)
+	labels := ts.MustOfSlice([]int64{0, 1, 1, 0})
+
+	temperature := nn.TemperatureCalibrate(logits, labels)
+	if temperature <= 1.0 {
+		t.Errorf("Expected temperature > 1 for over-confident logits, got %v\n", temperature)
+	}
+}
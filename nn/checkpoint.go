@@ -0,0 +1,108 @@
+package nn
+
+// Keeping the best checkpoints of a training run.
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Checkpoint is a var-store saved to disk with its evaluation metric.
+type Checkpoint struct {
+	Step   int64
+	Metric float64
+	Path   string
+}
+
+// CheckpointManager saves checkpoints of a var-store and only keeps the
+// `KeepN` best by metric on disk.
+type CheckpointManager struct {
+	Dir   string
+	KeepN int64
+	Mode  string // "min" if lower is better (e.g. loss), "max" if higher is better (e.g. accuracy)
+
+	vs          *VarStore
+	checkpoints []Checkpoint // best first
+}
+
+// NewCheckpointManager creates a new CheckpointManager saving vs in dir,
+// which is created if missing. mode is "min" or "max".
+func NewCheckpointManager(vs *VarStore, dir string, keepN int64, mode string) (*CheckpointManager, error) {
+	if mode != "min" && mode != "max" {
+		log.Fatalf("NewCheckpointManager - Unsupported mode %q, expected \"min\" or \"max\"\n", mode)
+	}
+	if keepN <= 0 {
+		log.Fatalf("NewCheckpointManager - Expected a positive number of checkpoints to keep, got %v\n", keepN)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &CheckpointManager{
+		Dir:   dir,
+		KeepN: keepN,
+		Mode:  mode,
+		vs:    vs,
+	}, nil
+}
+
+// better reports whether metric a is better than metric b.
+func (cm *CheckpointManager) better(a, b float64) bool {
+	if cm.Mode == "max" {
+		return a > b
+	}
+
+	return a < b
+}
+
+// Save saves the var-store at step with its metric if it is among the
+// `KeepN` best so far, and deletes the checkpoint it evicts, if any.
+func (cm *CheckpointManager) Save(step int64, metric float64) error {
+	n := len(cm.checkpoints)
+	if int64(n) >= cm.KeepN && !cm.better(metric, cm.checkpoints[n-1].Metric) {
+		return nil
+	}
+
+	path := filepath.Join(cm.Dir, fmt.Sprintf("checkpoint-%d.gt", step))
+	if err := cm.vs.Save(path); err != nil {
+		return err
+	}
+
+	i := sort.Search(n, func(i int) bool {
+		return cm.better(metric, cm.checkpoints[i].Metric)
+	})
+	cm.checkpoints = append(cm.checkpoints, Checkpoint{})
+	copy(cm.checkpoints[i+1:], cm.checkpoints[i:])
+	cm.checkpoints[i] = Checkpoint{Step: step, Metric: metric, Path: path}
+
+	if int64(len(cm.checkpoints)) > cm.KeepN {
+		worst := cm.checkpoints[len(cm.checkpoints)-1]
+		cm.checkpoints = cm.checkpoints[:len(cm.checkpoints)-1]
+		if err := os.Remove(worst.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checkpoints returns the kept checkpoints, best first.
+func (cm *CheckpointManager) Checkpoints() []Checkpoint {
+	checkpoints := make([]Checkpoint, len(cm.checkpoints))
+	copy(checkpoints, cm.checkpoints)
+
+	return checkpoints
+}
+
+// LoadBest loads the best checkpoint into the var-store.
+func (cm *CheckpointManager) LoadBest() error {
+	if len(cm.checkpoints) == 0 {
+		return fmt.Errorf("CheckpointManager.LoadBest - No checkpoint saved.\n")
+	}
+
+	return cm.vs.Load(cm.checkpoints[0].Path)
+}
@@ -0,0 +1,54 @@
+package nn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestCheckpointManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{1})
+	cm, err := nn.NewCheckpointManager(vs, dir, 2, "min")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The variable holds the step it was saved at.
+	for step, loss := range []float64{0.5, 0.3, 0.9, 0.2, 0.4} {
+		ts.NoGrad(func() { w.MustFill_(ts.IntScalar(int64(step))) })
+		if err := cm.Save(int64(step), loss); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Name())
+	}
+	if want := []string{"checkpoint-1.gt", "checkpoint-3.gt"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected files %v, got %v\n", want, got)
+	}
+
+	if err := cm.LoadBest(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Float64Values()[0]; got != 3 {
+		t.Errorf("Expected the best checkpoint (step 3) to be loaded, got step %v\n", got)
+	}
+}
@@ -0,0 +1,83 @@
+package nn
+
+// Class weights for imbalanced training.
+
+import (
+	"log"
+	"math"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// ComputeClassWeights computes a weight for each of numClasses classes from
+// the frequency of their labels in dataset, so that rare classes weigh more in
+// a loss, e.g. `ts.WeightedCrossEntropyForLogits`.
+//
+// Each dataset sample is a tensor of class labels of any shape. Negative
+// labels (e.g. padding) are ignored. dataset is read until exhausted.
+//
+// scheme is one of:
+//   - "inverse": w_c = N / (numClasses * n_c), where n_c is the count of class c
+//     and N the total count.
+//   - "effective-number": w_c = (1 - β) / (1 - β^n_c) with β = (N - 1) / N,
+//     normalized to sum to numClasses.
+//     Ref. Cui et al., "Class-Balanced Loss Based on Effective Number of
+//     Samples", 2019. https://arxiv.org/abs/1901.05555
+//
+// Classes without labels get a zero weight. It returns a Float tensor of
+// shape [numClasses].
+func ComputeClassWeights(dataset ts.Dataset, numClasses int64, scheme string) *ts.Tensor {
+	if scheme != "inverse" && scheme != "effective-number" {
+		log.Fatalf("ComputeClassWeights - Unsupported scheme %q, expected \"inverse\" or \"effective-number\"\n", scheme)
+	}
+
+	counts := make([]float64, numClasses)
+	var total float64
+	for {
+		labels, ok := dataset.Next()
+		if !ok {
+			break
+		}
+
+		for _, label := range labels.Int64Values() {
+			if label < 0 {
+				continue
+			}
+			if label >= numClasses {
+				log.Fatalf("ComputeClassWeights - Label %v out of range for %v classes\n", label, numClasses)
+			}
+			counts[label]++
+			total++
+		}
+		labels.MustDrop()
+	}
+
+	if total == 0 {
+		log.Fatalf("ComputeClassWeights - No labels in dataset\n")
+	}
+
+	weights := make([]float32, numClasses)
+	switch scheme {
+	case "inverse":
+		for c, n := range counts {
+			if n > 0 {
+				weights[c] = float32(total / (float64(numClasses) * n))
+			}
+		}
+	case "effective-number":
+		beta := (total - 1) / total
+		raw := make([]float64, numClasses)
+		var sum float64
+		for c, n := range counts {
+			if n > 0 {
+				raw[c] = (1 - beta) / (1 - math.Pow(beta, n))
+				sum += raw[c]
+			}
+		}
+		for c := range raw {
+			weights[c] = float32(raw[c] * float64(numClasses) / sum)
+		}
+	}
+
+	return ts.MustOfSlice(weights)
+}
@@ -0,0 +1,64 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// sliceDataset is a ts.Dataset over a slice of samples.
+type sliceDataset struct {
+	samples []*ts.Tensor
+}
+
+func (d *sliceDataset) Next() (*ts.Tensor, bool) {
+	if len(d.samples) == 0 {
+		return nil, false
+	}
+	sample := d.samples[0]
+	d.samples = d.samples[1:]
+
+	return sample, true
+}
+
+// imbalancedLabels returns 10 label sequences of length 10 with 90 labels of
+// class 0, 10 of class 1 and padding (-1).
+func imbalancedLabels() *sliceDataset {
+	var samples []*ts.Tensor
+	for i := 0; i < 10; i++ {
+		samples = append(samples, ts.MustOfSlice([]int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, -1, -1}))
+	}
+
+	return &sliceDataset{samples}
+}
+
+func TestComputeClassWeights(t *testing.T) {
+	// inverse: N / (C * n_c)
+	weights := nn.ComputeClassWeights(imbalancedLabels(), 3, "inverse").Float64Values()
+	want := []float64{100.0 / 270, 100.0 / 30, 0}
+	for c := range want {
+		if math.Abs(weights[c]-want[c]) > 1e-5 {
+			t.Errorf("inverse - Expected weights %v, got %v\n", want, weights)
+			break
+		}
+	}
+
+	weights = nn.ComputeClassWeights(imbalancedLabels(), 3, "effective-number").Float64Values()
+	if weights[1] <= weights[0] {
+		t.Errorf("effective-number - Expected the rare class to weigh more, got %v\n", weights)
+	}
+	if sum := weights[0] + weights[1] + weights[2]; math.Abs(sum-3) > 1e-5 {
+		t.Errorf("effective-number - Expected weights to sum to the number of classes, got %v\n", sum)
+	}
+
+	// The weights are usable by the cross-entropy helper.
+	logits := ts.MustZeros([]int64{4, 3}, gotch.Float, gotch.CPU)
+	targets := ts.MustOfSlice([]int64{0, 0, 0, 1})
+	loss := logits.WeightedCrossEntropyForLogits(targets, ts.MustOfSlice([]float32{1, 3, 0}))
+	if got, want := loss.Float64Values()[0], math.Log(3); math.Abs(got-want) > 1e-5 {
+		t.Errorf("Expected weighted cross-entropy %v, got %v\n", want, got)
+	}
+}
@@ -0,0 +1,125 @@
+package nn
+
+// A classification head on top of RNN outputs.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// SequenceClassifier pools RNN outputs over time, applies dropout and a
+// linear layer to class logits.
+//
+// Pool modes are:
+//   - "last": output at the last valid timestep
+//   - "mean": mean of outputs over valid timesteps
+//   - "max": element-wise max of outputs over valid timesteps
+//   - "attention": weighted sum of outputs with learnt attention scores
+type SequenceClassifier struct {
+	PoolMode  string
+	Attention *Linear // scores timesteps in "attention" mode, nil otherwise
+	Dropout   *Dropout
+	Out       *Linear
+}
+
+// NewSequenceClassifier creates a new SequenceClassifier.
+func NewSequenceClassifier(vs *Path, featureDim, numClasses int64, poolMode string, dropout float64) *SequenceClassifier {
+	var attention *Linear
+	switch poolMode {
+	case "last", "mean", "max":
+	case "attention":
+		attention = NewLinear(vs.Sub("attention"), featureDim, 1, DefaultLinearConfig())
+	default:
+		log.Fatalf("NewSequenceClassifier - Unsupported pool mode %q. Expected one of 'last', 'mean', 'max', 'attention'\n", poolMode)
+	}
+
+	return &SequenceClassifier{
+		PoolMode:  poolMode,
+		Attention: attention,
+		Dropout:   NewDropout(dropout),
+		Out:       NewLinear(vs.Sub("out"), featureDim, numClasses, DefaultLinearConfig()),
+	}
+}
+
+// Forward computes class logits of shape [batch_size, num_classes] from RNN
+// outputs of shape [batch_size, seq_len, features].
+//
+// lengths are the valid lengths of the sequences in the batch. If nil, all
+// timesteps are used.
+func (sc *SequenceClassifier) Forward(rnnOutput *ts.Tensor, lengths []int64) *ts.Tensor {
+	size := rnnOutput.MustSize()
+	if len(size) != 3 {
+		log.Fatalf("SequenceClassifier - Expected a 3D RNN output, got shape %v\n", size)
+	}
+	batchDim, seqLen := size[0], size[1]
+
+	if lengths == nil {
+		lengths = make([]int64, batchDim)
+		for i := range lengths {
+			lengths[i] = seqLen
+		}
+	}
+	if int64(len(lengths)) != batchDim {
+		log.Fatalf("SequenceClassifier - Expected %v lengths, got %v\n", batchDim, len(lengths))
+	}
+	for _, l := range lengths {
+		if l < 1 || l > seqLen {
+			log.Fatalf("SequenceClassifier - Expected lengths in [1, %v], got %v\n", seqLen, lengths)
+		}
+	}
+
+	device := rnnOutput.MustDevice()
+	lens := ts.MustOfSlice(lengths).MustTo(device, true)
+	defer lens.MustDrop()
+
+	var pooled *ts.Tensor
+	switch sc.PoolMode {
+	case "last":
+		pooled = LastTimestep(rnnOutput, lengths, true)
+
+	case "mean":
+		mask := lengthsMask(lens, seqLen, device)
+		maskF := mask.MustTotype(rnnOutput.DType(), true).MustUnsqueeze(2, true)
+		sum := rnnOutput.MustMul(maskF, false).MustSum1([]int64{1}, false, rnnOutput.DType(), true)
+		maskF.MustDrop()
+		counts := lens.MustTotype(rnnOutput.DType(), false).MustUnsqueeze(1, true)
+		pooled = sum.MustDiv(counts, true)
+		counts.MustDrop()
+
+	case "max":
+		invalid := lengthsMask(lens, seqLen, device).MustLogicalNot(true).MustUnsqueeze(2, true)
+		masked := rnnOutput.MustMaskedFill(invalid, ts.FloatScalar(math.Inf(-1)), false)
+		invalid.MustDrop()
+		pooled = masked.MustAmax([]int64{1}, false, true)
+
+	case "attention":
+		scores := sc.Attention.Forward(rnnOutput).MustSqueeze1(2, true)
+		invalid := lengthsMask(lens, seqLen, device).MustLogicalNot(true)
+		scores = scores.MustMaskedFill(invalid, ts.FloatScalar(math.Inf(-1)), true)
+		invalid.MustDrop()
+		weights := scores.MustSoftmax(1, rnnOutput.DType(), true).MustUnsqueeze(2, true)
+		pooled = rnnOutput.MustMul(weights, false).MustSum1([]int64{1}, false, rnnOutput.DType(), true)
+		weights.MustDrop()
+	}
+
+	dropped := sc.Dropout.Forward(pooled)
+	pooled.MustDrop()
+	retVal := sc.Out.Forward(dropped)
+	dropped.MustDrop()
+
+	return retVal
+}
+
+// lengthsMask returns a boolean mask of shape [batch_size, seq_len] which is
+// true at valid timesteps given sequence lengths of shape [batch_size].
+func lengthsMask(lengths *ts.Tensor, seqLen int64, device gotch.Device) *ts.Tensor {
+	positions := ts.MustArange(ts.IntScalar(seqLen), gotch.Int64, device).MustUnsqueeze(0, true)
+	lens := lengths.MustUnsqueeze(1, false)
+	mask := positions.MustLt1(lens, true)
+	lens.MustDrop()
+
+	return mask
+}
@@ -0,0 +1,35 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestSequenceClassifier(t *testing.T) {
+	var (
+		batchDim   int64 = 3
+		seqLen     int64 = 5
+		featureDim int64 = 8
+		numClasses int64 = 4
+	)
+
+	rnnOutput := ts.MustRandn([]int64{batchDim, seqLen, featureDim}, gotch.Float, gotch.CPU)
+	lengths := []int64{5, 2, 3}
+
+	for _, mode := range []string{"last", "mean", "max", "attention"} {
+		vs := nn.NewVarStore(gotch.CPU)
+		classifier := nn.NewSequenceClassifier(vs.Root(), featureDim, numClasses, mode, 0.1)
+
+		want := []int64{batchDim, numClasses}
+		for _, l := range [][]int64{lengths, nil} {
+			logits := classifier.Forward(rnnOutput, l)
+			if got := logits.MustSize(); !reflect.DeepEqual(want, got) {
+				t.Errorf("%v (lengths %v) - Expected logits shape %v, got %v\n", mode, l, want, got)
+			}
+		}
+	}
+}
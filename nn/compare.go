@@ -0,0 +1,23 @@
+package nn
+
+// Comparing models for regression testing.
+
+import (
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// CompareModels returns true if m1 and m2 produce close outputs on input,
+// i.e. `ts.AllClose(m1(input), m2(input), rtol, atol)`, e.g. to check that a
+// refactored model behaves like the original one. Outputs are computed
+// without gradient tracking.
+func CompareModels(m1, m2 ts.Module, input *ts.Tensor, rtol, atol float64) bool {
+	var out1, out2 *ts.Tensor
+	ts.NoGrad(func() {
+		out1 = m1.Forward(input)
+		out2 = m2.Forward(input)
+	})
+	defer out1.MustDrop()
+	defer out2.MustDrop()
+
+	return ts.AllClose(out1, out2, rtol, atol)
+}
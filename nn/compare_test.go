@@ -0,0 +1,48 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// seededLSTM returns a LSTM initialized from seed, as a module returning its
+// output sequence.
+func seededLSTM(seed int64) ts.Module {
+	ts.ManualSeed(seed)
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), 3, 8, nn.DefaultRNNConfig())
+
+	return nn.NewFunc(func(xs *ts.Tensor) *ts.Tensor {
+		output, state := lstm.Seq(xs)
+		state.(*nn.LSTMState).Tensor1.MustDrop()
+		state.(*nn.LSTMState).Tensor2.MustDrop()
+		return output
+	})
+}
+
+func TestCompareModels(t *testing.T) {
+	input := ts.MustRandn([]int64{2, 5, 3}, gotch.Float, gotch.CPU)
+
+	if !nn.CompareModels(seededLSTM(42), seededLSTM(42), input, 1e-5, 1e-6) {
+		t.Errorf("Expected identically seeded LSTMs to produce close outputs\n")
+	}
+	if nn.CompareModels(seededLSTM(42), seededLSTM(7), input, 1e-5, 1e-6) {
+		t.Errorf("Expected differently seeded LSTMs to produce different outputs\n")
+	}
+
+	// AllClose honours the tolerances and shapes.
+	a := ts.MustOfSlice([]float32{1, 2, 3})
+	b := ts.MustOfSlice([]float32{1, 2, 3.01})
+	if ts.AllClose(a, b, 0, 1e-3) {
+		t.Errorf("Expected tensors not to be close with atol 1e-3\n")
+	}
+	if !ts.AllClose(a, b, 1e-2, 0) {
+		t.Errorf("Expected tensors to be close with rtol 1e-2\n")
+	}
+	if ts.AllClose(a, a.MustView([]int64{3, 1}, false), 1e-2, 1e-2) {
+		t.Errorf("Expected tensors of different shapes not to be close\n")
+	}
+}
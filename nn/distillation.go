@@ -0,0 +1,59 @@
+package nn
+
+// Knowledge distillation.
+
+import (
+	"log"
+	"reflect"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// KLDivLoss computes the token-level KL divergence KL(teacher || student)
+// between softened teacher and student distributions.
+//
+// Logits have shape [..., vocab_size] and are divided by temperature before
+// softmax. The loss is multiplied by temperature^2 so that gradient magnitudes
+// do not depend on the temperature. Teacher logits are not back-propagated
+// through.
+//
+// mask is an optional tensor of shape [...] with 1 for valid tokens and 0 for
+// padding. It returns the mean loss over valid tokens.
+//
+// Ref. Hinton et al., "Distilling the Knowledge in a Neural Network", 2015.
+// https://arxiv.org/abs/1503.02531
+func KLDivLoss(studentLogits, teacherLogits *ts.Tensor, temperature float64, mask *ts.Tensor) *ts.Tensor {
+	if temperature <= 0 {
+		log.Fatalf("KLDivLoss - Expected a positive temperature, got %v\n", temperature)
+	}
+	size := studentLogits.MustSize()
+	if teacherSize := teacherLogits.MustSize(); !reflect.DeepEqual(size, teacherSize) {
+		log.Fatalf("KLDivLoss - Student and teacher logits shapes differ: %v and %v\n", size, teacherSize)
+	}
+
+	dtype := studentLogits.DType()
+	lastDim := int64(len(size) - 1)
+
+	studentLogProbs := studentLogits.MustDiv1(ts.FloatScalar(temperature), false).MustLogSoftmax(lastDim, dtype, true)
+	teacherLogProbs := teacherLogits.MustDetach(false).MustDiv1(ts.FloatScalar(temperature), true).MustLogSoftmax(lastDim, dtype, true)
+	teacherProbs := teacherLogProbs.MustExp(false)
+
+	// KL = sum_k p_k (log p_k - log q_k)
+	diff := teacherLogProbs.MustSub(studentLogProbs, true)
+	studentLogProbs.MustDrop()
+	kl := teacherProbs.MustMul(diff, true).MustSum1([]int64{lastDim}, false, dtype, true)
+	diff.MustDrop()
+
+	var loss *ts.Tensor
+	if mask == nil {
+		loss = kl.MustMean(dtype, true)
+	} else {
+		maskF := mask.MustTotype(dtype, false)
+		sum := kl.MustMul(maskF, true).MustSum(dtype, true)
+		count := maskF.MustSum(dtype, true).MustClampMin(ts.FloatScalar(1), true)
+		loss = sum.MustDiv(count, true)
+		count.MustDrop()
+	}
+
+	return loss.MustMul1(ts.FloatScalar(temperature*temperature), true)
+}
@@ -0,0 +1,41 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestKLDivLoss(t *testing.T) {
+	logits := ts.MustRandn([]int64{2, 5, 10}, gotch.Float, gotch.CPU)
+	mask := ts.MustOfSlice([]float32{1, 1, 1, 0, 0, 1, 1, 1, 1, 1}).MustView([]int64{2, 5}, true)
+
+	for _, temperature := range []float64{1, 2} {
+		loss := nn.KLDivLoss(logits, logits, temperature, nil).Float64Values()[0]
+		if math.Abs(loss) > 1e-6 {
+			t.Errorf("T=%v - Expected zero loss for identical logits, got %v\n", temperature, loss)
+		}
+
+		masked := nn.KLDivLoss(logits, logits, temperature, mask).Float64Values()[0]
+		if math.Abs(masked) > 1e-6 {
+			t.Errorf("T=%v - Expected zero masked loss for identical logits, got %v\n", temperature, masked)
+		}
+	}
+
+	other := ts.MustRandn([]int64{2, 5, 10}, gotch.Float, gotch.CPU)
+	if loss := nn.KLDivLoss(other, logits, 1, nil).Float64Values()[0]; loss <= 0 {
+		t.Errorf("Expected a positive loss for different logits, got %v\n", loss)
+	}
+
+	// Padding does not contribute to the loss.
+	padded := other.MustShallowClone()
+	padMask := mask.MustUnsqueeze(2, false).MustLogicalNot(true)
+	padded = padded.MustMaskedFill(padMask, ts.FloatScalar(100), true)
+	want := nn.KLDivLoss(other, logits, 1, mask).Float64Values()[0]
+	if got := nn.KLDivLoss(padded, logits, 1, mask).Float64Values()[0]; math.Abs(got-want) > 1e-5 {
+		t.Errorf("Expected padding to be ignored: got %v, want %v\n", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package nn
+
+// A dropout layer.
+
+import (
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// Dropout randomly zeroes elements of its input with probability `P` when
+// training and scales the remaining ones by 1/(1-P).
+type Dropout struct {
+	P     float64
+	Train bool
+
+	// NumSamples is the number of independent dropout masks applied when
+	// training. Results are averaged, which reduces the output variance
+	// (multi-sample dropout). A single mask is applied if it is 0 or 1.
+	//
+	// Ref. Inoue, "Multi-Sample Dropout for Accelerated Training and Better
+	// Generalization", 2019. https://arxiv.org/abs/1905.09788
+	NumSamples int64
+}
+
+// NewDropout creates a new Dropout layer in training mode.
+func NewDropout(p float64) *Dropout {
+	return &Dropout{
+		P:          p,
+		Train:      true,
+		NumSamples: 1,
+	}
+}
+
+// dropout applies `NumSamples` dropout masks to xs and averages the results.
+func (d *Dropout) dropout(xs *ts.Tensor, train bool) *ts.Tensor {
+	if !train || d.NumSamples <= 1 {
+		return ts.MustDropout(xs, d.P, train)
+	}
+
+	retVal := ts.MustDropout(xs, d.P, train)
+	for i := int64(1); i < d.NumSamples; i++ {
+		sample := ts.MustDropout(xs, d.P, train)
+		retVal = retVal.MustAdd(sample, true)
+		sample.MustDrop()
+	}
+
+	return retVal.MustDiv1(ts.FloatScalar(float64(d.NumSamples)), true)
+}
+
+// Implement Module, ModuleT interfaces for Dropout:
+// =================================================
+
+// Forward implements Module interface for Dropout. Dropout is applied
+// depending on the layer `Train` flag.
+func (d *Dropout) Forward(xs *ts.Tensor) *ts.Tensor {
+	return d.dropout(xs, d.Train)
+}
+
+// ForwardT implements ModuleT interface for Dropout.
+func (d *Dropout) ForwardT(xs *ts.Tensor, train bool) *ts.Tensor {
+	return d.dropout(xs, train)
+}
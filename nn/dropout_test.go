@@ -0,0 +1,40 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestDropoutNumSamples(t *testing.T) {
+	xs := ts.MustOnes([]int64{10000}, gotch.Float, gotch.CPU)
+	dropout := nn.NewDropout(0.5)
+
+	// A single sample is standard dropout.
+	ts.ManualSeed(42)
+	want := ts.MustDropout(xs, 0.5, true).Float64Values()
+	ts.ManualSeed(42)
+	if got := dropout.Forward(xs).Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected NumSamples=1 to match standard dropout\n")
+	}
+
+	// The output variance is p/(1-p) = 1 for a single sample, and decreases
+	// as 1/NumSamples.
+	variance := func(numSamples int64) float64 {
+		dropout.NumSamples = numSamples
+		return dropout.Forward(xs).MustVar(false, true).Float64Values()[0]
+	}
+	single, multi := variance(1), variance(8)
+	if multi > single/4 {
+		t.Errorf("Expected NumSamples=8 to reduce the output variance, got %v with 1 sample and %v with 8\n", single, multi)
+	}
+
+	// No dropout in evaluation mode.
+	dropout.Train = false
+	if diff := maxAbsDiff(dropout.Forward(xs), xs); diff != 0 {
+		t.Errorf("Expected identity in evaluation mode, got max difference %v\n", diff)
+	}
+}
@@ -0,0 +1,59 @@
+package nn
+
+// Early stopping on a validation metric.
+
+import (
+	"log"
+	"math"
+)
+
+// EarlyStopping stops training once a validation metric has not improved for
+// a number of evaluations.
+type EarlyStopping struct {
+	Patience int64   // evaluations without improvement before stopping
+	Mode     string  // "min" if lower is better (e.g. loss), "max" if higher is better (e.g. accuracy)
+	MinDelta float64 // minimum change counted as an improvement
+
+	Best     float64 // best metric so far
+	BestStep int64   // 0-based evaluation of the best metric
+	step     int64   // number of evaluations so far
+}
+
+// NewEarlyStopping creates a new EarlyStopping. mode is "min" or "max".
+func NewEarlyStopping(patience int64, mode string, minDelta float64) *EarlyStopping {
+	var best float64
+	switch mode {
+	case "min":
+		best = math.Inf(1)
+	case "max":
+		best = math.Inf(-1)
+	default:
+		log.Fatalf("NewEarlyStopping - Unsupported mode %q, expected \"min\" or \"max\"\n", mode)
+	}
+
+	return &EarlyStopping{
+		Patience: patience,
+		Mode:     mode,
+		MinDelta: minDelta,
+		Best:     best,
+		BestStep: -1,
+	}
+}
+
+// ShouldStop records metric, the result of an evaluation, and reports whether
+// training should stop: the best metric has not improved by more than
+// `MinDelta` for the last `Patience` evaluations.
+func (es *EarlyStopping) ShouldStop(metric float64) bool {
+	improved := metric < es.Best-es.MinDelta
+	if es.Mode == "max" {
+		improved = metric > es.Best+es.MinDelta
+	}
+
+	if improved {
+		es.Best = metric
+		es.BestStep = es.step
+	}
+	es.step++
+
+	return es.step-1-es.BestStep >= es.Patience
+}
@@ -0,0 +1,39 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch/nn"
+)
+
+func TestEarlyStopping(t *testing.T) {
+	tests := []struct {
+		mode     string
+		metrics  []float64
+		stopStep int
+		best     float64
+	}{
+		// 0.79 and 0.795 are within MinDelta of the best loss 0.8.
+		{"min", []float64{1.0, 0.8, 0.79, 0.795, 0.5}, 3, 0.8},
+		{"max", []float64{0.5, 0.6, 0.7, 0.65, 0.72, 0.9}, 4, 0.7},
+	}
+
+	for _, tt := range tests {
+		es := nn.NewEarlyStopping(2, tt.mode, 0.05)
+
+		stopStep := -1
+		for i, metric := range tt.metrics {
+			if es.ShouldStop(metric) {
+				stopStep = i
+				break
+			}
+		}
+
+		if stopStep != tt.stopStep {
+			t.Errorf("%v - Expected to stop at step %v, got %v\n", tt.mode, tt.stopStep, stopStep)
+		}
+		if es.Best != tt.best {
+			t.Errorf("%v - Expected best metric %v, got %v\n", tt.mode, tt.best, es.Best)
+		}
+	}
+}
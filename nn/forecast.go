@@ -0,0 +1,66 @@
+package nn
+
+// Autoregressive forecasting with RNNs.
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// ForecastRollout forecasts steps values beyond an observed window.
+//
+// It encodes history with rnn, projects the last output to a prediction of
+// the next value with projection, then feeds each prediction back as the next
+// input. history is laid out according to the RNN `BatchFirst` config, with
+// features on its last axis, and projection maps the RNN output to the same
+// number of features. It returns the predictions laid out as history, i.e.
+// [batch_size, steps, features] if `BatchFirst`.
+//
+// NOTE. only unidirectional LSTM, GRU, MinGRU and SRU layers are supported.
+func ForecastRollout(rnn RNN, history *ts.Tensor, steps int64, projection *Linear) *ts.Tensor {
+	_, config, _ := rnnInfo(rnn)
+	if config.Bidirectional {
+		log.Fatalf("ForecastRollout - Bidirectional RNNs are not supported\n")
+	}
+	if steps <= 0 {
+		log.Fatalf("ForecastRollout - Expected a positive number of steps, got %v\n", steps)
+	}
+
+	timeDim := int64(1)
+	if !config.BatchFirst {
+		timeDim = 0
+	}
+	seqLen := history.MustSize()[timeDim]
+
+	output, state := rnn.Seq(history)
+	last := output.MustSelect(timeDim, seqLen-1, true)
+
+	predictions := make([]ts.Tensor, steps)
+	for i := range predictions {
+		pred := projection.Forward(last)
+		last.MustDrop()
+		predictions[i] = *pred
+
+		if int64(i) == steps-1 {
+			break
+		}
+
+		input := pred.MustUnsqueeze(timeDim, false)
+		var nextState State
+		output, nextState = rnn.SeqInit(input, state)
+		input.MustDrop()
+		dropState(state)
+		state = nextState
+		last = output.MustSelect(timeDim, 0, true)
+	}
+
+	dropState(state)
+
+	retVal := ts.MustStack(predictions, timeDim)
+	for i := range predictions {
+		predictions[i].MustDrop()
+	}
+
+	return retVal
+}
@@ -0,0 +1,76 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// ramps returns batchDim linear series c + s * t of length seqLen + 1 with
+// random offsets c in [-1, 1] and slopes s in [-0.2, 0.2], of shape
+// [batch_size, seq_len + 1, 1].
+func ramps(batchDim, seqLen int64) *ts.Tensor {
+	c := ts.MustRand([]int64{batchDim, 1, 1}, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(2), true).MustSub1(ts.FloatScalar(1), true)
+	s := ts.MustRand([]int64{batchDim, 1, 1}, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(0.4), true).MustSub1(ts.FloatScalar(0.2), true)
+	t := ts.MustArange(ts.IntScalar(seqLen+1), gotch.Float, gotch.CPU).MustView([]int64{1, seqLen + 1, 1}, true)
+
+	return s.MustMul(t, true).MustAdd(c, true)
+}
+
+func TestForecastRollout(t *testing.T) {
+	const (
+		seqLen int64 = 8
+		steps  int64 = 3
+	)
+	ts.ManualSeed(42)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 1, 16, nn.DefaultRNNConfig())
+	projection := nn.NewLinear(vs.Root().Sub("proj"), 16, 1, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultAdamConfig().Build(vs, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Learn to predict the next value of a ramp.
+	for i := 0; i < 500; i++ {
+		series := ramps(32, seqLen)
+		input := series.MustNarrow(1, 0, seqLen, false)
+		target := series.MustNarrow(1, 1, seqLen, true)
+		output, state := lstm.Seq(input)
+		state.(*nn.LSTMState).Tensor1.MustDrop()
+		state.(*nn.LSTMState).Tensor2.MustDrop()
+		pred := projection.Forward(output)
+		loss := pred.MustMseLoss(target, int64(ts.ReductionMean), true)
+		opt.BackwardStep(loss)
+		input.MustDrop()
+		output.MustDrop()
+		target.MustDrop()
+		loss.MustDrop()
+	}
+
+	series := ramps(16, seqLen+steps-1)
+	history := series.MustNarrow(1, 0, seqLen, false)
+	future := series.MustNarrow(1, seqLen, steps, false)
+
+	var forecast *ts.Tensor
+	ts.NoGrad(func() {
+		forecast = nn.ForecastRollout(lstm, history, steps, projection)
+	})
+	if want, got := []int64{16, steps, 1}, forecast.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected forecast shape %v, got %v\n", want, got)
+	}
+
+	// The rollout follows the trend better than repeating the last observed
+	// value.
+	last := history.MustNarrow(1, seqLen-1, 1, false)
+	rolloutErr := forecast.MustSub(future, false).MustAbs(true).MustMean(gotch.Double, true).Float64Values()[0]
+	naiveErr := last.MustSub(future, false).MustAbs(true).MustMean(gotch.Double, true).Float64Values()[0]
+	if math.IsNaN(rolloutErr) || rolloutErr > naiveErr/2 {
+		t.Errorf("Expected the rollout error %v to be less than half the naive error %v\n", rolloutErr, naiveErr)
+	}
+}
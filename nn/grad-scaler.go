@@ -0,0 +1,121 @@
+package nn
+
+// Loss scaling for mixed-precision training.
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// GradScalerConfig holds parameters for building a GradScaler.
+type GradScalerConfig struct {
+	InitScale      float64 // initial scale factor
+	GrowthFactor   float64 // scale multiplier after `GrowthInterval` finite steps
+	BackoffFactor  float64 // scale multiplier after a step with non-finite gradients
+	GrowthInterval int64   // number of consecutive finite steps before growing the scale
+}
+
+// DefaultGradScalerConfig creates GradScalerConfig with default values.
+func DefaultGradScalerConfig() *GradScalerConfig {
+	return &GradScalerConfig{
+		InitScale:      65536.0,
+		GrowthFactor:   2.0,
+		BackoffFactor:  0.5,
+		GrowthInterval: 2000,
+	}
+}
+
+// GradScaler scales the loss to avoid gradient underflow in fp16 training.
+//
+// A training step looks like:
+//
+//	opt.ZeroGrad()
+//	scaler.Scale(loss).MustBackward()
+//	scaler.Step(opt)
+//	scaler.Update()
+type GradScaler struct {
+	scale         float64
+	config        *GradScalerConfig
+	growthTracker int64
+	foundInf      bool
+}
+
+// NewGradScaler creates a new GradScaler.
+func NewGradScaler(cfg *GradScalerConfig) *GradScaler {
+	if cfg.InitScale <= 0 || cfg.GrowthFactor <= 1 || cfg.BackoffFactor <= 0 || cfg.BackoffFactor >= 1 || cfg.GrowthInterval <= 0 {
+		log.Fatalf("NewGradScaler - invalid config: %+v\n", *cfg)
+	}
+
+	return &GradScaler{
+		scale:  cfg.InitScale,
+		config: cfg,
+	}
+}
+
+// GetScale returns the current scale factor.
+func (s *GradScaler) GetScale() float64 {
+	return s.scale
+}
+
+// Scale multiplies loss by the current scale factor.
+func (s *GradScaler) Scale(loss *ts.Tensor) *ts.Tensor {
+	return loss.MustMul1(ts.FloatScalar(s.scale), false)
+}
+
+// Step unscales the gradients of the variables tracked by opt and performs
+// an optimization step. The step is skipped if any gradient contains inf or NaN.
+//
+// It returns true if the optimizer step was performed.
+func (s *GradScaler) Step(opt *Optimizer) bool {
+	invScale := ts.FloatScalar(1.0 / s.scale)
+	for i := range opt.parameters {
+		grad := opt.parameters[i].MustGrad(false)
+		if !grad.MustDefined() {
+			grad.MustDrop()
+			continue
+		}
+
+		grad.MustMul1_(invScale)
+		if hasNonFinite(grad) {
+			s.foundInf = true
+		}
+		grad.MustDrop()
+	}
+
+	if s.foundInf {
+		return false
+	}
+
+	opt.Step()
+
+	return true
+}
+
+// Update adjusts the scale factor after a step. The scale is reduced by
+// `BackoffFactor` if non-finite gradients were found and grown by
+// `GrowthFactor` after `GrowthInterval` consecutive finite steps.
+func (s *GradScaler) Update() {
+	if s.foundInf {
+		s.scale *= s.config.BackoffFactor
+		s.growthTracker = 0
+	} else {
+		s.growthTracker++
+		if s.growthTracker == s.config.GrowthInterval {
+			s.scale *= s.config.GrowthFactor
+			s.growthTracker = 0
+		}
+	}
+
+	s.foundInf = false
+}
+
+// hasNonFinite returns true if x contains any inf or NaN value.
+func hasNonFinite(x *ts.Tensor) bool {
+	nonFinite := x.MustIsfinite(false).MustLogicalNot(true).MustSum(gotch.Int64, true)
+	retVal := nonFinite.Int64Values()[0] > 0
+	nonFinite.MustDrop()
+
+	return retVal
+}
@@ -0,0 +1,68 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestGradScaler(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root(), 2, 1, nn.DefaultLinearConfig())
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := nn.DefaultGradScalerConfig()
+	cfg.InitScale = 1024
+	cfg.GrowthInterval = 1
+	scaler := nn.NewGradScaler(cfg)
+
+	// Inf gradient: step is skipped and scale is reduced.
+	before := linear.Ws.Float64Values()
+	xs := ts.MustOfSlice([]float32{1, float32(math.Inf(1))}).MustView([]int64{1, 2}, true)
+	opt.ZeroGrad()
+	loss := linear.Forward(xs).MustSum(gotch.Float, true)
+	scaler.Scale(loss).MustBackward()
+
+	if stepped := scaler.Step(opt); stepped {
+		t.Errorf("Expected optimizer step to be skipped on inf gradient\n")
+	}
+	scaler.Update()
+
+	if got := linear.Ws.Float64Values(); !reflect.DeepEqual(before, got) {
+		t.Errorf("Expected weights unchanged: %v, got %v\n", before, got)
+	}
+	if got := scaler.GetScale(); got != 512 {
+		t.Errorf("Expected scale 512, got %v\n", got)
+	}
+
+	// Finite gradient: step is performed and scale grows.
+	xs = ts.MustOfSlice([]float32{1, 2}).MustView([]int64{1, 2}, true)
+	opt.ZeroGrad()
+	loss = linear.Forward(xs).MustSum(gotch.Float, true)
+	scaler.Scale(loss).MustBackward()
+
+	if stepped := scaler.Step(opt); !stepped {
+		t.Errorf("Expected optimizer step on finite gradient\n")
+	}
+	scaler.Update()
+
+	// Unscaled gradient of sum(xs @ wT + b) w.r.t. w is xs.
+	want := []float64{before[0] - 0.1*1, before[1] - 0.1*2}
+	got := linear.Ws.Float64Values()
+	for i := range want {
+		if math.Abs(want[i]-got[i]) > 1e-5 {
+			t.Errorf("Expected weights %v, got %v\n", want, got)
+			break
+		}
+	}
+	if got := scaler.GetScale(); got != 1024 {
+		t.Errorf("Expected scale 1024, got %v\n", got)
+	}
+}
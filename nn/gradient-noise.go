@@ -0,0 +1,68 @@
+package nn
+
+// Annealed gradient noise.
+
+import (
+	"math"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// GradientNoise adds annealed Gaussian noise to gradients, which helps
+// training deep and recurrent networks.
+//
+// At step t, the noise has zero mean and variance Eta / (1 + t)^Gamma.
+//
+// Ref. Neelakantan et al., "Adding Gradient Noise Improves Learning for Very
+// Deep Networks", 2015. https://arxiv.org/abs/1511.06807
+type GradientNoise struct {
+	Eta   float64
+	Gamma float64
+
+	// Generator is the random number generator used to sample the noise, so
+	// that it is reproducible from its seed. The global RNG is used if it is
+	// nil.
+	Generator *ts.Generator
+}
+
+// NewGradientNoise creates a new GradientNoise. The paper uses eta in
+// {0.01, 0.3, 1.0} and gamma = 0.55.
+func NewGradientNoise(eta, gamma float64) *GradientNoise {
+	return &GradientNoise{
+		Eta:   eta,
+		Gamma: gamma,
+	}
+}
+
+// Std returns the standard deviation of the noise at step.
+func (gn *GradientNoise) Std(step int64) float64 {
+	return math.Sqrt(gn.Eta / math.Pow(1+float64(step), gn.Gamma))
+}
+
+// Apply adds noise for step to the gradients of the trainable variables of vs
+// in place. It should be called between the backward pass and the optimizer
+// step.
+func (gn *GradientNoise) Apply(vs *VarStore, step int64) {
+	std := gn.Std(step)
+
+	ts.NoGrad(func() {
+		for _, v := range trainableVariables(vs) {
+			grad := v.MustGrad(false)
+			if !grad.MustDefined() {
+				grad.MustDrop()
+				continue
+			}
+
+			var noise *ts.Tensor
+			if gn.Generator != nil {
+				noise = ts.MustRandnWithGenerator(grad.MustSize(), grad.DType(), grad.MustDevice(), gn.Generator)
+			} else {
+				noise = ts.MustRandn(grad.MustSize(), grad.DType(), grad.MustDevice())
+			}
+			noise = noise.MustMul1(ts.FloatScalar(std), true)
+			grad.MustAdd_(noise)
+			noise.MustDrop()
+			grad.MustDrop()
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestGradientNoise(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{100000})
+	gn := nn.NewGradientNoise(0.3, 0.55)
+	gn.Generator = ts.MustNewGenerator(42)
+	defer gn.Generator.Drop()
+
+	var prev float64
+	for _, step := range []int64{0, 10, 1000} {
+		// Zero gradients, so that the gradients are the noise only.
+		loss := w.MustMul1(ts.FloatScalar(0), false).MustSum(gotch.Float, true)
+		w.ZeroGrad()
+		loss.MustBackward()
+		loss.MustDrop()
+
+		gn.Apply(vs, step)
+
+		want := math.Sqrt(0.3 / math.Pow(1+float64(step), 0.55))
+		got := w.MustGrad(false).MustStd(true, true).Float64Values()[0]
+		if math.Abs(got-want) > 0.02*want {
+			t.Errorf("Step %v - Expected noise std %v, got %v\n", step, want, got)
+		}
+		if step > 0 && got >= prev {
+			t.Errorf("Step %v - Expected noise to decay, got std %v after %v\n", step, got, prev)
+		}
+		prev = got
+	}
+}
@@ -0,0 +1,35 @@
+package nn
+
+// A gradient reversal layer for domain-adversarial training.
+
+import (
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// GradientReversal is the identity in the forward pass and multiplies the
+// gradient by -Lambda in the backward pass.
+//
+// Ref. Ganin & Lempitsky, "Unsupervised Domain Adaptation by
+// Backpropagation", 2015. https://arxiv.org/abs/1409.7495
+type GradientReversal struct {
+	Lambda float64
+}
+
+// NewGradientReversal creates a new GradientReversal layer.
+func NewGradientReversal(lambda float64) *GradientReversal {
+	return &GradientReversal{Lambda: lambda}
+}
+
+// Implement Module interface for GradientReversal:
+// ================================================
+
+// Forward computes detach(xs) - Lambda * (xs - detach(xs)). The second term is
+// exactly zero, so the output equals xs, but its gradient is -Lambda.
+func (g *GradientReversal) Forward(xs *ts.Tensor) *ts.Tensor {
+	detached := xs.MustDetach(false)
+	reversed := xs.MustSub(detached, false).MustMul1(ts.FloatScalar(-g.Lambda), true)
+	retVal := detached.MustAdd(reversed, true)
+	reversed.MustDrop()
+
+	return retVal
+}
@@ -0,0 +1,26 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestGradientReversal(t *testing.T) {
+	xs := ts.MustOfSlice([]float64{1, -2, 3}).MustSetRequiresGrad(true, true)
+	grl := nn.NewGradientReversal(0.5)
+
+	ys := grl.Forward(xs)
+	if want, got := xs.Float64Values(), ys.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected forward output %v, got %v\n", want, got)
+	}
+
+	// d/dx sum(y * w) = -lambda * w
+	w := ts.MustOfSlice([]float64{1, 2, 4})
+	ys.MustMul(w, true).MustSum(xs.DType(), true).MustBackward()
+	if want, got := []float64{-0.5, -1, -2}, xs.MustGrad(false).Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected gradient %v, got %v\n", want, got)
+	}
+}
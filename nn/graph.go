@@ -0,0 +1,66 @@
+package nn
+
+// Export of autograd graphs for visualization.
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// ExportGraphDOT writes the autograd graph that computed output to a Graphviz
+// DOT file, e.g. to be rendered with `dot -Tsvg graph.dot -o graph.svg`.
+//
+// Nodes are backward functions, edges go from an op to the op consuming its
+// result. Fused RNN kernels (e.g. "CudnnRnnBackward") and gate activations
+// (sigmoid, tanh) are highlighted, leaf variables are drawn as ellipses.
+func ExportGraphDOT(output *ts.Tensor, path string) error {
+	nodes, edges, err := output.AutogradGraph()
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("ExportGraphDOT - Output has no autograd graph. Does it require gradients?\n")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph autograd {\n")
+	buf.WriteString("  node [shape=box, style=filled, fillcolor=white, fontname=\"Helvetica\"];\n")
+	for i, name := range nodes {
+		label, attrs := dotNodeStyle(name)
+		if i == 0 {
+			attrs += ", peripheries=2"
+		}
+		fmt.Fprintf(&buf, "  n%v [label=%q%v];\n", i, label, attrs)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&buf, "  n%v -> n%v;\n", e[0], e[1])
+	}
+	buf.WriteString("}\n")
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// dotNodeStyle returns the label and extra DOT attributes of an autograd node.
+func dotNodeStyle(name string) (string, string) {
+	// Drops namespaces, e.g. "torch::autograd::AccumulateGrad".
+	short := name
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		short = name[i+2:]
+	}
+	lower := strings.ToLower(short)
+
+	switch {
+	case short == "AccumulateGrad":
+		return "Variable", ", shape=ellipse, fillcolor=lightblue"
+	case strings.Contains(lower, "rnn") || strings.Contains(lower, "lstm") || strings.Contains(lower, "gru"):
+		return short + "\n(RNN)", ", fillcolor=orange"
+	case strings.HasPrefix(short, "Sigmoid") || strings.HasPrefix(short, "Tanh"):
+		return short + "\n(gate)", ", fillcolor=lightyellow"
+	}
+
+	return short, ""
+}
@@ -0,0 +1,48 @@
+package nn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestExportGraphDOT(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), 2, 3, nn.DefaultRNNConfig())
+	input := ts.MustRandn([]int64{1, 2, 2}, gotch.Float, gotch.CPU)
+	output, _ := lstm.Seq(input)
+
+	path := filepath.Join(dir, "lstm.dot")
+	if err := nn.ExportGraphDOT(output, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dot := string(data)
+
+	for _, want := range []string{"digraph autograd {", "Variable", "SigmoidBackward", "TanhBackward", "(gate)", "->"} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT file to contain %q, got:\n%v\n", want, dot)
+		}
+	}
+
+	// A tensor without graph cannot be exported.
+	if err := nn.ExportGraphDOT(input, path); err == nil {
+		t.Errorf("Expected an error for a tensor without autograd graph\n")
+	}
+}
@@ -0,0 +1,85 @@
+package nn
+
+// A continuous-time GRU (GRU-ODE).
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// GRUODE is a continuous-time GRU for irregularly sampled time series. Its
+// hidden state follows the ODE
+//
+//	z = sigmoid(W_z x + U_z h + b_z)
+//	r = sigmoid(W_r x + U_r h + b_r)
+//	g = tanh(W_g x + U_g (r * h) + b_g)
+//	dh/dt = (1 - z) * (g - h)
+//
+// which `StepWithDelta` integrates over the time gap between observations with
+// an Euler step. It uses `GRUState` states of shape [1, batch_size,
+// hidden_dim].
+//
+// Ref. De Brouwer et al., "GRU-ODE-Bayes: Continuous modeling of
+// sporadically-observed time series", 2019. https://arxiv.org/abs/1905.12374
+type GRUODE struct {
+	InputProj  *Linear // x -> [z, r, g] pre-activations
+	HiddenProj *Linear // h -> [z, r] pre-activations
+	CandProj   *Linear // r * h -> g pre-activation
+	hiddenDim  int64
+	device     gotch.Device
+}
+
+// NewGRUODE creates a new GRUODE.
+func NewGRUODE(vs *Path, inDim, hiddenDim int64) *GRUODE {
+	noBias := DefaultLinearConfig()
+	noBias.Bias = false
+
+	return &GRUODE{
+		InputProj:  NewLinear(vs.Sub("input_proj"), inDim, 3*hiddenDim, DefaultLinearConfig()),
+		HiddenProj: NewLinear(vs.Sub("hidden_proj"), hiddenDim, 2*hiddenDim, noBias),
+		CandProj:   NewLinear(vs.Sub("cand_proj"), hiddenDim, hiddenDim, noBias),
+		hiddenDim:  hiddenDim,
+		device:     vs.Device(),
+	}
+}
+
+// ZeroState returns a zero state for a batch.
+func (g *GRUODE) ZeroState(batchDim int64) State {
+	return &GRUState{Tensor: ts.MustZeros([]int64{1, batchDim, g.hiddenDim}, gotch.Float, g.device)}
+}
+
+// StepWithDelta integrates the hidden state over a time gap dt >= 0 with
+// input of shape [batch_size, features] observed at the end of the gap. The
+// state is unchanged if dt is 0.
+func (g *GRUODE) StepWithDelta(input *ts.Tensor, dt float64, inState State) State {
+	if dt < 0 {
+		log.Fatalf("GRUODE - Expected a non-negative time gap, got %v\n", dt)
+	}
+
+	h := inState.(*GRUState).Tensor.MustSqueeze1(0, false)
+	defer h.MustDrop()
+
+	xProj := g.InputProj.Forward(input).MustChunk(3, -1, true)
+	hProj := g.HiddenProj.Forward(h).MustChunk(2, -1, true)
+	z := xProj[0].MustAdd(&hProj[0], true).MustSigmoid(true)
+	r := xProj[1].MustAdd(&hProj[1], true).MustSigmoid(true)
+	hProj[0].MustDrop()
+	hProj[1].MustDrop()
+
+	rh := r.MustMul(h, true)
+	uh := g.CandProj.Forward(rh)
+	rh.MustDrop()
+	cand := xProj[2].MustAdd(uh, true).MustTanh(true)
+	uh.MustDrop()
+
+	// h + dt * (1 - z) * (g - h)
+	oneMinusZ := z.MustRsub1(ts.FloatScalar(1), true)
+	dh := cand.MustSub(h, true).MustMul(oneMinusZ, true).MustMul1(ts.FloatScalar(dt), true)
+	oneMinusZ.MustDrop()
+	hNew := h.MustAdd(dh, false).MustUnsqueeze(0, true)
+	dh.MustDrop()
+
+	return &GRUState{Tensor: hNew}
+}
@@ -0,0 +1,33 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestGRUODE(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	gru := nn.NewGRUODE(vs.Root(), 3, 5)
+
+	input := ts.MustRandn([]int64{2, 3}, gotch.Float, gotch.CPU)
+	state := &nn.GRUState{Tensor: ts.MustRandn([]int64{1, 2, 5}, gotch.Float, gotch.CPU)}
+
+	unchanged := gru.StepWithDelta(input, 0, state).(*nn.GRUState).Tensor
+	if diff := maxAbsDiff(unchanged, state.Tensor); diff != 0 {
+		t.Errorf("Expected dt=0 to leave the state unchanged, got max difference %v\n", diff)
+	}
+
+	// Larger gaps move the state further toward the update.
+	var prev float64
+	for _, dt := range []float64{0.1, 0.5, 1.0} {
+		next := gru.StepWithDelta(input, dt, state).(*nn.GRUState).Tensor
+		dist := maxAbsDiff(next, state.Tensor)
+		if dist <= prev {
+			t.Errorf("dt=%v - Expected the state to move further than %v, got %v\n", dt, prev, dist)
+		}
+		prev = dist
+	}
+}
@@ -103,3 +103,38 @@ func (l *Linear) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
 	mul := xs.MustMatmul(l.Ws, false)
 	return mul.MustAdd(l.Bs, true)
 }
+
+// TiedLinear is a linear layer without bias that projects onto the vocabulary
+// using the weight of an embedding layer.
+//
+// Language models often tie input embeddings and output projection to save
+// parameters. The weight is shared, not copied, so any update to the embedding
+// weight is reflected in this layer.
+type TiedLinear struct {
+	Ws *ts.Tensor
+}
+
+// NewTiedLinear creates a linear layer sharing the weight of the given embedding.
+// NOTE: embedding weight has shape{numEmbeddings, embeddingDim}, hence this layer
+// maps embeddingDim input features to numEmbeddings output features.
+func NewTiedLinear(embedding *Embedding) *TiedLinear {
+	return &TiedLinear{
+		Ws: embedding.Ws,
+	}
+}
+
+// Forward implements Module interface for TiedLinear.
+func (tl *TiedLinear) Forward(xs *ts.Tensor) (retVal *ts.Tensor) {
+	wT := tl.Ws.MustT(false)
+	retVal = xs.MustMatmul(wT, false)
+	wT.MustDrop()
+
+	return retVal
+}
+
+// ForwardT implements ModuleT interface for TiedLinear.
+//
+// NOTE: train param will not be used.
+func (tl *TiedLinear) ForwardT(xs *ts.Tensor, train bool) (retVal *ts.Tensor) {
+	return tl.Forward(xs)
+}
@@ -0,0 +1,43 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestTiedLinear(t *testing.T) {
+	var (
+		batchDim     int64 = 3
+		vocabSize    int64 = 10
+		embeddingDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	embedding := nn.NewEmbedding(vs.Root(), vocabSize, embeddingDim, nn.DefaultEmbeddingConfig())
+	tied := nn.NewTiedLinear(embedding)
+
+	input := ts.MustRandn([]int64{batchDim, embeddingDim}, gotch.Float, gotch.CPU)
+	output := tied.Forward(input)
+
+	want := []int64{batchDim, vocabSize}
+	got := output.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape: %v\n", want)
+		t.Errorf("Got output shape: %v\n", got)
+	}
+
+	ts.NoGrad(func() {
+		embedding.Ws.MustMul1_(ts.FloatScalar(2.0))
+	})
+
+	// Weights are shared so output should be doubled.
+	updated := tied.Forward(input)
+	doubled := output.MustMul1(ts.FloatScalar(2.0), false)
+	if diff := maxAbsDiff(updated, doubled); diff > 1e-5 {
+		t.Errorf("Expected embedding update to be reflected in output, got max difference %v\n", diff)
+	}
+}
@@ -0,0 +1,89 @@
+package nn
+
+// Lookahead optimizer.
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// Lookahead wraps a base optimizer which updates "fast" weights. Every k
+// steps, "slow" weights move towards the fast weights,
+// `slow = slow + alpha * (fast - slow)`, and the fast weights are reset to
+// the slow ones.
+//
+// Ref. Zhang et al., "Lookahead Optimizer: k steps forward, 1 step back",
+// 2019. https://arxiv.org/abs/1907.08610
+type Lookahead struct {
+	base  *Optimizer
+	k     int64
+	alpha float64
+	steps int64
+	slow  []*ts.Tensor // slow copies of base.parameters
+}
+
+// NewLookahead creates a Lookahead optimizer wrapping base. The slow weights
+// start from the current weights.
+func NewLookahead(base *Optimizer, k int64, alpha float64) *Lookahead {
+	if k < 1 {
+		log.Fatalf("NewLookahead - Expected k >= 1, got %v\n", k)
+	}
+	if alpha <= 0 || alpha > 1 {
+		log.Fatalf("NewLookahead - Expected alpha in (0, 1], got %v\n", alpha)
+	}
+
+	slow := make([]*ts.Tensor, len(base.parameters))
+	ts.NoGrad(func() {
+		for i := range base.parameters {
+			slow[i] = base.parameters[i].MustZerosLike(false)
+			slow[i].Copy_(&base.parameters[i])
+		}
+	})
+
+	return &Lookahead{
+		base:  base,
+		k:     k,
+		alpha: alpha,
+		slow:  slow,
+	}
+}
+
+// ZeroGrad zeroes the gradients of the tracked variables.
+func (la *Lookahead) ZeroGrad() {
+	la.base.ZeroGrad()
+}
+
+// Step performs a step of the base optimizer and, every k steps, updates the
+// slow weights and copies them into the model.
+func (la *Lookahead) Step() {
+	la.base.Step()
+	la.steps++
+	if la.steps%la.k != 0 {
+		return
+	}
+
+	ts.NoGrad(func() {
+		for i := range la.slow {
+			fast := &la.base.parameters[i]
+			delta := fast.MustSub(la.slow[i], false)
+			delta.MustMul1_(ts.FloatScalar(la.alpha))
+			la.slow[i].MustAdd_(delta)
+			delta.MustDrop()
+			fast.Copy_(la.slow[i])
+		}
+	})
+}
+
+// BackwardStep applies a backward pass of loss then performs an optimization
+// step.
+func (la *Lookahead) BackwardStep(loss *ts.Tensor) {
+	la.ZeroGrad()
+	loss.MustBackward()
+	la.Step()
+}
+
+// SetLR sets the learning rate of the base optimizer.
+func (la *Lookahead) SetLR(lr float64) {
+	la.base.SetLR(lr)
+}
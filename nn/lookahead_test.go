@@ -0,0 +1,43 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestLookahead(t *testing.T) {
+	const (
+		lr    = 0.01
+		k     = 3
+		alpha = 0.5
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{1})
+	adam, err := nn.DefaultAdamConfig().Build(vs, lr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := nn.NewLookahead(adam, k, alpha)
+
+	// With a constant gradient, every Adam step moves w by -lr.
+	var slow, fast float64
+	for step := 1; step <= 2*k; step++ {
+		loss := w.MustMul1(ts.FloatScalar(2), false).MustSum(gotch.Float, true)
+		opt.BackwardStep(loss)
+
+		fast -= lr
+		if step%k == 0 {
+			slow += alpha * (fast - slow)
+			fast = slow
+		}
+
+		if got := w.Float64Values()[0]; math.Abs(got-fast) > 1e-6 {
+			t.Errorf("Step %v - Expected w = %v, got %v\n", step, fast, got)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package nn
+
+// Low-rank factorized weights.
+
+import (
+	"fmt"
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// LowRankWeight factorizes a weight of shape [out_dim, in_dim] as `w = U V`
+// with `U` of shape [out_dim, rank] and `V` of shape [rank, in_dim].
+//
+// It reduces the number of parameters from out_dim * in_dim to
+// rank * (out_dim + in_dim).
+type LowRankWeight struct {
+	U *ts.Tensor
+	V *ts.Tensor
+}
+
+// NewLowRankWeight creates a factorized variable of the given 2D shape.
+//
+// It registers `name_u` and `name_v` variables in the var-store, both
+// initialized with Kaiming uniform.
+func NewLowRankWeight(vs *Path, name string, shape []int64, rank int64) *LowRankWeight {
+	if len(shape) != 2 {
+		log.Fatalf("NewLowRankWeight - Expected a 2D shape, got %v\n", shape)
+	}
+	if rank <= 0 {
+		log.Fatalf("NewLowRankWeight - Expected a positive rank, got %v\n", rank)
+	}
+
+	return &LowRankWeight{
+		U: vs.KaimingUniform(fmt.Sprintf("%v_u", name), []int64{shape[0], rank}),
+		V: vs.KaimingUniform(fmt.Sprintf("%v_v", name), []int64{rank, shape[1]}),
+	}
+}
+
+// Weight computes the effective weight `U V`.
+func (lr *LowRankWeight) Weight() *ts.Tensor {
+	return lr.U.MustMatmul(lr.V, false)
+}
+
+// reset re-initializes both factors with Kaiming uniform.
+//
+// NOTE. It should be called in a `ts.NoGrad` scope.
+func (lr *LowRankWeight) reset() {
+	NewKaimingUniformInit().Set(lr.U)
+	NewKaimingUniformInit().Set(lr.V)
+}
+
+// variables returns both factors.
+func (lr *LowRankWeight) variables() []ts.Tensor {
+	return []ts.Tensor{*lr.U, *lr.V}
+}
@@ -0,0 +1,60 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func numParameters(vs *nn.VarStore) int64 {
+	var n int64
+	for _, v := range vs.Vars.TrainableVariables {
+		n += int64(v.Numel())
+	}
+
+	return n
+}
+
+func TestRNNWeightRank(t *testing.T) {
+	var (
+		batchDim  int64 = 3
+		seqLen    int64 = 5
+		inputDim  int64 = 16
+		hiddenDim int64 = 32
+		rank      int64 = 4
+	)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+
+	newRNNs := map[string]func(vs *nn.VarStore, cfg *nn.RNNConfig) nn.RNN{
+		"LSTM": func(vs *nn.VarStore, cfg *nn.RNNConfig) nn.RNN {
+			return nn.NewLSTM(vs.Root(), inputDim, hiddenDim, cfg)
+		},
+		"GRU": func(vs *nn.VarStore, cfg *nn.RNNConfig) nn.RNN {
+			return nn.NewGRU(vs.Root(), inputDim, hiddenDim, cfg)
+		},
+	}
+
+	for name, newRNN := range newRNNs {
+		fullVs := nn.NewVarStore(gotch.CPU)
+		full := newRNN(fullVs, nn.DefaultRNNConfig())
+
+		cfg := nn.DefaultRNNConfig()
+		cfg.WeightRank = rank
+		lowRankVs := nn.NewVarStore(gotch.CPU)
+		lowRank := newRNN(lowRankVs, cfg)
+
+		if got, want := numParameters(lowRankVs), numParameters(fullVs); got >= want {
+			t.Errorf("%v - Expected fewer parameters than %v, got %v\n", name, want, got)
+		}
+
+		fullOut, _ := full.Seq(input)
+		lowRankOut, _ := lowRank.Seq(input)
+		if want, got := fullOut.MustSize(), lowRankOut.MustSize(); !reflect.DeepEqual(want, got) {
+			t.Errorf("%v - Expected output shape %v, got %v\n", name, want, got)
+		}
+	}
+}
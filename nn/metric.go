@@ -0,0 +1,184 @@
+package nn
+
+// Evaluation metrics.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// Perplexity returns the perplexity, i.e. `exp(loss)`, of a language model
+// given its (mean) cross-entropy loss.
+func Perplexity(crossEntropyLoss *ts.Tensor) float64 {
+	return math.Exp(crossEntropyLoss.Float64Values()[0])
+}
+
+// TopKAccuracy returns the fraction of positions whose target is among the k
+// highest logits.
+//
+// logits has shape [..., num_classes] and targets has shape [...] with class
+// indexes. mask has the same shape as targets with 1 for valid positions and 0
+// for padding. If nil, all positions are counted. It returns NaN if there is
+// no valid position.
+func TopKAccuracy(logits, targets *ts.Tensor, k int64, mask *ts.Tensor) float64 {
+	lastDim := int64(len(logits.MustSize()) - 1)
+	values, indices := logits.MustTopK(k, lastDim, true, false)
+	values.MustDrop()
+
+	expanded := targets.MustTotype(gotch.Int64, false).MustUnsqueeze(-1, true)
+	hits := indices.MustEq1(expanded, true).MustAny1(-1, false, true).MustTotype(gotch.Double, true)
+	expanded.MustDrop()
+
+	var correct, total *ts.Tensor
+	if mask == nil {
+		correct = hits.MustSum(gotch.Double, true)
+		total = ts.MustOfSlice([]float64{float64(targets.Numel())})
+	} else {
+		maskD := mask.MustTotype(gotch.Double, false)
+		correct = hits.MustMul(maskD, true).MustSum(gotch.Double, true)
+		total = maskD.MustSum(gotch.Double, true)
+	}
+	defer correct.MustDrop()
+	defer total.MustDrop()
+
+	count := total.Float64Values()[0]
+	if count == 0 {
+		return math.NaN()
+	}
+
+	return correct.Float64Values()[0] / count
+}
+
+// ConfusionMatrix counts predicted against target class labels.
+//
+// predictions and targets hold class ids in [0, numClasses) and have the same
+// shape, e.g. [batch_size, seq_len]. If mask is not nil, only positions where
+// it is non-zero are counted. It returns an Int64 tensor of shape
+// [numClasses, numClasses] whose entry (i, j) is the number of positions with
+// target i predicted as j.
+func ConfusionMatrix(predictions, targets *ts.Tensor, numClasses int64, mask *ts.Tensor) *ts.Tensor {
+	// Each (target, prediction) pair is counted in bin target * numClasses + prediction.
+	bins := targets.MustTotype(gotch.Int64, false).MustMul1(ts.IntScalar(numClasses), true)
+	predsI64 := predictions.MustTotype(gotch.Int64, false)
+	bins = bins.MustAdd(predsI64, true).MustFlatten(0, -1, true)
+	predsI64.MustDrop()
+
+	weights := ts.NewTensor()
+	if mask != nil {
+		// Padded positions go to bin 0 with a zero weight, so that padding
+		// labels out of range are ignored.
+		keep := mask.MustNe(ts.IntScalar(0), false).MustFlatten(0, -1, true)
+		bins = bins.MustMul(keep, true)
+		weights = keep.MustTotype(gotch.Double, true)
+	}
+	counts := bins.MustBincount(weights, numClasses*numClasses, true)
+	weights.MustDrop()
+
+	return counts.MustTotype(gotch.Int64, true).MustView([]int64{numClasses, numClasses}, true)
+}
+
+// PerplexityAccumulator computes perplexity over multiple batches.
+//
+// It sums up token losses and counts tokens across batches so that the
+// result equals the perplexity over all the batches concatenated.
+type PerplexityAccumulator struct {
+	totalLoss   float64
+	totalTokens int64
+}
+
+// NewPerplexityAccumulator creates an empty PerplexityAccumulator.
+func NewPerplexityAccumulator() *PerplexityAccumulator {
+	return &PerplexityAccumulator{}
+}
+
+// Update accumulates a batch of per-token cross-entropy losses, i.e. computed
+// with `ts.ReductionNone`.
+//
+// mask has the same shape as tokenLosses with 1 for valid tokens and 0 for
+// padding. If nil, all tokens are counted.
+func (pa *PerplexityAccumulator) Update(tokenLosses *ts.Tensor, mask *ts.Tensor) {
+	if mask == nil {
+		sum := tokenLosses.MustSum(gotch.Double, false)
+		pa.totalLoss += sum.Float64Values()[0]
+		pa.totalTokens += int64(tokenLosses.Numel())
+		sum.MustDrop()
+		return
+	}
+
+	masked := tokenLosses.MustMul(mask, false)
+	sum := masked.MustSum(gotch.Double, true)
+	count := mask.MustSum(gotch.Double, false)
+
+	pa.totalLoss += sum.Float64Values()[0]
+	pa.totalTokens += int64(count.Float64Values()[0])
+
+	sum.MustDrop()
+	count.MustDrop()
+}
+
+// Perplexity returns `exp(total_loss / total_tokens)` over accumulated batches.
+func (pa *PerplexityAccumulator) Perplexity() float64 {
+	if pa.totalTokens == 0 {
+		return math.NaN()
+	}
+
+	return math.Exp(pa.totalLoss / float64(pa.totalTokens))
+}
+
+// Reset clears accumulated losses and token counts.
+func (pa *PerplexityAccumulator) Reset() {
+	pa.totalLoss = 0
+	pa.totalTokens = 0
+}
+
+// EMAMeter smooths a noisy series of values, e.g. per-batch training losses,
+// with an exponential moving average:
+//
+//	value = Decay * value + (1 - Decay) * x
+//
+// The first value initializes the average, so that it is not biased toward
+// zero.
+type EMAMeter struct {
+	Decay float64 // in [0, 1), higher values smooth more
+
+	value   float64
+	started bool
+}
+
+// NewEMAMeter creates an empty EMAMeter.
+func NewEMAMeter(decay float64) *EMAMeter {
+	if decay < 0 || decay >= 1 {
+		log.Fatalf("NewEMAMeter - Expected decay in [0, 1), got %v\n", decay)
+	}
+
+	return &EMAMeter{Decay: decay}
+}
+
+// Update adds a value to the moving average.
+func (m *EMAMeter) Update(value float64) {
+	if !m.started {
+		m.value = value
+		m.started = true
+		return
+	}
+
+	m.value = m.Decay*m.value + (1-m.Decay)*value
+}
+
+// Value returns the moving average, NaN if no value has been added.
+func (m *EMAMeter) Value() float64 {
+	if !m.started {
+		return math.NaN()
+	}
+
+	return m.value
+}
+
+// Reset clears the moving average.
+func (m *EMAMeter) Reset() {
+	m.value = 0
+	m.started = false
+}
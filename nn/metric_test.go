@@ -0,0 +1,113 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestPerplexityAccumulator(t *testing.T) {
+	losses1 := ts.MustOfSlice([]float32{1.0, 2.0, 3.0, 0.0, 0.5, 1.5, 0.0, 0.0}).MustView([]int64{2, 4}, true)
+	mask1 := ts.MustOfSlice([]float32{1, 1, 1, 0, 1, 1, 0, 0}).MustView([]int64{2, 4}, true)
+	losses2 := ts.MustOfSlice([]float32{2.5, 0.5, 1.0, 4.0}).MustView([]int64{2, 2}, true)
+	mask2 := ts.MustOfSlice([]float32{1, 1, 1, 0}).MustView([]int64{2, 2}, true)
+
+	acc := nn.NewPerplexityAccumulator()
+	acc.Update(losses1, mask1)
+	acc.Update(losses2, mask2)
+	got := acc.Perplexity()
+
+	// Perplexity over the concatenated valid tokens.
+	valid := ts.MustOfSlice([]float32{1.0, 2.0, 3.0, 0.5, 1.5, 2.5, 0.5, 1.0})
+	want := nn.Perplexity(valid.MustMean(gotch.Float, false))
+
+	if math.Abs(want-got) > 1e-4 {
+		t.Errorf("Expected perplexity: %v\n", want)
+		t.Errorf("Got perplexity: %v\n", got)
+	}
+}
+
+func TestTopKAccuracy(t *testing.T) {
+	// 2 sequences of 2 positions over 4 classes. The target is always the
+	// 3rd-highest logit, and the last position is padding.
+	logits := ts.MustOfSlice([]float32{
+		0.1, 0.9, 0.5, 0.3,
+		2.0, 1.0, 3.0, 0.0,
+		-1, -3, -2, 0,
+		5, 6, 7, 8,
+	}).MustView([]int64{2, 2, 4}, true)
+	targets := ts.MustOfSlice([]int64{3, 1, 2, 0}).MustView([]int64{2, 2}, true)
+	mask := ts.MustOfSlice([]float32{1, 1, 1, 0}).MustView([]int64{2, 2}, true)
+
+	for k, want := range map[int64]float64{1: 0, 2: 0, 3: 1} {
+		if got := nn.TopKAccuracy(logits, targets, k, mask); got != want {
+			t.Errorf("Top-%v - Expected accuracy %v, got %v\n", k, want, got)
+		}
+	}
+
+	// Without mask, the padding position (target is the lowest logit) counts
+	// as a miss.
+	if got := nn.TopKAccuracy(logits, targets, 3, nil); got != 0.75 {
+		t.Errorf("Expected unmasked top-3 accuracy 0.75, got %v\n", got)
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	predictions := ts.MustOfSlice([]int64{0, 1, 2, 2, 1, 1, 0, 0}).MustView([]int64{2, 4}, true)
+	targets := ts.MustOfSlice([]int64{0, 1, 1, 2, 1, 0, -100, -100}).MustView([]int64{2, 4}, true)
+	mask := ts.MustOfSlice([]float32{1, 1, 1, 1, 1, 1, 0, 0}).MustView([]int64{2, 4}, true)
+
+	// (target, prediction) pairs: (0,0) (1,1) (1,2) (2,2) (1,1) (0,1)
+	want := []float64{
+		1, 1, 0,
+		0, 2, 1,
+		0, 0, 1,
+	}
+	got := nn.ConfusionMatrix(predictions, targets, 3, mask)
+	if got.DType() != gotch.Int64 {
+		t.Errorf("Expected Int64 counts, got %v\n", got.DType())
+	}
+	for i, v := range got.Float64Values() {
+		if v != want[i] {
+			t.Errorf("Expected confusion matrix %v, got %v\n", want, got.Float64Values())
+			break
+		}
+	}
+
+	// Without a mask, all positions are counted.
+	targets = ts.MustOfSlice([]int64{0, 1, 1, 2, 1, 0, 2, 0}).MustView([]int64{2, 4}, true)
+	total := nn.ConfusionMatrix(predictions, targets, 3, nil).MustSum(gotch.Int64, true)
+	if got := total.Int64Values()[0]; got != 8 {
+		t.Errorf("Expected 8 counts without a mask, got %v\n", got)
+	}
+}
+
+func TestEMAMeter(t *testing.T) {
+	m := nn.NewEMAMeter(0.9)
+	if !math.IsNaN(m.Value()) {
+		t.Errorf("Expected NaN before any update, got %v\n", m.Value())
+	}
+
+	// Constant input.
+	for i := 0; i < 20; i++ {
+		m.Update(1)
+	}
+	if got := m.Value(); math.Abs(got-1) > 1e-12 {
+		t.Errorf("Expected 1 for a constant input, got %v\n", got)
+	}
+
+	// After a step change to 5, the average is 5 - 4 * 0.9^n after n updates.
+	for n := 1; n <= 50; n++ {
+		m.Update(5)
+		if want, got := 5-4*math.Pow(0.9, float64(n)), m.Value(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Step %v - Expected %v after the step change, got %v\n", n, want, got)
+			break
+		}
+	}
+	if got := m.Value(); math.Abs(got-5) > 0.05 {
+		t.Errorf("Expected the average to converge to 5, got %v\n", got)
+	}
+}
@@ -0,0 +1,221 @@
+package nn
+
+// A minimal gated recurrent unit (minGRU).
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// MinGRU is a minimal GRU whose update gate and candidate state only depend
+// on the input:
+//
+//	z_t = sigmoid(W_z x_t + b_z)
+//	h~_t = W_h x_t + b_h
+//	h_t = (1 - z_t) * h_{t-1} + z_t * h~_t
+//
+// As the recurrence is linear in h, `SeqInit` computes all timesteps at once
+// with a parallel scan, while `Step` applies the recurrence for a single
+// timestep.
+//
+// It uses `GRUState` states. `Bidirectional` is not supported.
+//
+// Ref. Feng et al., "Were RNNs All We Needed?", 2024.
+// https://arxiv.org/abs/2410.01201
+type MinGRU struct {
+	layers    []*Linear // input -> [update gate pre-activation, candidate]
+	hiddenDim int64
+	config    *RNNConfig
+	device    gotch.Device
+}
+
+// NewMinGRU creates a MinGRU layer. The projection of layer i is stored under
+// sub-path "i".
+func NewMinGRU(vs *Path, inDim, hiddenDim int64, cfg *RNNConfig) *MinGRU {
+	if cfg.Bidirectional {
+		log.Fatalf("NewMinGRU - Bidirectional is not supported\n")
+	}
+
+	linearCfg := DefaultLinearConfig()
+	linearCfg.Bias = cfg.HasBiases
+
+	layers := make([]*Linear, cfg.NumLayers)
+	for i := range layers {
+		layerInDim := inDim
+		if i > 0 {
+			layerInDim = hiddenDim
+		}
+		layers[i] = NewLinear(vs.Sub(fmt.Sprint(i)), layerInDim, 2*hiddenDim, linearCfg)
+	}
+
+	return &MinGRU{
+		layers:    layers,
+		hiddenDim: hiddenDim,
+		config:    cfg,
+		device:    vs.Device(),
+	}
+}
+
+// project returns the update gate pre-activation k and the candidate state
+// h~ of layer i.
+func (m *MinGRU) project(i int, xs *ts.Tensor) (k, candidate *ts.Tensor) {
+	proj := m.layers[i].Forward(xs)
+	chunks := proj.MustChunk(2, -1, true)
+
+	return &chunks[0], &chunks[1]
+}
+
+// parallelScan computes h_t = a_t * h_{t-1} + b_t for all timesteps of
+// [batch_size, seq_len, hidden_dim] tensors, given log(a_t) and h_0 of shape
+// [batch_size, hidden_dim].
+//
+// With A_t = a_1 * ... * a_t, h_t = A_t * h_0 + sum_{s<=t} (A_t / A_s) * b_s.
+// The sum is computed in log space (positive and negative parts of b
+// separately) so that it does not overflow on long sequences.
+func parallelScan(logA, b, h0 *ts.Tensor) *ts.Tensor {
+	const eps = 1e-30
+
+	cumLogA := logA.MustCumsum(1, logA.DType(), false)
+
+	// sum_{s<=t} exp(log|b_s| - log A_s), the eps parts of both signs cancel.
+	partial := func(part *ts.Tensor) *ts.Tensor {
+		logPart := part.MustRelu(false).MustClampMin(ts.FloatScalar(eps), true).MustLog(true)
+		return logPart.MustSub(cumLogA, true).MustLogcumsumexp(1, true).MustAdd(cumLogA, true).MustExp(true)
+	}
+	negB := b.MustNeg(false)
+	pos := partial(b)
+	neg := partial(negB)
+	negB.MustDrop()
+
+	h0Seq := h0.MustUnsqueeze(1, false)
+	init := cumLogA.MustExp(true).MustMul(h0Seq, true)
+	h0Seq.MustDrop()
+	retVal := init.MustAdd(pos, true).MustSub(neg, true)
+	pos.MustDrop()
+	neg.MustDrop()
+
+	return retVal
+}
+
+// layerInfo implements rnnLayer.
+func (m *MinGRU) layerInfo() (int64, *RNNConfig, gotch.Device) {
+	// Linear weights are stored transposed: [in_dim, out_dim]
+	return m.layers[0].Ws.MustSize()[0], m.config, m.device
+}
+
+// Implement RNN interface for MinGRU:
+// ===================================
+
+func (m *MinGRU) ZeroState(batchDim int64) State {
+	return &GRUState{Tensor: ts.MustZeros([]int64{m.config.NumLayers, batchDim, m.hiddenDim}, m.config.dtype(), m.device)}
+}
+
+// ZeroStateLike creates a zero state with the batch size, dtype and device of
+// input. input is either a sequence (see `SeqInit`) or a step input of shape
+// [batch_size, features].
+func (m *MinGRU) ZeroStateLike(input *ts.Tensor) State {
+	shape := []int64{m.config.NumLayers, m.config.batchDim(input), m.hiddenDim}
+	return &GRUState{Tensor: ts.MustZeros(shape, input.DType(), input.MustDevice())}
+}
+
+// Step applies the recurrence to an input of shape [batch_size, features].
+func (m *MinGRU) Step(input *ts.Tensor, inState State) State {
+	h0 := inState.(*GRUState).Tensor
+
+	hs := make([]ts.Tensor, len(m.layers))
+	xs := input.MustShallowClone()
+	for i := range m.layers {
+		k, candidate := m.project(i, xs)
+		z := k.MustSigmoid(true)
+
+		// h = h_prev + z * (h~ - h_prev)
+		hPrev := h0.MustSelect(0, int64(i), false)
+		update := candidate.MustSub(hPrev, true).MustMul(z, true)
+		z.MustDrop()
+		h := hPrev.MustAdd(update, true)
+		update.MustDrop()
+
+		hs[i] = *h
+		xs.MustDrop()
+		xs = h.MustShallowClone()
+		if i < len(m.layers)-1 {
+			xs = m.config.dropout(xs)
+		}
+	}
+	xs.MustDrop()
+
+	retVal := ts.MustStack(hs, 0)
+	for _, h := range hs {
+		h.MustDrop()
+	}
+
+	return &GRUState{Tensor: retVal}
+}
+
+func (m *MinGRU) Seq(input *ts.Tensor) (*ts.Tensor, State) {
+	inState := m.ZeroStateLike(input)
+
+	output, state := m.SeqInit(input, inState)
+
+	// Delete intermediate tensors in inState
+	inState.(*GRUState).Tensor.MustDrop()
+
+	return output, state
+}
+
+func (m *MinGRU) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
+	input = m.config.addInputNoise(input)
+	input = m.config.flipTime(input, true)
+	defer input.MustDrop()
+
+	// Work on [batch_size, seq_len, features] layout.
+	var xs *ts.Tensor
+	if m.config.BatchFirst {
+		xs = input.MustShallowClone()
+	} else {
+		xs = input.MustTranspose(0, 1, false)
+	}
+	seqLen := xs.MustSize()[1]
+	h0 := inState.(*GRUState).Tensor
+
+	hs := make([]ts.Tensor, len(m.layers))
+	for i := range m.layers {
+		k, candidate := m.project(i, xs)
+		xs.MustDrop()
+
+		// log(1 - sigmoid(k)) = -softplus(k)
+		logA := k.MustSoftplus(false).MustNeg(true)
+		b := k.MustSigmoid(true).MustMul(candidate, true)
+		candidate.MustDrop()
+
+		layerH0 := h0.MustSelect(0, int64(i), false)
+		xs = parallelScan(logA, b, layerH0)
+		logA.MustDrop()
+		b.MustDrop()
+		layerH0.MustDrop()
+
+		hs[i] = *xs.MustSelect(1, seqLen-1, false)
+		if i < len(m.layers)-1 {
+			xs = m.config.dropout(xs)
+		}
+	}
+
+	output := xs
+	if !m.config.BatchFirst {
+		output = xs.MustTranspose(0, 1, true)
+	}
+	output = m.config.flipTime(output, true)
+	output = m.config.activateOutput(output)
+	output = m.config.castOutput(output)
+
+	h := ts.MustStack(hs, 0)
+	for _, t := range hs {
+		t.MustDrop()
+	}
+	m.config.checkFinite("MinGRU", []string{"output", "hidden state"}, output, h)
+
+	return output, &GRUState{Tensor: h}
+}
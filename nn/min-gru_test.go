@@ -0,0 +1,47 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestMinGRU(t *testing.T) {
+	var (
+		batchDim  int64 = 3
+		seqLen    int64 = 20
+		inputDim  int64 = 2
+		hiddenDim int64 = 5
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	minGRU := nn.NewMinGRU(vs.Root(), inputDim, hiddenDim, cfg)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+	output, state := minGRU.Seq(input)
+	if want, got := []int64{batchDim, seqLen, hiddenDim}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape %v, got %v\n", want, got)
+	}
+	if want, got := []int64{2, batchDim, hiddenDim}, state.(*nn.GRUState).Tensor.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected state shape %v, got %v\n", want, got)
+	}
+
+	// The parallel scan matches the step-by-step recurrence.
+	stepState := minGRU.ZeroState(batchDim)
+	for i := int64(0); i < seqLen; i++ {
+		stepState = minGRU.Step(input.MustSelect(1, i, false), stepState)
+
+		top := stepState.(*nn.GRUState).Tensor.MustSelect(0, 1, false)
+		if diff := maxAbsDiff(output.MustSelect(1, i, false), top); diff > 1e-5 {
+			t.Errorf("Timestep %v - Expected parallel output to match sequential output, got max difference %v\n", i, diff)
+		}
+	}
+	if diff := maxAbsDiff(state.(*nn.GRUState).Tensor, stepState.(*nn.GRUState).Tensor); diff > 1e-5 {
+		t.Errorf("Expected parallel final state to match sequential state, got max difference %v\n", diff)
+	}
+}
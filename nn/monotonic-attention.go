@@ -0,0 +1,116 @@
+package nn
+
+// Monotonic attention for sequence-to-sequence models.
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// MonotonicAttention is an attention whose alignment only moves forward over
+// the encoder outputs across decoder steps, e.g. for speech recognition or
+// synthesis.
+//
+// At each decoder step, p_j = sigmoid(score(h, e_j)) is the probability to
+// stop at encoder position j when scanning forward from the previous
+// position. The alignment is the expected stopping position given the
+// previous alignment:
+//
+//	q_j = (1 - p_{j-1}) * q_{j-1} + a'_j
+//	a_j = p_j * q_j
+//
+// where a' is the previous alignment. The scan always stops at the last
+// position so that each alignment sums to 1 and its expected position never
+// decreases.
+//
+// Ref. Raffel et al., "Online and Linear-Time Attention by Enforcing
+// Monotonic Alignments", 2017. https://arxiv.org/abs/1704.00784
+type MonotonicAttention struct {
+	EncoderProj *Linear // encoder outputs -> attention space
+	DecoderProj *Linear // decoder state -> attention space
+	Score       *Linear // attention space -> scalar score
+}
+
+// NewMonotonicAttention creates a new MonotonicAttention for decoder states
+// of size queryDim and encoder outputs of size encoderDim.
+func NewMonotonicAttention(vs *Path, queryDim, encoderDim, attnDim int64) *MonotonicAttention {
+	return &MonotonicAttention{
+		EncoderProj: NewLinear(vs.Sub("encoder_proj"), encoderDim, attnDim, DefaultLinearConfig()),
+		DecoderProj: NewLinear(vs.Sub("decoder_proj"), queryDim, attnDim, DefaultLinearConfig()),
+		Score:       NewLinear(vs.Sub("score"), attnDim, 1, DefaultLinearConfig()),
+	}
+}
+
+// InitAlignment returns the alignment before the first decoder step, of shape
+// [batch_size, src_len], with all its mass on the first encoder position.
+func (m *MonotonicAttention) InitAlignment(batchDim, srcLen int64, device gotch.Device) *ts.Tensor {
+	first := ts.MustOnes([]int64{batchDim, 1}, gotch.Float, device)
+	rest := ts.MustZeros([]int64{batchDim, srcLen - 1}, gotch.Float, device)
+	retVal := ts.MustCat([]ts.Tensor{*first, *rest}, 1)
+	first.MustDrop()
+	rest.MustDrop()
+
+	return retVal
+}
+
+// Forward computes a decoder step attention.
+//
+// query has shape [batch_size, query_dim], encoderOutputs [batch_size,
+// src_len, encoder_dim] and prevAlignment [batch_size, src_len] (see
+// `InitAlignment`). It returns the context of shape [batch_size, encoder_dim]
+// and the alignment of shape [batch_size, src_len], to be passed to the next
+// step.
+func (m *MonotonicAttention) Forward(query, encoderOutputs, prevAlignment *ts.Tensor) (context, alignment *ts.Tensor) {
+	size := encoderOutputs.MustSize()
+	if len(size) != 3 {
+		log.Fatalf("MonotonicAttention - Expected 3D encoder outputs, got shape %v\n", size)
+	}
+	srcLen := size[1]
+
+	// score(h, e) = v^T tanh(W_e e + W_h h)
+	decProj := m.DecoderProj.Forward(query).MustUnsqueeze(1, true)
+	encProj := m.EncoderProj.Forward(encoderOutputs)
+	energy := encProj.MustAdd(decProj, true).MustTanh(true)
+	decProj.MustDrop()
+	scores := m.Score.Forward(energy).MustSqueeze1(2, true)
+	energy.MustDrop()
+
+	// Stopping probabilities, forced to 1 at the last position.
+	probs := scores.MustNarrow(1, 0, srcLen-1, true).MustSigmoid(true)
+	last := ts.MustOnes([]int64{size[0], 1}, probs.DType(), probs.MustDevice())
+	p := ts.MustCat([]ts.Tensor{*probs, *last}, 1)
+	probs.MustDrop()
+	last.MustDrop()
+	defer p.MustDrop()
+
+	columns := make([]ts.Tensor, srcLen)
+	var q *ts.Tensor
+	for j := int64(0); j < srcLen; j++ {
+		prevJ := prevAlignment.MustSelect(1, j, false)
+		pJ := p.MustSelect(1, j, false)
+		if q == nil {
+			q = prevJ
+		} else {
+			pPrev := p.MustSelect(1, j-1, false)
+			carry := pPrev.MustRsub1(ts.FloatScalar(1), true).MustMul(q, true)
+			q.MustDrop()
+			q = carry.MustAdd(prevJ, true)
+			prevJ.MustDrop()
+		}
+		columns[j] = *pJ.MustMul(q, true)
+	}
+	q.MustDrop()
+
+	alignment = ts.MustStack(columns, 1)
+	for i := range columns {
+		columns[i].MustDrop()
+	}
+
+	// context = sum_j a_j e_j
+	weights := alignment.MustUnsqueeze(1, false)
+	context = weights.MustBmm(encoderOutputs, true).MustSqueeze1(1, true)
+
+	return context, alignment
+}
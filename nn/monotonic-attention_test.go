@@ -0,0 +1,44 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestMonotonicAttention(t *testing.T) {
+	var (
+		batchDim int64 = 3
+		srcLen   int64 = 7
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	attn := nn.NewMonotonicAttention(vs.Root(), 4, 5, 8)
+	encoderOutputs := ts.MustRandn([]int64{batchDim, srcLen, 5}, gotch.Float, gotch.CPU)
+	positions := ts.MustArange(ts.IntScalar(srcLen), gotch.Float, gotch.CPU)
+
+	alignment := attn.InitAlignment(batchDim, srcLen, gotch.CPU)
+	prev := make([]float64, batchDim)
+	for step := 0; step < 10; step++ {
+		query := ts.MustRandn([]int64{batchDim, 4}, gotch.Float, gotch.CPU)
+		_, next := attn.Forward(query, encoderOutputs, alignment)
+		alignment = next
+
+		sums := alignment.MustSum1([]int64{1}, false, gotch.Double, false).Float64Values()
+		expected := alignment.MustMul(positions, false).MustSum1([]int64{1}, false, gotch.Double, true).Float64Values()
+		for i := range expected {
+			if math.Abs(sums[i]-1) > 1e-5 {
+				t.Errorf("Step %v - Expected alignments summing to 1, got %v\n", step, sums)
+				break
+			}
+			if expected[i] < prev[i]-1e-5 {
+				t.Errorf("Step %v - Expected non-decreasing positions, got %v after %v\n", step, expected, prev)
+				break
+			}
+		}
+		prev = expected
+	}
+}
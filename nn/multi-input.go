@@ -0,0 +1,81 @@
+package nn
+
+// Fusion of multiple input feature streams.
+
+import (
+	"fmt"
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// MultiInputProjection projects several input feature streams, e.g. sensor
+// groups, with their own linear layer and fuses them into a single input.
+//
+// Projections are summed by default, giving outDim features. If Concat is
+// set, they are concatenated instead, giving outDim * num_streams features.
+type MultiInputProjection struct {
+	Projections []*Linear
+	Concat      bool
+}
+
+// NewMultiInputProjection creates a MultiInputProjection for streams of
+// inDims features. The projection of stream i is stored under sub-path "i".
+func NewMultiInputProjection(vs *Path, inDims []int64, outDim int64) *MultiInputProjection {
+	if len(inDims) == 0 {
+		log.Fatalf("NewMultiInputProjection - Expected at least one input stream\n")
+	}
+
+	projections := make([]*Linear, len(inDims))
+	for i, inDim := range inDims {
+		projections[i] = NewLinear(vs.Sub(fmt.Sprint(i)), inDim, outDim, DefaultLinearConfig())
+	}
+
+	return &MultiInputProjection{
+		Projections: projections,
+		Concat:      false,
+	}
+}
+
+// OutDim returns the number of fused features.
+func (m *MultiInputProjection) OutDim() int64 {
+	outDim := m.Projections[0].Ws.MustSize()[0]
+	if m.Concat {
+		return outDim * int64(len(m.Projections))
+	}
+
+	return outDim
+}
+
+// Forward projects and fuses the input streams.
+//
+// inputs holds one tensor per stream, in the order of inDims, with features on
+// the last axis and matching leading dimensions, e.g. [batch_size, seq_len,
+// in_dim].
+func (m *MultiInputProjection) Forward(inputs []ts.Tensor) *ts.Tensor {
+	if len(inputs) != len(m.Projections) {
+		log.Fatalf("MultiInputProjection Forward - Expected %v input streams, got %v\n", len(m.Projections), len(inputs))
+	}
+
+	projected := make([]ts.Tensor, len(inputs))
+	for i := range inputs {
+		projected[i] = *m.Projections[i].Forward(&inputs[i])
+	}
+
+	var retVal *ts.Tensor
+	if m.Concat {
+		lastDim := int64(len(projected[0].MustSize()) - 1)
+		retVal = ts.MustCat(projected, lastDim)
+	} else {
+		retVal = projected[0].MustShallowClone()
+		for i := 1; i < len(projected); i++ {
+			retVal = retVal.MustAdd(&projected[i], true)
+		}
+	}
+
+	for _, p := range projected {
+		p.MustDrop()
+	}
+
+	return retVal
+}
@@ -0,0 +1,53 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestMultiInputProjection(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	proj := nn.NewMultiInputProjection(vs.Root(), []int64{3, 5}, 4)
+
+	inputs := []ts.Tensor{
+		*ts.MustRandn([]int64{2, 6, 3}, gotch.Float, gotch.CPU),
+		*ts.MustRandn([]int64{2, 6, 5}, gotch.Float, gotch.CPU),
+	}
+
+	for _, concat := range []bool{false, true} {
+		proj.Concat = concat
+		want := []int64{2, 6, proj.OutDim()}
+		if got := proj.Forward(inputs).MustSize(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Concat %v - Expected output shape %v, got %v\n", concat, want, got)
+		}
+	}
+	if proj.OutDim() != 8 {
+		t.Errorf("Expected 8 concatenated features, got %v\n", proj.OutDim())
+	}
+
+	// Each projection is learnable.
+	before := make(map[string][]float64)
+	for name, v := range vs.Vars.NamedVariables {
+		before[name] = v.Float64Values()
+	}
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := ts.MustOnes([]int64{2, 6, 8}, gotch.Float, gotch.CPU)
+	loss := proj.Forward(inputs).MustMseLoss(target, int64(ts.ReductionMean), true)
+	opt.BackwardStep(loss)
+
+	if len(before) != 4 {
+		t.Errorf("Expected 4 variables, got %v\n", len(before))
+	}
+	for name, v := range vs.Vars.NamedVariables {
+		if reflect.DeepEqual(before[name], v.Float64Values()) {
+			t.Errorf("%v - Expected variable to change\n", name)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package nn
+
+// Gradient tools for multi-task training.
+
+import (
+	"log"
+	"math"
+	"math/rand"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// taskGradients computes the gradients of loss w.r.t. vars without touching
+// their `.Grad()`. Variables loss does not depend on get zero gradients.
+//
+// The graph is kept so that other losses sharing it can be differentiated.
+func taskGradients(loss *ts.Tensor, vars []ts.Tensor) []ts.Tensor {
+	grads, err := ts.RunBackward([]ts.Tensor{*loss}, vars, true, false)
+	if err != nil {
+		log.Fatalf("taskGradients - RunBackward error: %v\n", err)
+	}
+
+	for i := range grads {
+		if !grads[i].MustDefined() {
+			grads[i] = *vars[i].MustZerosLike(false)
+		}
+	}
+
+	return grads
+}
+
+// trainableVariables returns a copy of the trainable variables of vs.
+func trainableVariables(vs *VarStore) []ts.Tensor {
+	vs.Vars.mutex.Lock()
+	defer vs.Vars.mutex.Unlock()
+
+	vars := make([]ts.Tensor, len(vs.Vars.TrainableVariables))
+	copy(vars, vs.Vars.TrainableVariables)
+
+	return vars
+}
+
+// gradVariables returns the trainable variables of vs which require
+// gradients, i.e. without those of frozen layers (e.g. see
+// `Embedding.Freeze`).
+func gradVariables(vs *VarStore) []ts.Tensor {
+	var vars []ts.Tensor
+	for _, v := range trainableVariables(vs) {
+		if v.MustRequiresGrad() {
+			vars = append(vars, v)
+		}
+	}
+
+	return vars
+}
+
+// gradDot returns the dot product of two flattened gradients.
+func gradDot(a, b []ts.Tensor) float64 {
+	var dot float64
+	for i := range a {
+		prod := a[i].MustMul(&b[i], false)
+		dot += prod.MustSum(gotch.Double, true).Float64Values()[0]
+	}
+
+	return dot
+}
+
+// dropGrads deletes gradient tensors.
+func dropGrads(grads []ts.Tensor) {
+	for i := range grads {
+		grads[i].MustDrop()
+	}
+}
+
+// GradCosineSimilarity returns the cosine similarity between the gradients of
+// lossA and lossB w.r.t. the trainable variables of vs, flattened into single
+// vectors. A negative value means the tasks conflict.
+//
+// It does not modify the variables gradients. Frozen variables are ignored.
+// It returns 0 if one of the gradients is zero.
+func GradCosineSimilarity(vs *VarStore, lossA, lossB *ts.Tensor) float64 {
+	vars := gradVariables(vs)
+	gradsA := taskGradients(lossA, vars)
+	defer dropGrads(gradsA)
+	gradsB := taskGradients(lossB, vars)
+	defer dropGrads(gradsB)
+
+	norms := math.Sqrt(gradDot(gradsA, gradsA) * gradDot(gradsB, gradsB))
+	if norms == 0 {
+		return 0
+	}
+
+	return gradDot(gradsA, gradsB) / norms
+}
+
+// PCGrad performs "gradient surgery" for multi-task training.
+//
+// The gradient of each task loss w.r.t. the trainable variables of vs is
+// projected onto the normal plane of the gradient of every other task it
+// conflicts with (negative dot product), in random order. The projected
+// gradients are summed and accumulated into the variables gradients, as a
+// backward pass would do. Gradients should be zeroed beforehand, e.g. with
+// `Optimizer.ZeroGrad`. Frozen variables are ignored.
+//
+// Ref. Yu et al., "Gradient Surgery for Multi-Task Learning", 2020.
+// https://arxiv.org/abs/2001.06782
+func PCGrad(vs *VarStore, losses []*ts.Tensor) {
+	if len(losses) == 0 {
+		log.Fatalf("PCGrad - Expected at least one loss\n")
+	}
+
+	vars := gradVariables(vs)
+	grads := make([][]ts.Tensor, len(losses))
+	for i, loss := range losses {
+		grads[i] = taskGradients(loss, vars)
+	}
+	defer func() {
+		for _, g := range grads {
+			dropGrads(g)
+		}
+	}()
+
+	sqNorms := make([]float64, len(grads))
+	for j, g := range grads {
+		sqNorms[j] = gradDot(g, g)
+	}
+
+	combined := make([]ts.Tensor, len(vars))
+	for n := range vars {
+		combined[n] = *vars[n].MustZerosLike(false)
+	}
+	defer dropGrads(combined)
+
+	for i := range grads {
+		projected := make([]ts.Tensor, len(vars))
+		for n := range grads[i] {
+			projected[n] = *grads[i][n].MustShallowClone()
+		}
+
+		for _, j := range rand.Perm(len(grads)) {
+			if j == i || sqNorms[j] == 0 {
+				continue
+			}
+			dot := gradDot(projected, grads[j])
+			if dot >= 0 {
+				continue
+			}
+
+			coeff := ts.FloatScalar(dot / sqNorms[j])
+			for n := range projected {
+				scaled := grads[j][n].MustMul1(coeff, false)
+				projected[n] = *projected[n].MustSub(scaled, true)
+				scaled.MustDrop()
+			}
+		}
+
+		for n := range projected {
+			combined[n].MustAdd_(&projected[n])
+		}
+		dropGrads(projected)
+	}
+
+	// Back-propagates sum(v * g) to accumulate g into the gradient of v.
+	var surrogate *ts.Tensor
+	for n := range vars {
+		term := vars[n].MustMul(&combined[n], false).MustSum(vars[n].DType(), true)
+		if surrogate == nil {
+			surrogate = term
+			continue
+		}
+		surrogate = surrogate.MustAdd(term, true)
+		term.MustDrop()
+	}
+	if surrogate == nil {
+		return
+	}
+	surrogate.MustBackward()
+	surrogate.MustDrop()
+}
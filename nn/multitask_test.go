@@ -0,0 +1,105 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestGradCosineSimilarity(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root(), 3, 2, nn.DefaultLinearConfig())
+	xs := ts.MustRandn([]int64{4, 3}, gotch.Float, gotch.CPU)
+	output := linear.Forward(xs)
+
+	lossA := output.MustSum(gotch.Float, false)
+	sameLoss := output.MustSum(gotch.Float, false).MustMul1(ts.FloatScalar(3), true)
+	opposingLoss := output.MustSum(gotch.Float, false).MustMul1(ts.FloatScalar(-2), true)
+
+	if got := nn.GradCosineSimilarity(vs, lossA, sameLoss); math.Abs(got-1) > 1e-5 {
+		t.Errorf("Expected similarity 1 for identical losses, got %v\n", got)
+	}
+	if got := nn.GradCosineSimilarity(vs, lossA, opposingLoss); math.Abs(got+1) > 1e-5 {
+		t.Errorf("Expected similarity -1 for opposing losses, got %v\n", got)
+	}
+}
+
+func TestGradCosineSimilarityFrozen(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	embedding := nn.NewEmbedding(vs.Root().Sub("embedding"), 5, 3, nn.DefaultEmbeddingConfig())
+	embedding.Freeze()
+	linear := nn.NewLinear(vs.Root().Sub("linear"), 3, 2, nn.DefaultLinearConfig())
+
+	indices := ts.MustOfSlice([]int64{0, 2, 4})
+	output := linear.Forward(embedding.Forward(indices))
+	lossA := output.MustSum(gotch.Float, false)
+	lossB := output.MustSum(gotch.Float, false).MustMul1(ts.FloatScalar(2), true)
+
+	if got := nn.GradCosineSimilarity(vs, lossA, lossB); math.Abs(got-1) > 1e-5 {
+		t.Errorf("Expected similarity 1 with a frozen layer, got %v\n", got)
+	}
+}
+
+func TestPCGrad(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{2})
+
+	// Gradients a and b conflict: a.b = -0.5 < 0.
+	a := ts.MustOfSlice([]float32{1, 0})
+	b := ts.MustOfSlice([]float32{-0.5, 1})
+	lossA := w.MustMul(a, false).MustSum(gotch.Float, true)
+	lossB := w.MustMul(b, false).MustSum(gotch.Float, true)
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt.ZeroGrad()
+	nn.PCGrad(vs, []*ts.Tensor{lossA, lossB})
+
+	grad := w.MustGrad(false)
+	for name, task := range map[string]*ts.Tensor{"a": a, "b": b} {
+		dot := grad.MustMul(task, false).MustSum(gotch.Double, true).Float64Values()[0]
+		if dot < -1e-6 {
+			t.Errorf("Expected a non-negative projection onto task %v gradient, got %v\n", name, dot)
+		}
+	}
+
+	// g_a' = a - (a.b / |b|^2) b = (0.8, 0.4) and
+	// g_b' = b - (a.b / |a|^2) a = (0, 1).
+	want := []float64{0.8, 1.4}
+	got := grad.Float64Values()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-5 {
+			t.Errorf("Expected combined gradient %v, got %v\n", want, got)
+			break
+		}
+	}
+}
+
+func TestPCGradFrozen(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	frozen := vs.Root().Zeros("frozen", []int64{2})
+	frozen.MustRequiresGrad_(false)
+	w := vs.Root().Zeros("w", []int64{2})
+
+	a := ts.MustOfSlice([]float32{1, 0})
+	b := ts.MustOfSlice([]float32{0, 1})
+	lossA := w.MustAdd(frozen, false).MustMul(a, true).MustSum(gotch.Float, true)
+	lossB := w.MustMul(b, false).MustSum(gotch.Float, true)
+
+	nn.PCGrad(vs, []*ts.Tensor{lossA, lossB})
+
+	// Non-conflicting gradients are summed.
+	want := []float64{1, 1}
+	got := w.MustGrad(false).Float64Values()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-5 {
+			t.Errorf("Expected combined gradient %v, got %v\n", want, got)
+			break
+		}
+	}
+}
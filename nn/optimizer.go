@@ -3,7 +3,10 @@ package nn
 // Optimizers to be used for gradient-descent based training.
 
 import (
+	"fmt"
+	"io/ioutil"
 	"log"
+	"strings"
 
 	ts "github.com/sugarme/gotch/tensor"
 )
@@ -14,6 +17,22 @@ type Optimizer struct {
 	// variables            Variables // having embedded sync.Mutex
 	variablesInOptimizer uint8
 	config               interface{}
+
+	// parameters are the trainable variables of the var-store. They are added
+	// to the C optimizer on the first `ZeroGrad` or `Step` call, those of
+	// `paramGroups` in their own group, the others in the default group.
+	parameters     []ts.Tensor
+	parameterNames []string // var-store names of parameters
+	paramGroups    []paramGroup
+	added          bool
+	lr             float64 // current learning rate, see `SetLR`
+}
+
+// paramGroup is a set of variables optimized with their own learning rate.
+type paramGroup struct {
+	variables []ts.Tensor
+	lr        float64
+	optLR     float64 // optimizer learning rate when the group was added
 }
 
 // OptimizerConfig defines Optimizer configurations. These configs can be used to build optimizer.
@@ -40,16 +59,13 @@ func defaultBuild(config OptimizerConfig, vs *VarStore, lr float64) (retVal *Opt
 		return retVal, err
 	}
 
-	var parameters []ts.Tensor
-	for _, v := range vs.Vars.TrainableVariables {
-		param := v.MustShallowClone()
-		parameters = append(parameters, *param)
-	}
-
-	if len(vs.Vars.TrainableVariables) > 0 {
-		if err = opt.AddParameters(vs.Vars.TrainableVariables); err != nil {
-			return retVal, err
-		}
+	// NOTE. parameters are added to the C optimizer lazily so that they can
+	// be assigned to parameter groups first (see `AddParamGroup`). They are
+	// sorted by name so that a saved optimizer state matches the variables of
+	// the same name (see `SaveState`).
+	parameterNames, parameters, err := vs.namedTrainableVariables("")
+	if err != nil {
+		return retVal, err
 	}
 
 	// TODO: should we clone or copy?
@@ -59,6 +75,9 @@ func defaultBuild(config OptimizerConfig, vs *VarStore, lr float64) (retVal *Opt
 		// variables:            vs.Vars,
 		variablesInOptimizer: uint8(len(vs.Vars.TrainableVariables)),
 		config:               config,
+		parameters:           parameters,
+		parameterNames:       parameterNames,
+		lr:                   lr,
 	}, nil
 }
 
@@ -66,6 +85,11 @@ func defaultBuild(config OptimizerConfig, vs *VarStore, lr float64) (retVal *Opt
 //===============
 
 // SGDConfig holds parameters for building the SGD (Stochastic Gradient Descent) optimizer.
+//
+// With momentum, the velocity is updated as `v = Momentum * v + (1 - Dampening) * g`
+// and parameters as `w = w - lr * v`. If Nesterov is set, the look-ahead
+// gradient is used instead: `w = w - lr * (g + Momentum * v)`. Nesterov
+// momentum requires a positive Momentum and zero Dampening.
 type SGDConfig struct {
 	Momentum  float64
 	Dampening float64
@@ -180,24 +204,133 @@ func (c *RMSPropConfig) Build(vs *VarStore, lr float64) (*Optimizer, error) {
 	return defaultBuild(c, vs, lr)
 }
 
+// Adagrad optimizer:
+// ==================
+
+// AdagradConfig holds parameters for building the Adagrad optimizer.
+//
+// Each parameter is updated with its own learning rate
+// `lr / (1 + (step - 1) * LrDecay) / (sqrt(sum of squared gradients) + Eps)`,
+// which shrinks faster for frequently updated parameters.
+type AdagradConfig struct {
+	LrDecay float64
+	Wd      float64
+	Eps     float64
+}
+
+// DefaultAdagradConfig creates AdagradConfig with default values.
+func DefaultAdagradConfig() *AdagradConfig {
+	return &AdagradConfig{
+		LrDecay: 0.0,
+		Wd:      0.0,
+		Eps:     1e-10,
+	}
+}
+
+// NewAdagradConfig creates AdagradConfig with specified values.
+func NewAdagradConfig(lrDecay, wd, eps float64) *AdagradConfig {
+	return &AdagradConfig{
+		LrDecay: lrDecay,
+		Wd:      wd,
+		Eps:     eps,
+	}
+}
+
+// Implement OptimizerConfig interface for AdagradConfig
+func (c *AdagradConfig) buildCOpt(lr float64) (*ts.COptimizer, error) {
+	return ts.Adagrad(lr, c.LrDecay, c.Wd, c.Eps)
+}
+
+func (c *AdagradConfig) Build(vs *VarStore, lr float64) (*Optimizer, error) {
+	return defaultBuild(c, vs, lr)
+}
+
+// NewAdagrad builds an Adagrad optimizer for the variables of vs.
+func NewAdagrad(vs *VarStore, lr, lrDecay, weightDecay, eps float64) (*Optimizer, error) {
+	return NewAdagradConfig(lrDecay, weightDecay, eps).Build(vs, lr)
+}
+
 // Optimizer methods:
 // ==================
 func (opt *Optimizer) addMissingVariables() {
+	if opt.added {
+		return
+	}
+	opt.added = true
 
-	// missingVariables := len(opt.variables.TrainableVariables) - int(opt.variablesInOptimizer)
-	//
-	// if missingVariables > 0 {
-	// var tensors []ts.Tensor
-	// for _, t := range opt.variables.TrainableVariables[opt.variablesInOptimizer:] {
-	// tensor := t.MustShallowClone()
-	// tensor.Detach_()
-	// tensors = append(tensors, tensor)
-	// }
-	//
-	// opt.opt.AddParameters(tensors)
-	// opt.variablesInOptimizer = uint8(len(opt.variables.TrainableVariables))
-	// }
+	var defaultGroup []ts.Tensor
+	for _, v := range opt.parameters {
+		if !opt.inParamGroup(v) {
+			defaultGroup = append(defaultGroup, v)
+		}
+	}
+
+	if len(defaultGroup) > 0 {
+		if err := opt.opt.AddParameters(defaultGroup); err != nil {
+			log.Fatalf("Optimizer - AddParameters error: %v\n", err)
+		}
+	}
+
+	for i, g := range opt.paramGroups {
+		group := uint(i + 1)
+		for n := range g.variables {
+			if err := opt.opt.AddParameter(&g.variables[n], group); err != nil {
+				log.Fatalf("Optimizer - AddParameter error: %v\n", err)
+			}
+		}
+	}
+	opt.setGroupLRs()
+}
+
+// setGroupLRs sets the learning rate of parameter groups in the C optimizer,
+// scaled as the optimizer learning rate since the groups were added.
+func (opt *Optimizer) setGroupLRs() {
+	for i, g := range opt.paramGroups {
+		lr := g.lr
+		if g.optLR != 0 {
+			lr *= opt.lr / g.optLR
+		}
+
+		if err := opt.opt.SetLearningRateGroup(uint(i+1), lr); err != nil {
+			log.Fatalf("Optimizer - SetLearningRateGroup error: %v\n", err)
+		}
+	}
+}
+
+// inParamGroup returns true if v was assigned to a parameter group.
+func (opt *Optimizer) inParamGroup(v ts.Tensor) bool {
+	for _, g := range opt.paramGroups {
+		for _, gv := range g.variables {
+			if gv == v {
+				return true
+			}
+		}
+	}
 
+	return false
+}
+
+// AddParamGroup optimizes vars with their own learning rate lr. The other
+// variables keep the optimizer learning rate. Later `SetLR` calls scale lr
+// as the optimizer learning rate.
+//
+// vars should be variables of the var-store the optimizer was built from,
+// e.g. obtained with `VarStore.TrainableVariablesMatching`. It must be called
+// before the first `ZeroGrad` or `Step` call.
+func (opt *Optimizer) AddParamGroup(vars []ts.Tensor, lr float64) {
+	if opt.added {
+		log.Fatalf("Optimizer - AddParamGroup should be called before the first ZeroGrad or Step call\n")
+	}
+
+	for _, v := range vars {
+		if opt.inParamGroup(v) {
+			log.Fatalf("Optimizer - AddParamGroup: variable already in a parameter group\n")
+		}
+	}
+
+	variables := make([]ts.Tensor, len(vars))
+	copy(variables, vars)
+	opt.paramGroups = append(opt.paramGroups, paramGroup{variables: variables, lr: lr, optLR: opt.lr})
 }
 
 // ZeroGrad zeroes the gradient for the tensors tracked by this optimizer.
@@ -208,15 +341,30 @@ func (opt *Optimizer) ZeroGrad() {
 	}
 }
 
-// Clips gradient value at some specified maximum value.
+// ClipGradValue clamps every gradient element of the tracked tensors to
+// [-max, max] in place.
 func (opt *Optimizer) ClipGradValue(max float64) {
+	clampGrads(opt.parameters, max)
+}
 
-	// opt.variables.mutex.Lock()
-	// defer opt.variables.mutex.Unlock()
+// ClipGradValue clamps every gradient element of the trainable variables of
+// vs to [-clipValue, clipValue] in place.
+//
+// Unlike norm clipping, each element is clipped independently so the
+// gradient direction may change.
+func ClipGradValue(vs *VarStore, clipValue float64) {
+	clampGrads(trainableVariables(vs), clipValue)
+}
 
-	// for _, tensor := range opt.variables.TrainableVariables {
-	// tensor.MustGrad().Clamp_(ts.FloatScalar(-max), ts.FloatScalar(max))
-	// }
+// clampGrads clamps the defined gradients of vars to [-max, max] in place.
+func clampGrads(vars []ts.Tensor, max float64) {
+	for _, v := range vars {
+		grad := v.MustGrad(false)
+		if grad.MustDefined() {
+			grad.MustClamp_(ts.FloatScalar(-max), ts.FloatScalar(max))
+		}
+		grad.MustDrop()
+	}
 }
 
 // Step performs an optimization step, updating the tracked tensors based on their gradients.
@@ -247,9 +395,25 @@ func (opt *Optimizer) BackwardStep(loss *ts.Tensor) {
 
 }
 
+// TrainStep runs a whole training step: it computes the loss of model on
+// input with lossFn, zeroes gradients, back-propagates and steps opt. It
+// returns the scalar loss value.
+func TrainStep(model ts.Module, opt *Optimizer, input, target *ts.Tensor, lossFn func(pred, target *ts.Tensor) *ts.Tensor) float64 {
+	pred := model.Forward(input)
+	loss := lossFn(pred, target)
+	pred.MustDrop()
+
+	opt.BackwardStep(loss)
+	retVal := loss.Float64Values()[0]
+	loss.MustDrop()
+
+	return retVal
+}
+
 // BackwardStepClip applies a backward step pass, update the gradients, and performs an optimization step.
 //
-// The gradients are clipped based on `max` before being applied.
+// The gradients are clipped to [-max, max] before being applied (see
+// `Optimizer.ClipGradValue`).
 func (opt *Optimizer) BackwardStepClip(loss *ts.Tensor, max float64) {
 	opt.addMissingVariables()
 
@@ -269,11 +433,19 @@ func (opt *Optimizer) BackwardStepClip(loss *ts.Tensor, max float64) {
 }
 
 // SetLR sets the optimizer learning rate.
+//
+// The learning rates of parameter groups are scaled by the same factor, so
+// that a schedule keeps their ratio to the optimizer learning rate.
 func (opt *Optimizer) SetLR(lr float64) {
 	err := opt.opt.SetLearningRate(lr)
 	if err != nil {
 		log.Fatalf("Optimizer - SetLR  method call error: %v\n", err)
 	}
+
+	opt.lr = lr
+	if opt.added {
+		opt.setGroupLRs()
+	}
 }
 
 // SetMomentum sets the optimizer momentum.
@@ -283,3 +455,78 @@ func (opt *Optimizer) SetMomentum(m float64) {
 		log.Fatalf("Optimizer - SetMomentum  method call error: %v\n", err)
 	}
 }
+
+// SaveState saves the optimizer internal state, e.g. momentum buffers and
+// step counts, to a file so that training can be resumed exactly.
+//
+// NOTE. per-parameter states are stored by position in the C optimizer, not
+// by variable name. The names of the variables of each parameter group, in
+// this order, are saved to path + ".names" so that `LoadState` can check that
+// they match.
+func (opt *Optimizer) SaveState(path string) error {
+	opt.addMissingVariables()
+
+	if err := opt.opt.Save(path); err != nil {
+		return err
+	}
+
+	layout := strings.Join(opt.stateLayout(), "\n") + "\n"
+
+	return ioutil.WriteFile(path+".names", []byte(layout), 0644)
+}
+
+// LoadState loads an optimizer state saved with `SaveState`.
+//
+// The optimizer should be built from a var-store with the same trainable
+// variables and parameter groups as the one the state was saved from. An
+// error is returned if variables were added, removed, renamed or assigned to
+// other groups since, as states would be applied to the wrong variables.
+func (opt *Optimizer) LoadState(path string) error {
+	opt.addMissingVariables()
+
+	data, err := ioutil.ReadFile(path + ".names")
+	if err != nil {
+		return err
+	}
+	saved := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	current := opt.stateLayout()
+	if len(saved) != len(current) {
+		err := fmt.Errorf("Optimizer - LoadState: state saved for %v variables, got %v\n", len(saved), len(current))
+		return err
+	}
+	for i := range saved {
+		if saved[i] != current[i] {
+			err := fmt.Errorf("Optimizer - LoadState: state saved for variable %q, got %q at position %v\n", saved[i], current[i], i)
+			return err
+		}
+	}
+
+	return opt.opt.Load(path)
+}
+
+// stateLayout returns the parameter group and name of the variables in the
+// order they were added to the C optimizer (see `addMissingVariables`), e.g.
+// "0 linear.weight".
+func (opt *Optimizer) stateLayout() []string {
+	var layout []string
+	for i, v := range opt.parameters {
+		if !opt.inParamGroup(v) {
+			layout = append(layout, fmt.Sprintf("0 %v", opt.parameterNames[i]))
+		}
+	}
+
+	for i, g := range opt.paramGroups {
+		for _, gv := range g.variables {
+			name := "?"
+			for n, v := range opt.parameters {
+				if v == gv {
+					name = opt.parameterNames[n]
+					break
+				}
+			}
+			layout = append(layout, fmt.Sprintf("%v %v", i+1, name))
+		}
+	}
+
+	return layout
+}
@@ -1,5 +1,17 @@
 package nn_test
 
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
 /*
  * import (
  *   // "reflect"
@@ -67,3 +79,338 @@ package nn_test
  *     t.Errorf("Expect initial loss < 0.25, got %v", finalLoss)
  *   }
  * } */
+
+func TestOptimizerParamGroup(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	body := nn.NewLinear(vs.Root().Sub("body"), 2, 1, nn.DefaultLinearConfig())
+	head := nn.NewLinear(vs.Root().Sub("head"), 2, 1, nn.DefaultLinearConfig())
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headVars, err := vs.TrainableVariablesMatching("^head\\.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headVars) != 2 {
+		t.Fatalf("Expected 2 head variables, got %v\n", len(headVars))
+	}
+	opt.AddParamGroup(headVars, 1.0)
+
+	bodyBefore := body.Ws.Float64Values()
+	headBefore := head.Ws.Float64Values()
+
+	// d(sum(xs @ wT + b))/dw = xs for both layers.
+	xs := ts.MustOfSlice([]float32{1, 2}).MustView([]int64{1, 2}, true)
+	loss := body.Forward(xs).MustAdd(head.Forward(xs), true).MustSum(gotch.Float, true)
+	opt.BackwardStep(loss)
+
+	bodyAfter := body.Ws.Float64Values()
+	headAfter := head.Ws.Float64Values()
+	grads := []float64{1, 2}
+	for i, g := range grads {
+		if got, want := bodyBefore[i]-bodyAfter[i], 0.1*g; math.Abs(got-want) > 1e-5 {
+			t.Errorf("Expected body weight %v to move by %v, got %v\n", i, want, got)
+		}
+		if got, want := headBefore[i]-headAfter[i], 1.0*g; math.Abs(got-want) > 1e-5 {
+			t.Errorf("Expected head weight %v to move by %v, got %v\n", i, want, got)
+		}
+	}
+}
+
+func TestOptimizerParamGroupSetLR(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	body := nn.NewLinear(vs.Root().Sub("body"), 2, 1, nn.DefaultLinearConfig())
+	head := nn.NewLinear(vs.Root().Sub("head"), 2, 1, nn.DefaultLinearConfig())
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headVars, err := vs.TrainableVariablesMatching("^head\\.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt.AddParamGroup(headVars, 1.0)
+
+	xs := ts.MustOfSlice([]float32{1, 2}).MustView([]int64{1, 2}, true)
+	step := func() {
+		loss := body.Forward(xs).MustAdd(head.Forward(xs), true).MustSum(gotch.Float, true)
+		opt.BackwardStep(loss)
+		loss.MustDrop()
+	}
+
+	// Scheduler ticks before and after the parameters are added to the C
+	// optimizer halve all learning rates.
+	opt.SetLR(0.05)
+	step()
+	opt.SetLR(0.025)
+
+	bodyBefore := body.Ws.Float64Values()
+	headBefore := head.Ws.Float64Values()
+	step()
+	bodyAfter := body.Ws.Float64Values()
+	headAfter := head.Ws.Float64Values()
+
+	grads := []float64{1, 2}
+	for i, g := range grads {
+		if got, want := bodyBefore[i]-bodyAfter[i], 0.025*g; math.Abs(got-want) > 1e-5 {
+			t.Errorf("Expected body weight %v to move by %v, got %v\n", i, want, got)
+		}
+		if got, want := headBefore[i]-headAfter[i], 0.25*g; math.Abs(got-want) > 1e-5 {
+			t.Errorf("Expected head weight %v to move by %v, got %v\n", i, want, got)
+		}
+	}
+}
+
+func TestOptimizerSaveLoadState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-optimizer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newModel := func() (*nn.VarStore, *nn.Linear, *nn.Optimizer) {
+		vs := nn.NewVarStore(gotch.CPU)
+		linear := nn.NewLinear(vs.Root(), 3, 2, nn.DefaultLinearConfig())
+		opt, err := nn.DefaultAdamConfig().Build(vs, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vs, linear, opt
+	}
+
+	inputs := make([]*ts.Tensor, 4)
+	for i := range inputs {
+		inputs[i] = ts.MustRandn([]int64{5, 3}, gotch.Float, gotch.CPU)
+	}
+	step := func(linear *nn.Linear, opt *nn.Optimizer, xs *ts.Tensor) {
+		loss := linear.Forward(xs).MustPow(ts.FloatScalar(2), true).MustSum(gotch.Float, true)
+		opt.BackwardStep(loss)
+	}
+
+	vs, linear, opt := newModel()
+	for _, xs := range inputs[:3] {
+		step(linear, opt, xs)
+	}
+	path := filepath.Join(dir, "optimizer.pt")
+	if err := opt.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+
+	resumedVs, resumedLinear, resumedOpt := newModel()
+	if err := resumedVs.Copy(*vs); err != nil {
+		t.Fatal(err)
+	}
+	if err := resumedOpt.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+
+	step(linear, opt, inputs[3])
+	step(resumedLinear, resumedOpt, inputs[3])
+
+	want := linear.Ws.Float64Values()
+	got := resumedLinear.Ws.Float64Values()
+	for i := range want {
+		if math.Abs(want[i]-got[i]) > 1e-6 {
+			t.Errorf("Expected resumed weight %v to be %v, got %v\n", i, want[i], got[i])
+		}
+	}
+
+	// States are stored by position: loading them for other variables fails.
+	otherVs := nn.NewVarStore(gotch.CPU)
+	nn.NewLinear(otherVs.Root(), 3, 2, nn.DefaultLinearConfig())
+	otherVs.Root().Zeros("extra", []int64{2})
+	otherOpt, err := nn.DefaultAdamConfig().Build(otherVs, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := otherOpt.LoadState(path); err == nil {
+		t.Errorf("Expected an error loading a state saved for other variables\n")
+	}
+}
+
+func TestAdagrad(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{2})
+	target := ts.MustOfSlice([]float32{3, -1})
+
+	opt, err := nn.NewAdagrad(vs, 0.1, 0, 0, 1e-10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Convex loss sum((w - target)^2).
+	lossValue := func() float64 {
+		return w.MustSub(target, false).MustPow(ts.FloatScalar(2), true).MustSum(gotch.Double, true).Float64Values()[0]
+	}
+
+	initialLoss := lossValue()
+	prevLR := math.Inf(1)
+	for step := 0; step < 10; step++ {
+		before := w.Float64Values()
+		loss := w.MustSub(target, false).MustPow(ts.FloatScalar(2), true).MustSum(gotch.Float, true)
+		opt.BackwardStep(loss)
+		after := w.Float64Values()
+		grad := w.MustGrad(false).Float64Values()
+
+		// Effective learning rate of the first parameter: |dw| / |g|.
+		lr := math.Abs(after[0]-before[0]) / math.Abs(grad[0])
+		if lr >= prevLR {
+			t.Errorf("Step %v - Expected the effective learning rate to shrink, got %v after %v\n", step, lr, prevLR)
+		}
+		prevLR = lr
+	}
+
+	if finalLoss := lossValue(); finalLoss >= initialLoss {
+		t.Errorf("Expected the loss to decrease from %v, got %v\n", initialLoss, finalLoss)
+	}
+}
+
+func TestSGDNesterov(t *testing.T) {
+	const (
+		lr       = 0.1
+		momentum = 0.9
+		steps    = 10
+	)
+
+	// Minimizes 0.5 * w^2 from w = 1, i.e. g = w.
+	trajectory := func(nesterov bool) []float64 {
+		vs := nn.NewVarStore(gotch.CPU)
+		w := vs.Root().Ones("w", []int64{1})
+		opt, err := nn.NewSGDConfig(momentum, 0, 0, nesterov).Build(vs, lr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var values []float64
+		for i := 0; i < steps; i++ {
+			loss := w.MustMul(w, false).MustMul1(ts.FloatScalar(0.5), true).MustSum(gotch.Float, true)
+			opt.BackwardStep(loss)
+			values = append(values, w.Float64Values()[0])
+		}
+		return values
+	}
+
+	// Reference updates documented in SGDConfig.
+	reference := func(nesterov bool) []float64 {
+		var (
+			w      = 1.0
+			v      = 0.0
+			values []float64
+		)
+		for i := 0; i < steps; i++ {
+			g := w
+			v = momentum*v + g
+			if nesterov {
+				w -= lr * (g + momentum*v)
+			} else {
+				w -= lr * v
+			}
+			values = append(values, w)
+		}
+		return values
+	}
+
+	standard, nesterov := trajectory(false), trajectory(true)
+	for name, pair := range map[string][2][]float64{
+		"standard": {reference(false), standard},
+		"nesterov": {reference(true), nesterov},
+	} {
+		want, got := pair[0], pair[1]
+		for i := range want {
+			if math.Abs(want[i]-got[i]) > 1e-5 {
+				t.Errorf("%v - Step %v: expected w = %v, got %v\n", name, i, want[i], got[i])
+			}
+		}
+	}
+
+	// The look-ahead takes larger steps at first then damps oscillations.
+	if nesterov[0] >= standard[0] {
+		t.Errorf("Expected a larger first Nesterov step: got w = %v, standard w = %v\n", nesterov[0], standard[0])
+	}
+	if math.Abs(nesterov[steps-1]) >= math.Abs(standard[steps-1]) {
+		t.Errorf("Expected Nesterov to end closer to the minimum: got |w| = %v, standard |w| = %v\n", math.Abs(nesterov[steps-1]), math.Abs(standard[steps-1]))
+	}
+}
+
+func TestTrainStep(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 2, 8, nn.DefaultRNNConfig())
+	linear := nn.NewLinear(vs.Root().Sub("linear"), 8, 1, nn.DefaultLinearConfig())
+	model := nn.NewFunc(func(xs *ts.Tensor) *ts.Tensor {
+		output, state := lstm.Seq(xs)
+		state.(*nn.LSTMState).Tensor1.MustDrop()
+		state.(*nn.LSTMState).Tensor2.MustDrop()
+		return output.Apply(linear)
+	})
+
+	opt, err := nn.DefaultAdamConfig().Build(vs, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Predict the sum of both input features at each timestep.
+	input := ts.MustRandn([]int64{8, 5, 2}, gotch.Float, gotch.CPU)
+	target := input.MustSum1([]int64{2}, true, gotch.Float, false)
+	mse := func(pred, target *ts.Tensor) *ts.Tensor {
+		return pred.MustMseLoss(target, int64(ts.ReductionMean), false)
+	}
+
+	first := nn.TrainStep(model, opt, input, target, mse)
+	var last float64
+	for i := 0; i < 50; i++ {
+		last = nn.TrainStep(model, opt, input, target, mse)
+	}
+	if last >= first/2 {
+		t.Errorf("Expected loss to decrease, got %v after the first step and %v after 50 more\n", first, last)
+	}
+}
+
+func TestClipGradValue(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{4})
+
+	// The gradient of sum(w * coeffs) w.r.t. w is coeffs.
+	coeffs := ts.MustOfSlice([]float32{5, 0.5, -5, -0.5})
+	loss := w.MustMul(coeffs, false).MustSum(gotch.Float, true)
+	loss.MustBackward()
+
+	nn.ClipGradValue(vs, 1)
+
+	want := []float64{1, 0.5, -1, -0.5}
+	got := w.MustGrad(false).Float64Values()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Errorf("Expected clipped gradient %v, got %v\n", want, got)
+			break
+		}
+	}
+}
+
+func TestBackwardStepClip(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{4})
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coeffs := ts.MustOfSlice([]float32{5, 0.5, -5, -0.5})
+	loss := w.MustMul(coeffs, false).MustSum(gotch.Float, true)
+	opt.BackwardStepClip(loss, 1)
+
+	// w = -0.1 * clamp(coeffs, -1, 1)
+	want := []float64{-0.1, -0.05, 0.1, 0.05}
+	got := w.Float64Values()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Errorf("Expected weights %v after a clipped step, got %v\n", want, got)
+			break
+		}
+	}
+}
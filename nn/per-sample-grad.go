@@ -0,0 +1,57 @@
+package nn
+
+// Per-sample gradient clipping for differentially private training.
+
+import (
+	"log"
+	"math"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// PerSampleGradClip computes the gradients of lossFn w.r.t. the trainable
+// variables of vs separately for each sample of microBatch, clips each of
+// them to an L2 norm (over all variables) of at most clipNorm and returns
+// their sum, in the order of the trainable variables.
+//
+// Samples are taken along the first dimension of microBatch and passed to
+// lossFn with a batch dimension of size 1. This is the gradient computation
+// of DP-SGD, to which noise is then added. It does not modify the variables
+// gradients. Frozen variables are ignored.
+//
+// Ref. Abadi et al., "Deep Learning with Differential Privacy", 2016.
+// https://arxiv.org/abs/1607.00133
+func PerSampleGradClip(vs *VarStore, microBatch ts.Tensor, lossFn func(sample *ts.Tensor) *ts.Tensor, clipNorm float64) []ts.Tensor {
+	if clipNorm <= 0 {
+		log.Fatalf("PerSampleGradClip - Expected a positive clipping norm, got %v\n", clipNorm)
+	}
+
+	vars := gradVariables(vs)
+	sum := make([]ts.Tensor, len(vars))
+	for n := range vars {
+		sum[n] = *vars[n].MustZerosLike(false)
+	}
+
+	batchDim := microBatch.MustSize()[0]
+	for i := int64(0); i < batchDim; i++ {
+		sample := microBatch.MustNarrow(0, i, 1, false)
+		loss := lossFn(sample)
+		grads := taskGradients(loss, vars)
+		loss.MustDrop()
+		sample.MustDrop()
+
+		// g / max(1, |g| / clipNorm)
+		norm := math.Sqrt(gradDot(grads, grads))
+		scale := 1 / math.Max(1, norm/clipNorm)
+		ts.NoGrad(func() {
+			for n := range grads {
+				scaled := grads[n].MustMul1(ts.FloatScalar(scale), false)
+				sum[n].MustAdd_(scaled)
+				scaled.MustDrop()
+			}
+		})
+		dropGrads(grads)
+	}
+
+	return sum
+}
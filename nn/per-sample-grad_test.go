@@ -0,0 +1,67 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestPerSampleGradClip(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	w := vs.Root().Zeros("w", []int64{2})
+
+	// The gradient of sum(w * x) w.r.t. w is x: the first sample gradient has
+	// norm 5 and the second one norm 0.05.
+	microBatch := ts.MustOfSlice([]float32{3, 4, 0.03, 0.04}).MustView([]int64{2, 2}, true)
+	lossFn := func(sample *ts.Tensor) *ts.Tensor {
+		return sample.MustMul(w, false).MustSum(gotch.Float, true)
+	}
+
+	grads := nn.PerSampleGradClip(vs, *microBatch, lossFn, 1)
+	if len(grads) != 1 {
+		t.Fatalf("Expected 1 gradient, got %v\n", len(grads))
+	}
+
+	// Only the first gradient is clipped, to (0.6, 0.8).
+	want := []float64{0.63, 0.84}
+	got := grads[0].Float64Values()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-5 {
+			t.Errorf("Expected summed clipped gradient %v, got %v\n", want, got)
+			break
+		}
+	}
+
+	if grad := w.MustGrad(false); grad.MustDefined() {
+		t.Errorf("Expected variables gradients to be left untouched, got %v\n", grad.Float64Values())
+	}
+}
+
+func TestPerSampleGradClipFrozen(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	frozen := vs.Root().Ones("frozen", []int64{2})
+	frozen.MustRequiresGrad_(false)
+	w := vs.Root().Zeros("w", []int64{2})
+
+	microBatch := ts.MustOfSlice([]float32{3, 4}).MustView([]int64{1, 2}, true)
+	lossFn := func(sample *ts.Tensor) *ts.Tensor {
+		return sample.MustMul(w, false).MustMul(frozen, true).MustSum(gotch.Float, true)
+	}
+
+	grads := nn.PerSampleGradClip(vs, *microBatch, lossFn, 1)
+	if len(grads) != 1 {
+		t.Fatalf("Expected a gradient for the only unfrozen variable, got %v\n", len(grads))
+	}
+
+	want := []float64{0.6, 0.8}
+	got := grads[0].Float64Values()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-5 {
+			t.Errorf("Expected clipped gradient %v, got %v\n", want, got)
+			break
+		}
+	}
+}
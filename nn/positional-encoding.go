@@ -0,0 +1,93 @@
+package nn
+
+// Positional encodings for sequence models.
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// PositionalEncoding adds an encoding of the position of each timestep to a
+// [batch_size, seq_len, d_model] input.
+type PositionalEncoding struct {
+	Pe     *ts.Tensor // shape{maxLen, dModel}
+	MaxLen int64
+}
+
+// NewSinusoidalPositionalEncoding creates a fixed sinusoidal positional encoding:
+//
+//	PE(pos, 2i)   = sin(pos / 10000^(2i/dModel))
+//	PE(pos, 2i+1) = cos(pos / 10000^(2i/dModel))
+//
+// Ref. Vaswani et al., "Attention Is All You Need", 2017. https://arxiv.org/abs/1706.03762
+func NewSinusoidalPositionalEncoding(dModel, maxLen int64) *PositionalEncoding {
+	data := make([]float32, maxLen*dModel)
+	for pos := int64(0); pos < maxLen; pos++ {
+		for i := int64(0); i < dModel; i++ {
+			angle := float64(pos) / math.Pow(10000, float64(2*(i/2))/float64(dModel))
+			if i%2 == 0 {
+				data[pos*dModel+i] = float32(math.Sin(angle))
+			} else {
+				data[pos*dModel+i] = float32(math.Cos(angle))
+			}
+		}
+	}
+
+	pe, err := ts.NewTensorFromData(data, []int64{maxLen, dModel})
+	if err != nil {
+		log.Fatalf("NewSinusoidalPositionalEncoding - error: %v\n", err)
+	}
+
+	return &PositionalEncoding{
+		Pe:     pe,
+		MaxLen: maxLen,
+	}
+}
+
+// NewLearnedPositionalEncoding creates a trainable positional encoding.
+func NewLearnedPositionalEncoding(vs *Path, dModel, maxLen int64) *PositionalEncoding {
+	return &PositionalEncoding{
+		Pe:     vs.Uniform("weight", []int64{maxLen, dModel}, -0.1, 0.1),
+		MaxLen: maxLen,
+	}
+}
+
+// Encode adds the positional encoding to a [batch_size, seq_len, d_model] input.
+//
+// It returns an error if seq_len is greater than the maximum length.
+func (pe *PositionalEncoding) Encode(xs *ts.Tensor) (*ts.Tensor, error) {
+	size := xs.MustSize()
+	if len(size) != 3 {
+		err := fmt.Errorf("Expected input of shape [batch_size, seq_len, d_model], got %v\n", size)
+		return nil, err
+	}
+
+	seqLen := size[1]
+	if seqLen > pe.MaxLen {
+		err := fmt.Errorf("Sequence length (%v) exceeds positional encoding maximum length (%v)\n", seqLen, pe.MaxLen)
+		return nil, err
+	}
+
+	enc := pe.Pe.MustNarrow(0, 0, seqLen, false).MustTo(xs.MustDevice(), true)
+	retVal := xs.MustAdd(enc, false)
+	enc.MustDrop()
+
+	return retVal, nil
+}
+
+// Implement Module interface for PositionalEncoding:
+// ==================================================
+
+// Forward adds the positional encoding to the input. It panics if the input
+// sequence is longer than the maximum length.
+func (pe *PositionalEncoding) Forward(xs *ts.Tensor) *ts.Tensor {
+	retVal, err := pe.Encode(xs)
+	if err != nil {
+		log.Fatalf("PositionalEncoding - Forward method call error: %v\n", err)
+	}
+
+	return retVal
+}
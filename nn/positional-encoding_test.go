@@ -0,0 +1,78 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestSinusoidalPositionalEncoding(t *testing.T) {
+	var (
+		dModel int64 = 6
+		maxLen int64 = 8
+		seqLen int64 = 5
+	)
+
+	pe := nn.NewSinusoidalPositionalEncoding(dModel, maxLen)
+
+	input := ts.MustZeros([]int64{2, seqLen, dModel}, gotch.Float, gotch.CPU)
+	output := pe.Forward(input)
+	vals := output.Float64Values()
+
+	// PE(0) = [sin(0), cos(0), ...]
+	for i, want := range []float64{0, 1, 0, 1, 0, 1} {
+		if got := vals[i]; math.Abs(want-got) > 1e-6 {
+			t.Errorf("Expected PE(0, %v): %v, got %v\n", i, want, got)
+		}
+	}
+
+	// The first pair of dimensions has frequency 1: PE(pos, 0) = sin(pos) and
+	// PE(pos, 1) = cos(pos).
+	for pos := int64(1); pos < seqLen; pos++ {
+		if want, got := math.Sin(float64(pos)), vals[pos*dModel]; math.Abs(want-got) > 1e-6 {
+			t.Errorf("Expected PE(%v, 0): %v, got %v\n", pos, want, got)
+		}
+		if want, got := math.Cos(float64(pos)), vals[pos*dModel+1]; math.Abs(want-got) > 1e-6 {
+			t.Errorf("Expected PE(%v, 1): %v, got %v\n", pos, want, got)
+		}
+	}
+
+	// The encoding is added to every sequence of the batch.
+	ones := ts.MustOnes([]int64{2, seqLen, dModel}, gotch.Float, gotch.CPU)
+	shifted := pe.Forward(ones)
+	if want, got := ones.MustSize(), shifted.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape %v, got %v\n", want, got)
+	}
+	shiftedVals := shifted.Float64Values()
+	offset := seqLen * dModel
+	for i := int64(0); i < offset; i++ {
+		if want, got := vals[i]+1, shiftedVals[offset+i]; math.Abs(want-got) > 1e-6 {
+			t.Errorf("Expected second sequence value %v at %v, got %v\n", want, i, got)
+		}
+	}
+
+	tooLong := ts.MustZeros([]int64{2, maxLen + 1, dModel}, gotch.Float, gotch.CPU)
+	if _, err := pe.Encode(tooLong); err == nil {
+		t.Errorf("Expected error for sequence longer than maxLen\n")
+	}
+
+	flat := ts.MustZeros([]int64{seqLen, dModel}, gotch.Float, gotch.CPU)
+	if _, err := pe.Encode(flat); err == nil {
+		t.Errorf("Expected error for input without batch dimension\n")
+	}
+}
+
+func TestLearnedPositionalEncoding(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	pe := nn.NewLearnedPositionalEncoding(vs.Root(), 6, 8)
+
+	input := ts.MustZeros([]int64{2, 5, 6}, gotch.Float, gotch.CPU)
+	output := pe.Forward(input)
+	if !output.MustRequiresGrad() {
+		t.Errorf("Expected learned positional encoding to be trainable\n")
+	}
+}
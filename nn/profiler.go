@@ -0,0 +1,127 @@
+package nn
+
+// Wall-clock profiling of layers.
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// Profiler records the wall-clock time spent in each layer of a model, e.g.
+// see `RNNSequential.SetProfiler`. Durations accumulate over calls.
+type Profiler struct {
+	mu        sync.Mutex
+	durations map[int]time.Duration
+}
+
+// NewProfiler creates a new empty Profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{durations: make(map[int]time.Duration)}
+}
+
+// Report returns the accumulated duration of each layer by layer index.
+func (p *Profiler) Report() map[int]time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	retVal := make(map[int]time.Duration, len(p.durations))
+	for k, v := range p.durations {
+		retVal[k] = v
+	}
+
+	return retVal
+}
+
+// Reset clears recorded durations.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.durations = make(map[int]time.Duration)
+}
+
+func (p *Profiler) record(layer int, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.durations[layer] += d
+}
+
+// synchronize waits for pending CUDA kernels on device to complete so that
+// they are accounted to the right layer.
+func (p *Profiler) synchronize(device gotch.Device) {
+	synchronize(device)
+}
+
+// synchronize waits for pending CUDA kernels on device to complete.
+//
+// NOTE. Copying a tensor to CPU blocks until the current CUDA stream is done.
+func synchronize(device gotch.Device) {
+	if !device.IsCuda() {
+		return
+	}
+
+	x := ts.MustZeros([]int64{1}, gotch.Float, device)
+	x.Float64Values()
+	x.MustDrop()
+}
+
+// ThroughputMeter measures the number of tokens processed per second by a
+// forward loop. Padding is not counted: tokens are counted from the true
+// sequence lengths of each batch.
+type ThroughputMeter struct {
+	Device gotch.Device // synchronized before and after each measure
+
+	tokens  int64
+	elapsed time.Duration
+}
+
+// NewThroughputMeter creates a new ThroughputMeter for a model running on
+// device.
+func NewThroughputMeter(device gotch.Device) *ThroughputMeter {
+	return &ThroughputMeter{Device: device}
+}
+
+// Measure runs fn, e.g. a forward pass over a batch, and accumulates its
+// wall-clock time and the sum of lengths, the true length of each sequence
+// of the batch, as the number of processed tokens.
+func (m *ThroughputMeter) Measure(lengths []int64, fn func()) {
+	synchronize(m.Device)
+	start := time.Now()
+	fn()
+	synchronize(m.Device)
+	m.elapsed += time.Since(start)
+
+	for _, l := range lengths {
+		m.tokens += l
+	}
+}
+
+// Tokens returns the number of tokens processed so far.
+func (m *ThroughputMeter) Tokens() int64 {
+	return m.tokens
+}
+
+// Elapsed returns the time spent in measured calls so far.
+func (m *ThroughputMeter) Elapsed() time.Duration {
+	return m.elapsed
+}
+
+// TokensPerSec returns the number of tokens processed per second, 0 if
+// nothing has been measured.
+func (m *ThroughputMeter) TokensPerSec() float64 {
+	if m.elapsed == 0 {
+		return 0
+	}
+
+	return float64(m.tokens) / m.elapsed.Seconds()
+}
+
+// Reset clears the measured tokens and time.
+func (m *ThroughputMeter) Reset() {
+	m.tokens = 0
+	m.elapsed = 0
+}
@@ -0,0 +1,41 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestThroughputMeter(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), 4, 8, nn.DefaultRNNConfig())
+	meter := nn.NewThroughputMeter(gotch.CPU)
+
+	// Batches of 3 sequences padded to 10 timesteps.
+	lengths := []int64{10, 7, 2}
+	input := ts.MustRandn([]int64{3, 10, 4}, gotch.Float, gotch.CPU)
+	for i := 0; i < 2; i++ {
+		meter.Measure(lengths, func() {
+			ts.NoGrad(func() {
+				output, state := lstm.Seq(input)
+				output.MustDrop()
+				state.(*nn.LSTMState).Tensor1.MustDrop()
+				state.(*nn.LSTMState).Tensor2.MustDrop()
+			})
+		})
+	}
+
+	if got := meter.Tokens(); got != 38 {
+		t.Errorf("Expected 38 tokens, padding excluded, got %v\n", got)
+	}
+	if rate := meter.TokensPerSec(); rate <= 0 {
+		t.Errorf("Expected a positive rate, got %v\n", rate)
+	}
+
+	meter.Reset()
+	if got := meter.TokensPerSec(); got != 0 {
+		t.Errorf("Expected no rate after reset, got %v\n", got)
+	}
+}
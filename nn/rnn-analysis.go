@@ -0,0 +1,304 @@
+package nn
+
+// Tools to analyse the behaviour of trained RNNs.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// rnnLayer is implemented by the LSTM, GRU, MinGRU and SRU layers.
+type rnnLayer interface {
+	// layerInfo returns the input feature dimension, config and device of the
+	// layer.
+	layerInfo() (inDim int64, config *RNNConfig, device gotch.Device)
+}
+
+// rnnInfo returns the input feature dimension, config and device of a LSTM,
+// GRU, MinGRU or SRU layer.
+func rnnInfo(rnn RNN) (int64, *RNNConfig, gotch.Device) {
+	l, ok := rnn.(rnnLayer)
+	if !ok {
+		log.Fatalf("Unsupported RNN type: %T\n", rnn)
+	}
+
+	return l.layerInfo()
+}
+
+// EffectiveContext estimates how far back the output of a (stacked) RNN
+// depends on its input.
+//
+// It feeds a random sequence of length seqLen and computes the gradient of
+// the last output step w.r.t. every input step. It returns the number of
+// timesteps whose gradient magnitude (L1 norm) is above threshold.
+//
+// NOTE. only LSTM, GRU, MinGRU and SRU layers are supported.
+func EffectiveContext(rnn RNN, seqLen int64, threshold float64) int64 {
+	inDim, config, device := rnnInfo(rnn)
+	if inDim == 0 {
+		log.Fatalf("RNN weights are not initialized yet. Run a forward pass first.\n")
+	}
+
+	seqDim := int64(1)
+	shape := []int64{1, seqLen, inDim}
+	if !config.BatchFirst {
+		seqDim = 0
+		shape = []int64{seqLen, 1, inDim}
+	}
+
+	x := ts.MustRandn(shape, gotch.Float, device)
+	input, err := x.SetRequiresGrad(true, true)
+	if err != nil {
+		log.Fatalf("EffectiveContext - SetRequiresGrad error: %v\n", err)
+	}
+	defer input.MustDrop()
+
+	output, state := rnn.Seq(input)
+	dropState(state)
+	last := output.MustSelect(seqDim, seqLen-1, true).MustSum(gotch.Float, true)
+
+	grads, err := ts.RunBackward([]ts.Tensor{*last}, []ts.Tensor{*input}, false, false)
+	if err != nil {
+		log.Fatalf("EffectiveContext - RunBackward error: %v\n", err)
+	}
+	last.MustDrop()
+
+	featDims := []int64{1 - seqDim, 2}
+	magnitudes := grads[0].MustAbs(true).MustSum1(featDims, false, gotch.Double, true)
+	values := magnitudes.Float64Values()
+	magnitudes.MustDrop()
+
+	var context int64
+	for _, v := range values {
+		if v > threshold {
+			context++
+		}
+	}
+
+	return context
+}
+
+// StepJacobian computes the Jacobian of the hidden state update
+// `d h_t / d h_{t-1}` of a single-layer unidirectional LSTM, for a single
+// sample, by autograd. The previous cell state is held fixed.
+//
+// input has shape [1, features] and state holds tensors of shape
+// [1, 1, hidden_dim]. It returns a tensor J of shape [hidden_dim, hidden_dim]
+// with `J[i][j] = d h_t[i] / d h_{t-1}[j]`.
+func (l *LSTM) StepJacobian(input *ts.Tensor, state State) *ts.Tensor {
+	if l.config.NumLayers*l.config.numDirections() != 1 {
+		log.Fatalf("StepJacobian - Expected a single-layer unidirectional LSTM\n")
+	}
+	if size := input.MustSize(); len(size) != 2 || size[0] != 1 {
+		log.Fatalf("StepJacobian - Expected an input of shape [1, features], got %v\n", size)
+	}
+
+	lstmState := state.(*LSTMState)
+	h := lstmState.Tensor1.MustDetach(false)
+	h0, err := h.SetRequiresGrad(true, true)
+	if err != nil {
+		log.Fatalf("StepJacobian - SetRequiresGrad error: %v\n", err)
+	}
+	defer h0.MustDrop()
+	c0 := lstmState.Tensor2.MustDetach(false)
+	defer c0.MustDrop()
+
+	newState := l.Step(input, &LSTMState{Tensor1: h0, Tensor2: c0}).(*LSTMState)
+	defer newState.Tensor2.MustDrop()
+	hNew := newState.Tensor1.MustView([]int64{-1}, true)
+	defer hNew.MustDrop()
+
+	rows := make([]ts.Tensor, l.hiddenDim)
+	for i := int64(0); i < l.hiddenDim; i++ {
+		hi := hNew.MustSelect(0, i, false)
+		grads, err := ts.RunBackward([]ts.Tensor{*hi}, []ts.Tensor{*h0}, true, false)
+		if err != nil {
+			log.Fatalf("StepJacobian - RunBackward error: %v\n", err)
+		}
+		hi.MustDrop()
+		rows[i] = *grads[0].MustView([]int64{-1}, true)
+	}
+
+	jacobian := ts.MustStack(rows, 0)
+	for _, r := range rows {
+		r.MustDrop()
+	}
+
+	return jacobian
+}
+
+// RecurrentSpectralRadius returns the spectral radius (largest eigenvalue
+// magnitude) of the recurrent weights w_hh of each gate, ordered by layer,
+// direction then gate (input, forget, cell, output).
+//
+// A radius above 1 hints at exploding dynamics. It is estimated with
+// Gelfand's formula `rho(W) = lim ||W^k||^(1/k)` by repeated squaring.
+func (l *LSTM) RecurrentSpectralRadius() []float64 {
+	if len(l.flatWeights) == 0 {
+		log.Fatalf("RecurrentSpectralRadius - Weights are not initialized yet. Run a forward pass first.\n")
+	}
+	refreshWeights(l.flatWeights, l.reparams)
+
+	var radii []float64
+	ts.NoGrad(func() {
+		for i := 1; i < len(l.flatWeights); i += 4 {
+			wHh := l.flatWeights[i].MustTotype(gotch.Double, false)
+			for gate := int64(0); gate < 4; gate++ {
+				w := wHh.MustNarrow(0, gate*l.hiddenDim, l.hiddenDim, false)
+				radii = append(radii, spectralRadius(w))
+				w.MustDrop()
+			}
+			wHh.MustDrop()
+		}
+	})
+
+	return radii
+}
+
+// spectralRadius estimates the spectral radius of a square matrix w from
+// W^(2^30). Powers are kept normalized while their log-scale is tracked.
+func spectralRadius(w *ts.Tensor) float64 {
+	const numSquarings = 30
+
+	var logScale float64
+	m := w.MustShallowClone()
+	for k := 0; k <= numSquarings; k++ {
+		if k > 0 {
+			m = m.MustMatmul(m, true)
+			logScale *= 2
+		}
+
+		norm := m.MustFrobeniusNorm(false).Float64Values()[0]
+		if norm == 0 {
+			m.MustDrop()
+			return 0
+		}
+		m = m.MustDiv1(ts.FloatScalar(norm), true)
+		logScale += math.Log(norm)
+	}
+	m.MustDrop()
+
+	return math.Exp(logScale / math.Pow(2, numSquarings))
+}
+
+// IntegratedGradients attributes the output of forwardFn to each timestep of
+// input with integrated gradients.
+//
+// forwardFn runs the model, e.g. an RNN followed by the selection of a target
+// logit, and its output is summed into a scalar. Gradients are averaged over
+// steps points on the straight path from baseline to input (a zero baseline
+// is used if it is nil), multiplied by input - baseline and summed over the
+// feature (last) axis. The result has the input shape without its last axis,
+// e.g. [batch_size, seq_len] for a batch-first sequence.
+//
+// Ref. Sundararajan et al., "Axiomatic Attribution for Deep Networks", 2017.
+// https://arxiv.org/abs/1703.01365
+func IntegratedGradients(forwardFn func(*ts.Tensor) *ts.Tensor, input, baseline *ts.Tensor, steps int64) *ts.Tensor {
+	if steps <= 0 {
+		log.Fatalf("IntegratedGradients - Expected a positive number of steps, got %v\n", steps)
+	}
+
+	input = input.MustDetach(false)
+	defer input.MustDrop()
+	if baseline == nil {
+		baseline = input.MustZerosLike(false)
+	} else {
+		baseline = baseline.MustDetach(false)
+	}
+	defer baseline.MustDrop()
+	delta := input.MustSub(baseline, false)
+	defer delta.MustDrop()
+
+	var total *ts.Tensor
+	for k := int64(1); k <= steps; k++ {
+		// Right Riemann sum over alpha in (0, 1].
+		alpha := float64(k) / float64(steps)
+		point := delta.MustMul1(ts.FloatScalar(alpha), false).MustAdd(baseline, true).MustSetRequiresGrad(true, true)
+
+		output := forwardFn(point).MustSum(gotch.Double, true)
+		grads, err := ts.RunBackward([]ts.Tensor{*output}, []ts.Tensor{*point}, false, false)
+		if err != nil {
+			log.Fatalf("IntegratedGradients - RunBackward error: %v\n", err)
+		}
+		output.MustDrop()
+		point.MustDrop()
+
+		if total == nil {
+			total = &grads[0]
+		} else {
+			total = total.MustAdd(&grads[0], true)
+			grads[0].MustDrop()
+		}
+	}
+
+	lastDim := int64(len(input.MustSize()) - 1)
+	attributions := total.MustMul(delta, true).MustDiv1(ts.FloatScalar(float64(steps)), true)
+
+	return attributions.MustSum1([]int64{lastDim}, false, input.DType(), true)
+}
+
+// DeadUnitReport returns the indices of hidden units whose activation
+// variance is below threshold, i.e. units stuck at a constant (saturated or
+// dead) value.
+//
+// activations are hidden activations collected over a batch, with units on
+// the last axis, e.g. the [batch_size, seq_len, hidden_dim] output of an RNN.
+// The variance of each unit is computed over all other axes.
+func DeadUnitReport(activations *ts.Tensor, threshold float64) []int64 {
+	size := activations.MustSize()
+	numUnits := size[len(size)-1]
+
+	flat := activations.MustTotype(gotch.Double, false).MustReshape([]int64{-1, numUnits}, true)
+	variances := flat.MustVar1([]int64{0}, false, false, true)
+	values := variances.Float64Values()
+	variances.MustDrop()
+
+	dead := []int64{}
+	for i, v := range values {
+		if v < threshold {
+			dead = append(dead, int64(i))
+		}
+	}
+
+	return dead
+}
+
+// InputGradient computes the gradient of the RNN output w.r.t. the input at
+// timestep t, e.g. to attribute a prediction to the input steps.
+//
+// input is laid out according to the RNN `BatchFirst` config.
+// outputReduction maps the output sequence to the quantity to differentiate,
+// e.g. the logit of a target class at the last step. Its result is summed into
+// a scalar, and it should not delete its input. It returns the gradient of
+// shape [batch_size, features].
+//
+// NOTE. only LSTM, GRU, MinGRU and SRU layers are supported.
+func InputGradient(rnn RNN, input *ts.Tensor, outputReduction func(*ts.Tensor) *ts.Tensor, t int64) *ts.Tensor {
+	_, config, _ := rnnInfo(rnn)
+	seqDim := int64(1)
+	if !config.BatchFirst {
+		seqDim = 0
+	}
+	if seqLen := input.MustSize()[seqDim]; t < 0 || t >= seqLen {
+		log.Fatalf("InputGradient - Expected timestep in [0, %v), got %v\n", seqLen, t)
+	}
+
+	x := input.MustDetach(false).MustSetRequiresGrad(true, true)
+	defer x.MustDrop()
+
+	output, state := rnn.Seq(x)
+	dropState(state)
+	reduced := outputReduction(output).MustSum(gotch.Double, true)
+	output.MustDrop()
+
+	grads, err := ts.RunBackward([]ts.Tensor{*reduced}, []ts.Tensor{*x}, false, false)
+	if err != nil {
+		log.Fatalf("InputGradient - RunBackward error: %v\n", err)
+	}
+	reduced.MustDrop()
+
+	return grads[0].MustSelect(seqDim, t, true)
+}
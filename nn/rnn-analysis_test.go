@@ -0,0 +1,222 @@
+package nn_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// newShortMemoryLSTM creates a toy LSTM under vs "lstm" path with no
+// recurrent weights and a forget gate always closed, whose last output only
+// depends on the last input.
+func newShortMemoryLSTM(vs *nn.VarStore, inputDim, hiddenDim int64) *nn.LSTM {
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), inputDim, hiddenDim, nn.DefaultRNNConfig())
+	ts.NoGrad(func() {
+		for name, v := range vs.Vars.NamedVariables {
+			switch {
+			case strings.HasPrefix(name, "lstm.w_hh"):
+				v.MustZero_()
+			case strings.HasPrefix(name, "lstm.b_ih"):
+				forget := v.MustNarrow(0, hiddenDim, hiddenDim, false)
+				forget.MustFill_(ts.FloatScalar(-30.0))
+				forget.MustDrop()
+			}
+		}
+	})
+
+	return lstm
+}
+
+func TestEffectiveContext(t *testing.T) {
+	var (
+		seqLen    int64 = 10
+		inputDim  int64 = 2
+		hiddenDim int64 = 4
+		threshold       = 1e-6
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), inputDim, hiddenDim, nn.DefaultRNNConfig())
+
+	if got := nn.EffectiveContext(lstm, seqLen, threshold); got <= 1 {
+		t.Errorf("Expected LSTM context > 1, got %v\n", got)
+	}
+
+	lstm = newShortMemoryLSTM(nn.NewVarStore(gotch.CPU), inputDim, hiddenDim)
+	if got := nn.EffectiveContext(lstm, seqLen, threshold); got != 1 {
+		t.Errorf("Expected short-memory LSTM context 1, got %v\n", got)
+	}
+
+	minGRU := nn.NewMinGRU(vs.Root().Sub("min_gru"), inputDim, hiddenDim, nn.DefaultRNNConfig())
+	if got := nn.EffectiveContext(minGRU, seqLen, threshold); got <= 1 {
+		t.Errorf("Expected MinGRU context > 1, got %v\n", got)
+	}
+
+	sru := nn.NewSRU(vs.Root().Sub("sru"), inputDim, hiddenDim, nn.DefaultRNNConfig())
+	if got := nn.EffectiveContext(sru, seqLen, threshold); got <= 1 {
+		t.Errorf("Expected SRU context > 1, got %v\n", got)
+	}
+}
+
+func TestLSTMStepJacobian(t *testing.T) {
+	var hiddenDim int64 = 3
+
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), 2, hiddenDim, nn.DefaultRNNConfig())
+	vs.ToDType(gotch.Double)
+
+	input := ts.MustRandn([]int64{1, 2}, gotch.Double, gotch.CPU)
+	h := ts.MustRandn([]int64{1, 1, hiddenDim}, gotch.Double, gotch.CPU)
+	c := ts.MustRandn([]int64{1, 1, hiddenDim}, gotch.Double, gotch.CPU)
+
+	jacobian := lstm.StepJacobian(input, &nn.LSTMState{Tensor1: h, Tensor2: c})
+	if got, want := jacobian.MustSize(), []int64{hiddenDim, hiddenDim}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected Jacobian shape %v, got %v\n", want, got)
+	}
+	got := jacobian.Float64Values()
+
+	// Central finite differences.
+	const eps = 1e-6
+	hValues := h.Float64Values()
+	stepH := func(j int, delta float64) []float64 {
+		values := make([]float64, len(hValues))
+		copy(values, hValues)
+		values[j] += delta
+		hj := ts.MustOfSlice(values).MustView([]int64{1, 1, hiddenDim}, true)
+		state := lstm.Step(input, &nn.LSTMState{Tensor1: hj, Tensor2: c})
+		return state.(*nn.LSTMState).H().Float64Values()
+	}
+	for j := 0; j < int(hiddenDim); j++ {
+		plus, minus := stepH(j, eps), stepH(j, -eps)
+		for i := 0; i < int(hiddenDim); i++ {
+			want := (plus[i] - minus[i]) / (2 * eps)
+			if diff := math.Abs(got[i*int(hiddenDim)+j] - want); diff > 1e-6 {
+				t.Errorf("J[%v][%v] - Expected %v, got %v\n", i, j, want, got[i*int(hiddenDim)+j])
+			}
+		}
+	}
+}
+
+func TestLSTMRecurrentSpectralRadius(t *testing.T) {
+	var hiddenDim int64 = 4
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	lstm := nn.NewLSTM(vs.Root(), 3, hiddenDim, cfg)
+
+	// Gate blocks of w_hh are 0.5 * I, I, 2 * I and a rotation by 90 degrees
+	// (complex eigenvalues of magnitude 1) in both layers.
+	scales := []float64{0.5, 1, 2}
+	ts.NoGrad(func() {
+		for name, v := range vs.Vars.NamedVariables {
+			if !strings.HasPrefix(name, "w_hh") {
+				continue
+			}
+			values := make([]float32, 4*hiddenDim*hiddenDim)
+			for gate, s := range scales {
+				for i := int64(0); i < hiddenDim; i++ {
+					values[(int64(gate)*hiddenDim+i)*hiddenDim+i] = float32(s)
+				}
+			}
+			for i := int64(0); i < hiddenDim; i += 2 {
+				row := 3*hiddenDim + i
+				values[row*hiddenDim+i+1] = -1
+				values[(row+1)*hiddenDim+i] = 1
+			}
+			w := ts.MustOfSlice(values).MustView([]int64{4 * hiddenDim, hiddenDim}, true)
+			v.Copy_(w)
+			w.MustDrop()
+		}
+	})
+
+	radii := lstm.RecurrentSpectralRadius()
+	if len(radii) != 8 {
+		t.Fatalf("Expected 8 radii (2 layers x 4 gates), got %v\n", len(radii))
+	}
+	want := []float64{0.5, 1, 2, 1}
+	for i, r := range radii {
+		if math.Abs(r-want[i%4]) > 1e-6 {
+			t.Errorf("Radius %v - Expected %v, got %v\n", i, want[i%4], r)
+		}
+	}
+}
+
+func TestIntegratedGradients(t *testing.T) {
+	var (
+		seqLen    int64 = 6
+		inputDim  int64 = 2
+		hiddenDim int64 = 4
+	)
+
+	lstm := newShortMemoryLSTM(nn.NewVarStore(gotch.CPU), inputDim, hiddenDim)
+
+	lastOutput := func(x *ts.Tensor) *ts.Tensor {
+		output, _ := lstm.Seq(x)
+		return output.MustSelect(1, seqLen-1, true)
+	}
+
+	input := ts.MustOnes([]int64{1, seqLen, inputDim}, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(2.0), true)
+	attributions := nn.IntegratedGradients(lastOutput, input, nil, 50)
+
+	if want, got := []int64{1, seqLen}, attributions.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected attributions shape %v, got %v\n", want, got)
+	}
+
+	values := attributions.Float64Values()
+	last := math.Abs(values[seqLen-1])
+	if last < 1e-3 {
+		t.Errorf("Expected a non-zero attribution for the last timestep, got %v\n", values)
+	}
+	for i := int64(0); i < seqLen-1; i++ {
+		if math.Abs(values[i]) > 1e-6 {
+			t.Errorf("Expected timestep %v attribution ~0, got %v\n", i, values[i])
+		}
+	}
+}
+
+func TestDeadUnitReport(t *testing.T) {
+	// Units 1 and 3 are stuck at a constant value (saturated tanh and dead
+	// ReLU), the others vary.
+	activations := ts.MustRand([]int64{4, 6, 5}, gotch.Float, gotch.CPU)
+	ts.NoGrad(func() {
+		activations.MustNarrow(2, 1, 1, false).MustFill_(ts.FloatScalar(1))
+		activations.MustNarrow(2, 3, 1, false).MustFill_(ts.FloatScalar(0))
+	})
+
+	if want, got := []int64{1, 3}, nn.DeadUnitReport(activations, 1e-6); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected dead units %v, got %v\n", want, got)
+	}
+}
+
+func TestInputGradient(t *testing.T) {
+	var (
+		seqLen    int64 = 10
+		hiddenDim int64 = 4
+	)
+
+	lstm := newShortMemoryLSTM(nn.NewVarStore(gotch.CPU), 2, hiddenDim)
+
+	input := ts.MustRandn([]int64{3, seqLen, 2}, gotch.Float, gotch.CPU)
+	lastStep := func(output *ts.Tensor) *ts.Tensor {
+		return output.MustSelect(1, seqLen-1, false)
+	}
+
+	last := nn.InputGradient(lstm, input, lastStep, seqLen-1)
+	if want, got := []int64{3, 2}, last.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected gradient shape %v, got %v\n", want, got)
+	}
+	if norm := last.MustAbs(false).MustSum(gotch.Double, true).Float64Values()[0]; norm < 1e-4 {
+		t.Errorf("Expected a gradient at the last timestep, got L1 norm %v\n", norm)
+	}
+
+	distant := nn.InputGradient(lstm, input, lastStep, 0)
+	if norm := distant.MustAbs(false).MustSum(gotch.Double, true).Float64Values()[0]; norm > 1e-6 {
+		t.Errorf("Expected no gradient at a distant timestep, got L1 norm %v\n", norm)
+	}
+}
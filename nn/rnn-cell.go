@@ -0,0 +1,232 @@
+package nn
+
+// Step-by-step (manual) LSTM computation.
+//
+// It is slower than the fused `ts.Lstm` kernel but exposes what happens
+// inside each cell, e.g. gate activations.
+
+import (
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// numDirections returns 2 for a bidirectional RNN, 1 otherwise.
+func (c *RNNConfig) numDirections() int64 {
+	if c.Bidirectional {
+		return 2
+	}
+
+	return 1
+}
+
+// rnnLinear computes `input * wT + b`. Bias is skipped if hasBiases is false.
+func rnnLinear(input, w, b *ts.Tensor, hasBiases bool) *ts.Tensor {
+	if hasBiases {
+		return ts.MustLinear(input, w, b)
+	}
+
+	wT := w.MustT(false)
+	retVal := input.MustMatmul(wT, false)
+	wT.MustDrop()
+
+	return retVal
+}
+
+// lstmCell applies a single LSTM step on an input of shape [batch_size, features].
+//
+// weights are w_ih, w_hh, b_ih and b_hh of a layer direction. Gate
+// pre-activations are clamped to [-clip, clip] if clip > 0. It returns the new
+// hidden and cell states together with the input, forget, cell and output gate
+// activations (in this order).
+func lstmCell(input, h, c *ts.Tensor, weights []ts.Tensor, hasBiases bool, clip float64) (hNew, cNew *ts.Tensor, gates []ts.Tensor) {
+	ih := rnnLinear(input, &weights[0], &weights[2], hasBiases)
+	hh := rnnLinear(h, &weights[1], &weights[3], hasBiases)
+	preact := ih.MustAdd(hh, true)
+	hh.MustDrop()
+	if clip > 0 {
+		preact = preact.MustClamp(ts.FloatScalar(-clip), ts.FloatScalar(clip), true)
+	}
+
+	chunks := preact.MustChunk(4, 1, true)
+	i := chunks[0].MustSigmoid(true)
+	f := chunks[1].MustSigmoid(true)
+	g := chunks[2].MustTanh(true)
+	o := chunks[3].MustSigmoid(true)
+
+	fc := f.MustMul(c, false)
+	ig := i.MustMul(g, false)
+	cNew = fc.MustAdd(ig, true)
+	ig.MustDrop()
+
+	tanhC := cNew.MustTanh(false)
+	hNew = o.MustMul(tanhC, false)
+	tanhC.MustDrop()
+
+	return hNew, cNew, []ts.Tensor{*i, *f, *g, *o}
+}
+
+// keepMasked returns x where mask is 1 and prev where it is 0, i.e.
+// prev + mask * (x - prev). It deletes x.
+func keepMasked(x, prev, mask *ts.Tensor) *ts.Tensor {
+	diff := x.MustSub(prev, true).MustMul(mask, true)
+	retVal := prev.MustAdd(diff, false)
+	diff.MustDrop()
+
+	return retVal
+}
+
+// manualSeqInit is the step-by-step counterpart of `SeqInit`.
+//
+// mask, if not nil, is laid out as the input without its feature axis. States
+// are left unchanged at timesteps where it is 0, and the output is zero.
+//
+// If keepGates is true, it also returns the input, forget, cell and output
+// gate activations of the last layer. They are laid out as the output, i.e.
+// [batch_size, seq_len, hidden_dim * num_directions] if `BatchFirst`.
+func (l *LSTM) manualSeqInit(input, mask *ts.Tensor, inState State, keepGates bool) (*ts.Tensor, State, []ts.Tensor) {
+	l.initWeights(input)
+
+	numDirections := l.config.numDirections()
+	numLayers := l.config.NumLayers
+
+	// Work on [seq_len, batch_size, features] layout.
+	var xs *ts.Tensor
+	if l.config.BatchFirst {
+		xs = input.MustTranspose(0, 1, false)
+	} else {
+		xs = input.MustShallowClone()
+	}
+
+	// masks[t] has shape [batch_size, 1].
+	var masks []ts.Tensor
+	if mask != nil {
+		m := mask.MustTotype(input.DType(), false)
+		if l.config.BatchFirst {
+			m = m.MustTranspose(0, 1, true)
+		}
+		masks = m.MustUnsqueeze(-1, true).MustUnbind(0, true)
+		defer func() {
+			for _, m := range masks {
+				m.MustDrop()
+			}
+		}()
+	}
+
+	h0 := inState.(*LSTMState).Tensor1
+	c0 := inState.(*LSTMState).Tensor2
+
+	var (
+		hs    []ts.Tensor
+		cs    []ts.Tensor
+		gates []ts.Tensor
+	)
+
+	for layer := int64(0); layer < numLayers; layer++ {
+		steps := xs.MustUnbind(0, true)
+		seqLen := len(steps)
+
+		var dirOutputs []ts.Tensor
+		dirGates := make([][]ts.Tensor, 4)
+
+		for dir := int64(0); dir < numDirections; dir++ {
+			idx := layer*numDirections + dir
+			weights := l.flatWeights[idx*4 : idx*4+4]
+
+			h := h0.MustSelect(0, idx, false)
+			c := c0.MustSelect(0, idx, false)
+
+			outputs := make([]ts.Tensor, seqLen)
+			stepGates := make([][]ts.Tensor, 4)
+			for k := range stepGates {
+				stepGates[k] = make([]ts.Tensor, seqLen)
+			}
+
+			for n := 0; n < seqLen; n++ {
+				t := n
+				if dir == 1 {
+					t = seqLen - 1 - n
+				}
+
+				hNew, cNew, g := lstmCell(&steps[t], h, c, weights, l.config.HasBiases, l.config.GateClip)
+				if masks != nil {
+					hNew = keepMasked(hNew, h, &masks[t])
+					cNew = keepMasked(cNew, c, &masks[t])
+				}
+				h.MustDrop()
+				c.MustDrop()
+				h, c = hNew, cNew
+
+				if masks != nil {
+					outputs[t] = *h.MustMul(&masks[t], false)
+				} else {
+					outputs[t] = *h.MustShallowClone()
+				}
+				for k := range g {
+					if keepGates && layer == numLayers-1 {
+						stepGates[k][t] = g[k]
+					} else {
+						g[k].MustDrop()
+					}
+				}
+			}
+
+			dirOutputs = append(dirOutputs, *ts.MustStack(outputs, 0))
+			for _, o := range outputs {
+				o.MustDrop()
+			}
+
+			if keepGates && layer == numLayers-1 {
+				for k := range stepGates {
+					dirGates[k] = append(dirGates[k], *ts.MustStack(stepGates[k], 0))
+					for _, g := range stepGates[k] {
+						g.MustDrop()
+					}
+				}
+			}
+
+			hs = append(hs, *h)
+			cs = append(cs, *c)
+		}
+
+		for _, s := range steps {
+			s.MustDrop()
+		}
+
+		xs = ts.MustCat(dirOutputs, 2)
+		for _, o := range dirOutputs {
+			o.MustDrop()
+		}
+
+		if layer < numLayers-1 {
+			xs = l.config.dropout(xs)
+		}
+
+		if keepGates && layer == numLayers-1 {
+			for k := range dirGates {
+				g := ts.MustCat(dirGates[k], 2)
+				for _, d := range dirGates[k] {
+					d.MustDrop()
+				}
+				if l.config.BatchFirst {
+					g = g.MustTranspose(0, 1, true)
+				}
+				gates = append(gates, *g)
+			}
+		}
+	}
+
+	output := xs
+	if l.config.BatchFirst {
+		output = xs.MustTranspose(0, 1, true)
+	}
+
+	state := &LSTMState{
+		Tensor1: ts.MustStack(hs, 0),
+		Tensor2: ts.MustStack(cs, 0),
+	}
+	for i := range hs {
+		hs[i].MustDrop()
+		cs[i].MustDrop()
+	}
+
+	return output, state, gates
+}
@@ -0,0 +1,227 @@
+package nn
+
+// A stack of RNN layers.
+
+import (
+	"log"
+	"time"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// RNNSequential stacks RNN layers. The output sequence of a layer is the
+// input of the next one.
+type RNNSequential struct {
+	layers   []RNN
+	profiler *Profiler
+}
+
+// RNNSequentialState holds the state of each layer of a RNNSequential.
+type RNNSequentialState struct {
+	States []State
+}
+
+// NewRNNSequential creates a new RNNSequential from the given layers.
+func NewRNNSequential(layers ...RNN) *RNNSequential {
+	return &RNNSequential{layers: layers}
+}
+
+// Add appends a layer on top of the current layers.
+func (s *RNNSequential) Add(l RNN) {
+	s.layers = append(s.layers, l)
+}
+
+// Len returns the number of layers.
+func (s *RNNSequential) Len() int {
+	return len(s.layers)
+}
+
+// SetProfiler sets a profiler recording the time spent in each layer's
+// `SeqInit`. A nil profiler disables profiling.
+func (s *RNNSequential) SetProfiler(p *Profiler) {
+	s.profiler = p
+}
+
+// dropState deletes the tensors of a LSTM, GRU or RNNSequential state.
+func dropState(state State) {
+	switch st := state.(type) {
+	case *LSTMState:
+		st.Tensor1.MustDrop()
+		st.Tensor2.MustDrop()
+	case *GRUState:
+		st.Tensor.MustDrop()
+	case *RNNSequentialState:
+		for _, s := range st.States {
+			dropState(s)
+		}
+	default:
+		log.Fatalf("Unsupported state type: %T\n", state)
+	}
+}
+
+// batchFirst reports whether the stack expects batch-first sequences, as set
+// by the config of its first layer. Layers not exposing their config (see
+// `rnnLayer`) are assumed batch-first.
+func (s *RNNSequential) batchFirst() bool {
+	if len(s.layers) == 0 {
+		return true
+	}
+	l, ok := s.layers[0].(rnnLayer)
+	if !ok {
+		return true
+	}
+	_, config, _ := l.layerInfo()
+
+	return config.BatchFirst
+}
+
+// Implement RNN interface for RNNSequential:
+// ==========================================
+
+func (s *RNNSequential) ZeroState(batchDim int64) State {
+	states := make([]State, len(s.layers))
+	for i, l := range s.layers {
+		states[i] = l.ZeroState(batchDim)
+	}
+
+	return &RNNSequentialState{States: states}
+}
+
+// ZeroStateLike creates a zero state with the batch size, dtype and device of
+// input for layers supporting it (e.g. LSTM, GRU) and the default zero state
+// for the others.
+func (s *RNNSequential) ZeroStateLike(input *ts.Tensor) State {
+	size := input.MustSize()
+	batchDim := size[0]
+	if len(size) == 3 && !s.batchFirst() {
+		batchDim = size[1]
+	}
+
+	states := make([]State, len(s.layers))
+	for i, l := range s.layers {
+		if lz, ok := l.(interface{ ZeroStateLike(*ts.Tensor) State }); ok {
+			states[i] = lz.ZeroStateLike(input)
+		} else {
+			states[i] = l.ZeroState(batchDim)
+		}
+	}
+
+	return &RNNSequentialState{States: states}
+}
+
+func (s *RNNSequential) Step(input *ts.Tensor, inState State) State {
+	seqDim := int64(1)
+	if !s.batchFirst() {
+		seqDim = 0
+	}
+	ip := input.MustUnsqueeze(seqDim, false)
+
+	output, state := s.SeqInit(ip, inState)
+	ip.MustDrop()
+	output.MustDrop()
+
+	return state
+}
+
+func (s *RNNSequential) Seq(input *ts.Tensor) (*ts.Tensor, State) {
+	inState := s.ZeroStateLike(input)
+
+	output, state := s.SeqInit(input, inState)
+
+	// Delete intermediate tensors in inState
+	dropState(inState)
+
+	return output, state
+}
+
+func (s *RNNSequential) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
+	outputs, states := s.seqInit(input, inState, false)
+
+	return &outputs[0], &RNNSequentialState{States: states}
+}
+
+// SeqAllLayers runs the stack from a zero state and returns the output
+// sequence and final state of every layer, bottom layer first, e.g. for
+// probing intermediate representations.
+func (s *RNNSequential) SeqAllLayers(input *ts.Tensor) ([]ts.Tensor, []State) {
+	inState := s.ZeroStateLike(input)
+	outputs, states := s.seqInit(input, inState, true)
+	dropState(inState)
+
+	return outputs, states
+}
+
+// seqInit runs the layers in order. It returns the output of every layer if
+// keepAll is true, only the output of the top layer otherwise.
+func (s *RNNSequential) seqInit(input *ts.Tensor, inState State, keepAll bool) ([]ts.Tensor, []State) {
+	if len(s.layers) == 0 {
+		log.Fatalf("RNNSequential - SeqInit: no layers\n")
+	}
+
+	inStates := inState.(*RNNSequentialState).States
+	if len(inStates) != len(s.layers) {
+		log.Fatalf("RNNSequential - Expected %v layer states, got %v\n", len(s.layers), len(inStates))
+	}
+
+	var outputs []ts.Tensor
+	states := make([]State, len(s.layers))
+	xs := input
+	for i, l := range s.layers {
+		var start time.Time
+		if s.profiler != nil {
+			s.profiler.synchronize(xs.MustDevice())
+			start = time.Now()
+		}
+
+		output, state := l.SeqInit(xs, inStates[i])
+
+		if s.profiler != nil {
+			s.profiler.synchronize(xs.MustDevice())
+			s.profiler.record(i, time.Since(start))
+		}
+
+		if keepAll {
+			outputs = append(outputs, *output)
+		} else if i > 0 {
+			xs.MustDrop()
+		}
+		xs = output
+		states[i] = state
+	}
+
+	if !keepAll {
+		outputs = append(outputs, *xs)
+	}
+
+	return outputs, states
+}
+
+// FreezeLayers stops tracking gradients of the variables of the layers at the
+// given indices, e.g. to fine-tune only the top of a pre-trained stack. Frozen
+// variables are no longer updated by optimizers.
+//
+// Only LSTM and GRU layers are supported.
+func (s *RNNSequential) FreezeLayers(indices []int) {
+	for _, i := range indices {
+		if i < 0 || i >= len(s.layers) {
+			log.Fatalf("RNNSequential.FreezeLayers - Invalid layer index %v for %v layers\n", i, len(s.layers))
+		}
+
+		var vars []ts.Tensor
+		switch l := s.layers[i].(type) {
+		case *LSTM:
+			if len(l.flatWeights) == 0 {
+				log.Fatalf("RNNSequential.FreezeLayers - Weights of layer %v are not initialized yet. Run a forward pass first.\n", i)
+			}
+			vars = rnnVariables(l.flatWeights, l.reparams, l.initStates)
+		case *GRU:
+			vars = rnnVariables(l.flatWeights, l.reparams, l.initStates)
+		default:
+			log.Fatalf("RNNSequential.FreezeLayers - Unsupported layer type: %T\n", l)
+		}
+
+		for _, v := range vars {
+			v.MustRequiresGrad_(false)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package nn_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestRNNSequential(t *testing.T) {
+	var (
+		batchDim int64 = 3
+		seqLen   int64 = 5
+		inputDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	stack := nn.NewRNNSequential(
+		nn.NewLSTM(vs.Root().Sub("l0"), inputDim, 8, nn.DefaultRNNConfig()),
+		nn.NewGRU(vs.Root().Sub("l1"), 8, 6, nn.DefaultRNNConfig()),
+	)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+	output, state := stack.Seq(input)
+
+	if want, got := []int64{batchDim, seqLen, 6}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape %v, got %v\n", want, got)
+	}
+
+	states := state.(*nn.RNNSequentialState).States
+	if len(states) != 2 {
+		t.Fatalf("Expected 2 layer states, got %v\n", len(states))
+	}
+	if want, got := []int64{1, batchDim, 6}, states[1].(*nn.GRUState).Tensor.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected top layer state shape %v, got %v\n", want, got)
+	}
+
+	step := ts.MustRandn([]int64{batchDim, inputDim}, gotch.Float, gotch.CPU)
+	stepState := stack.Step(step, stack.ZeroState(batchDim))
+	if got := len(stepState.(*nn.RNNSequentialState).States); got != 2 {
+		t.Errorf("Expected 2 layer states after a step, got %v\n", got)
+	}
+}
+
+func TestRNNSequentialSeqFirst(t *testing.T) {
+	var (
+		batchDim int64 = 3
+		seqLen   int64 = 5
+		inputDim int64 = 4
+	)
+
+	cfg := nn.DefaultRNNConfig()
+	cfg.BatchFirst = false
+
+	vs := nn.NewVarStore(gotch.CPU)
+	stack := nn.NewRNNSequential(
+		nn.NewLSTM(vs.Root().Sub("l0"), inputDim, 8, cfg),
+		nn.NewGRU(vs.Root().Sub("l1"), 8, 6, cfg),
+	)
+
+	input := ts.MustRandn([]int64{seqLen, batchDim, inputDim}, gotch.Float, gotch.CPU)
+	output, state := stack.Seq(input)
+	if want, got := []int64{seqLen, batchDim, 6}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape %v, got %v\n", want, got)
+	}
+	states := state.(*nn.RNNSequentialState).States
+	if want, got := []int64{1, batchDim, 6}, states[1].(*nn.GRUState).Tensor.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected top layer state shape %v, got %v\n", want, got)
+	}
+
+	step := ts.MustRandn([]int64{batchDim, inputDim}, gotch.Float, gotch.CPU)
+	stepState := stack.Step(step, stack.ZeroState(batchDim))
+	states = stepState.(*nn.RNNSequentialState).States
+	if want, got := []int64{1, batchDim, 6}, states[1].(*nn.GRUState).Tensor.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected top layer state shape after a step %v, got %v\n", want, got)
+	}
+}
+
+func TestRNNSequentialProfiler(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	stack := nn.NewRNNSequential()
+	for i, dims := range [][2]int64{{4, 8}, {8, 8}, {8, 4}} {
+		stack.Add(nn.NewLSTM(vs.Root().Sub(fmt.Sprintf("l%v", i)), dims[0], dims[1], nn.DefaultRNNConfig()))
+	}
+
+	profiler := nn.NewProfiler()
+	stack.SetProfiler(profiler)
+
+	input := ts.MustRandn([]int64{3, 5, 4}, gotch.Float, gotch.CPU)
+	stack.Seq(input)
+
+	report := profiler.Report()
+	if len(report) != stack.Len() {
+		t.Fatalf("Expected %v profiled layers, got %v\n", stack.Len(), len(report))
+	}
+	for i := 0; i < stack.Len(); i++ {
+		if report[i] <= 0 {
+			t.Errorf("Expected a nonzero duration for layer %v, got %v\n", i, report[i])
+		}
+	}
+
+	profiler.Reset()
+	if got := len(profiler.Report()); got != 0 {
+		t.Errorf("Expected an empty report after reset, got %v layers\n", got)
+	}
+}
+
+func TestRNNSequentialFreezeLayers(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	stack := nn.NewRNNSequential(
+		nn.NewLSTM(vs.Root().Sub("l0"), 4, 8, nn.DefaultRNNConfig()),
+		nn.NewLSTM(vs.Root().Sub("l1"), 8, 6, nn.DefaultRNNConfig()),
+	)
+	stack.FreezeLayers([]int{0})
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := make(map[string][]float64)
+	for name, v := range vs.Vars.NamedVariables {
+		before[name] = v.Float64Values()
+	}
+
+	for i := 0; i < 3; i++ {
+		input := ts.MustRandn([]int64{3, 5, 4}, gotch.Float, gotch.CPU)
+		output, _ := stack.Seq(input)
+		loss := output.MustSum(gotch.Float, true)
+		opt.BackwardStep(loss)
+	}
+
+	for name, v := range vs.Vars.NamedVariables {
+		changed := !reflect.DeepEqual(before[name], v.Float64Values())
+		switch {
+		case strings.HasPrefix(name, "l0.") && changed:
+			t.Errorf("%v - Expected frozen layer variable to stay fixed\n", name)
+		case strings.HasPrefix(name, "l1.") && !changed:
+			t.Errorf("%v - Expected trainable layer variable to change\n", name)
+		}
+	}
+}
+
+func TestRNNSequentialSeqAllLayers(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	bidirCfg := nn.DefaultRNNConfig()
+	bidirCfg.Bidirectional = true
+	stack := nn.NewRNNSequential(
+		nn.NewLSTM(vs.Root().Sub("l0"), 4, 8, nn.DefaultRNNConfig()),
+		nn.NewGRU(vs.Root().Sub("l1"), 8, 6, nn.DefaultRNNConfig()),
+		nn.NewLSTM(vs.Root().Sub("l2"), 6, 5, bidirCfg),
+	)
+
+	input := ts.MustRandn([]int64{3, 7, 4}, gotch.Float, gotch.CPU)
+	outputs, states := stack.SeqAllLayers(input)
+	if len(outputs) != 3 || len(states) != 3 {
+		t.Fatalf("Expected 3 outputs and states, got %v and %v\n", len(outputs), len(states))
+	}
+
+	for i, featureDim := range []int64{8, 6, 10} {
+		if want, got := []int64{3, 7, featureDim}, outputs[i].MustSize(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Layer %v - Expected output shape %v, got %v\n", i, want, got)
+		}
+	}
+
+	// The top layer output is the stack output.
+	output, _ := stack.Seq(input)
+	if diff := maxAbsDiff(output, &outputs[2]); diff > 1e-6 {
+		t.Errorf("Expected top layer output to match Seq output, got max difference %v\n", diff)
+	}
+}
@@ -0,0 +1,491 @@
+package nn
+
+// Helpers to manipulate RNN outputs and states.
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// ConcatOutputs concatenates RNN outputs along the feature (last) axis.
+//
+// Outputs should have shape [batch_size, seq_len, features] (or [seq_len,
+// batch_size, features]) with matching first 2 dimensions.
+func ConcatOutputs(outputs ...*ts.Tensor) (*ts.Tensor, error) {
+	if len(outputs) == 0 {
+		err := fmt.Errorf("ConcatOutputs - Expected at least one output\n")
+		return nil, err
+	}
+
+	want := outputs[0].MustSize()
+	if len(want) != 3 {
+		err := fmt.Errorf("ConcatOutputs - Expected 3D outputs, got shape %v\n", want)
+		return nil, err
+	}
+
+	tensors := make([]ts.Tensor, len(outputs))
+	for i, o := range outputs {
+		got := o.MustSize()
+		if len(got) != 3 || !reflect.DeepEqual(want[:2], got[:2]) {
+			err := fmt.Errorf("ConcatOutputs - Mismatched batch and sequence dimensions: output 0 has shape %v, output %v has shape %v\n", want, i, got)
+			return nil, err
+		}
+		tensors[i] = *o
+	}
+
+	return ts.Cat(tensors, 2)
+}
+
+// ConcatStates concatenates RNN states along the hidden (last) axis.
+//
+// States should all be `*LSTMState` or all be `*GRUState`, with the same
+// number of layers and batch size.
+func ConcatStates(states ...State) (State, error) {
+	if len(states) == 0 {
+		err := fmt.Errorf("ConcatStates - Expected at least one state\n")
+		return nil, err
+	}
+
+	switch states[0].(type) {
+	case *LSTMState:
+		var hs, cs []*ts.Tensor
+		for i, s := range states {
+			ls, ok := s.(*LSTMState)
+			if !ok {
+				err := fmt.Errorf("ConcatStates - Expected state %v to be *LSTMState, got %T\n", i, s)
+				return nil, err
+			}
+			hs = append(hs, ls.Tensor1)
+			cs = append(cs, ls.Tensor2)
+		}
+
+		h, err := ConcatOutputs(hs...)
+		if err != nil {
+			return nil, err
+		}
+		c, err := ConcatOutputs(cs...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LSTMState{Tensor1: h, Tensor2: c}, nil
+
+	case *GRUState:
+		var hs []*ts.Tensor
+		for i, s := range states {
+			gs, ok := s.(*GRUState)
+			if !ok {
+				err := fmt.Errorf("ConcatStates - Expected state %v to be *GRUState, got %T\n", i, s)
+				return nil, err
+			}
+			hs = append(hs, gs.Tensor)
+		}
+
+		h, err := ConcatOutputs(hs...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &GRUState{Tensor: h}, nil
+
+	default:
+		err := fmt.Errorf("ConcatStates - Unsupported state type: %T\n", states[0])
+		return nil, err
+	}
+}
+
+// StackStates stacks per-sample states into a batched state.
+//
+// Each state should be a `*LSTMState` or a `*GRUState` of shape
+// [num_layers * num_directions, 1, hidden_dim], i.e. a state for a batch of
+// size 1. States are concatenated along the batch dimension.
+func StackStates(states []State) (State, error) {
+	if len(states) == 0 {
+		err := fmt.Errorf("StackStates - Expected at least one state\n")
+		return nil, err
+	}
+
+	switch states[0].(type) {
+	case *LSTMState:
+		var hs, cs []ts.Tensor
+		for i, s := range states {
+			ls, ok := s.(*LSTMState)
+			if !ok {
+				err := fmt.Errorf("StackStates - Expected state %v to be *LSTMState, got %T\n", i, s)
+				return nil, err
+			}
+			hs = append(hs, *ls.Tensor1)
+			cs = append(cs, *ls.Tensor2)
+		}
+
+		h, err := stackSamples(hs)
+		if err != nil {
+			return nil, err
+		}
+		c, err := stackSamples(cs)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LSTMState{Tensor1: h, Tensor2: c}, nil
+
+	case *GRUState:
+		var hs []ts.Tensor
+		for i, s := range states {
+			gs, ok := s.(*GRUState)
+			if !ok {
+				err := fmt.Errorf("StackStates - Expected state %v to be *GRUState, got %T\n", i, s)
+				return nil, err
+			}
+			hs = append(hs, *gs.Tensor)
+		}
+
+		h, err := stackSamples(hs)
+		if err != nil {
+			return nil, err
+		}
+
+		return &GRUState{Tensor: h}, nil
+
+	default:
+		err := fmt.Errorf("StackStates - Unsupported state type: %T\n", states[0])
+		return nil, err
+	}
+}
+
+// stackSamples concatenates [layers, 1, hidden] tensors along the batch dimension.
+func stackSamples(tensors []ts.Tensor) (*ts.Tensor, error) {
+	want := tensors[0].MustSize()
+	for i, t := range tensors {
+		got := t.MustSize()
+		if len(got) != 3 || got[1] != 1 || !reflect.DeepEqual(want, got) {
+			err := fmt.Errorf("StackStates - Expected per-sample state of shape %v with batch size 1, state %v has shape %v\n", want, i, got)
+			return nil, err
+		}
+	}
+
+	return ts.Cat(tensors, 1)
+}
+
+// UnstackStates splits a batched state into n states along the batch
+// dimension. It is the inverse of `StackStates` when n is the batch size.
+func UnstackStates(state State, n int64) []State {
+	var retVal []State
+
+	switch s := state.(type) {
+	case *LSTMState:
+		hs := s.Tensor1.MustChunk(n, 1, false)
+		cs := s.Tensor2.MustChunk(n, 1, false)
+		for i := range hs {
+			retVal = append(retVal, &LSTMState{Tensor1: &hs[i], Tensor2: &cs[i]})
+		}
+	case *GRUState:
+		hs := s.Tensor.MustChunk(n, 1, false)
+		for i := range hs {
+			retVal = append(retVal, &GRUState{Tensor: &hs[i]})
+		}
+	default:
+		log.Fatalf("UnstackStates - Unsupported state type: %T\n", state)
+	}
+
+	return retVal
+}
+
+// MergeBidirState merges the forward and backward directions of a
+// bidirectional RNN state, e.g. to initialize a unidirectional decoder from a
+// bidirectional encoder.
+//
+// s is a `*LSTMState` or a `*GRUState` of shape [num_layers * 2, batch_size,
+// hidden_dim]. mode is "concat" to concatenate both directions, giving a
+// state of shape [num_layers, batch_size, 2 * hidden_dim], or "sum" to add
+// them, giving a state of shape [num_layers, batch_size, hidden_dim].
+func MergeBidirState(s State, numLayers int64, mode string) State {
+	switch st := s.(type) {
+	case *LSTMState:
+		return &LSTMState{
+			Tensor1: mergeDirections(st.Tensor1, numLayers, mode),
+			Tensor2: mergeDirections(st.Tensor2, numLayers, mode),
+		}
+	case *GRUState:
+		return &GRUState{Tensor: mergeDirections(st.Tensor, numLayers, mode)}
+	default:
+		log.Fatalf("MergeBidirState - Unsupported state type: %T\n", s)
+	}
+
+	return nil
+}
+
+// mergeDirections merges the directions of a [num_layers * 2, batch_size,
+// hidden_dim] state tensor.
+func mergeDirections(x *ts.Tensor, numLayers int64, mode string) *ts.Tensor {
+	size := x.MustSize()
+	if len(size) != 3 || size[0] != numLayers*2 {
+		log.Fatalf("MergeBidirState - Expected a state of shape [%v, batch_size, hidden_dim], got %v\n", numLayers*2, size)
+	}
+
+	// Directions of a layer are contiguous: index layer * 2 + direction.
+	layers := x.MustView([]int64{numLayers, 2, size[1], size[2]}, false)
+	defer layers.MustDrop()
+
+	switch mode {
+	case "concat":
+		fwd := layers.MustSelect(1, 0, false)
+		bwd := layers.MustSelect(1, 1, false)
+		retVal := ts.MustCat([]ts.Tensor{*fwd, *bwd}, 2)
+		fwd.MustDrop()
+		bwd.MustDrop()
+		return retVal
+	case "sum":
+		return layers.MustSum1([]int64{1}, false, x.DType(), false)
+	default:
+		log.Fatalf("MergeBidirState - Unsupported mode: %q. Expected \"concat\" or \"sum\"\n", mode)
+	}
+
+	return nil
+}
+
+// ToSeqFirst converts a batch-first sequence of shape [batch_size, seq_len,
+// features] to the time-first layout [seq_len, batch_size, features] of RNNs
+// with `BatchFirst` unset. The result is contiguous and input is not deleted.
+func ToSeqFirst(input *ts.Tensor) *ts.Tensor {
+	return swapLayout("ToSeqFirst", input)
+}
+
+// ToBatchFirstLayout converts a time-first sequence of shape [seq_len,
+// batch_size, features] to the batch-first layout [batch_size, seq_len,
+// features]. The result is contiguous and input is not deleted.
+func ToBatchFirstLayout(input *ts.Tensor) *ts.Tensor {
+	return swapLayout("ToBatchFirstLayout", input)
+}
+
+// swapLayout swaps the first two axes of a 3D sequence.
+func swapLayout(caller string, input *ts.Tensor) *ts.Tensor {
+	if size := input.MustSize(); len(size) != 3 {
+		log.Fatalf("%v - Expected a 3D sequence, got shape %v\n", caller, size)
+	}
+
+	return input.MustTranspose(0, 1, false).MustContiguous(true)
+}
+
+// LastTimestep returns the RNN output at the last valid timestep
+// `lengths[i] - 1` of each sequence i, i.e. a tensor of shape
+// [batch_size, features].
+//
+// rnnOutput has shape [batch_size, seq_len, features] if batchFirst,
+// [seq_len, batch_size, features] otherwise.
+func LastTimestep(rnnOutput *ts.Tensor, lengths []int64, batchFirst bool) *ts.Tensor {
+	size := rnnOutput.MustSize()
+	if len(size) != 3 {
+		log.Fatalf("LastTimestep - Expected a 3D RNN output, got shape %v\n", size)
+	}
+
+	xs := rnnOutput.MustShallowClone()
+	if !batchFirst {
+		xs = xs.MustTranspose(0, 1, true)
+	}
+	defer xs.MustDrop()
+
+	batchDim, seqLen, featureDim := xs.MustSize()[0], xs.MustSize()[1], size[2]
+	if int64(len(lengths)) != batchDim {
+		log.Fatalf("LastTimestep - Expected %v lengths, got %v\n", batchDim, len(lengths))
+	}
+
+	last := make([]int64, batchDim)
+	for i, l := range lengths {
+		if l < 1 || l > seqLen {
+			log.Fatalf("LastTimestep - Expected lengths in [1, %v], got %v\n", seqLen, lengths)
+		}
+		last[i] = l - 1
+	}
+
+	idx := ts.MustOfSlice(last).MustTo(xs.MustDevice(), true).MustView([]int64{batchDim, 1, 1}, true)
+	idx = idx.MustExpand([]int64{batchDim, 1, featureDim}, false, true)
+	retVal := xs.MustGather(1, idx, false, false).MustSqueeze1(1, true)
+	idx.MustDrop()
+
+	return retVal
+}
+
+// SequenceEmbedding runs rnn over input and pools its output over the valid
+// timesteps of each sequence into a fixed-size embedding of shape
+// [batch_size, features], e.g. for retrieval.
+//
+// input is laid out according to the RNN `BatchFirst` config and lengths holds
+// the valid length of each sequence. mode is "mean" or "max" to pool over
+// timesteps [0, lengths[i]), or "last" to take the output at timestep
+// lengths[i] - 1 (see `LastTimestep`).
+//
+// NOTE. only LSTM, GRU, MinGRU and SRU layers are supported.
+func SequenceEmbedding(rnn RNN, input *ts.Tensor, lengths []int64, mode string) *ts.Tensor {
+	output, state := rnn.Seq(input)
+	dropState(state)
+	_, config, _ := rnnInfo(rnn)
+
+	if mode == "last" {
+		retVal := LastTimestep(output, lengths, config.BatchFirst)
+		output.MustDrop()
+		return retVal
+	}
+
+	xs := output
+	if !config.BatchFirst {
+		xs = output.MustTranspose(0, 1, true)
+	}
+	defer xs.MustDrop()
+
+	batchDim, seqLen := xs.MustSize()[0], xs.MustSize()[1]
+	if int64(len(lengths)) != batchDim {
+		log.Fatalf("SequenceEmbedding - Expected %v lengths, got %v\n", batchDim, len(lengths))
+	}
+
+	// mask[i][t] is 1 for valid timesteps t < lengths[i], 0 for padding.
+	maskData := make([]float64, batchDim*seqLen)
+	for i, l := range lengths {
+		if l < 1 || l > seqLen {
+			log.Fatalf("SequenceEmbedding - Expected lengths in [1, %v], got %v\n", seqLen, lengths)
+		}
+		for t := int64(0); t < l; t++ {
+			maskData[int64(i)*seqLen+t] = 1
+		}
+	}
+	mask := ts.MustOfSlice(maskData).MustView([]int64{batchDim, seqLen, 1}, true).MustTotype(xs.DType(), true).MustTo(xs.MustDevice(), true)
+	defer mask.MustDrop()
+
+	switch mode {
+	case "mean":
+		sum := xs.MustMul(mask, false).MustSum1([]int64{1}, false, xs.DType(), true)
+		count := mask.MustSum1([]int64{1}, false, xs.DType(), false)
+		retVal := sum.MustDiv(count, true)
+		count.MustDrop()
+		return retVal
+	case "max":
+		padding := mask.MustEq(ts.FloatScalar(0), false)
+		masked := xs.MustMaskedFill(padding, ts.FloatScalar(math.Inf(-1)), false)
+		padding.MustDrop()
+		return masked.MustAmax([]int64{1}, false, true)
+	default:
+		log.Fatalf("SequenceEmbedding - Unsupported mode: %q. Expected \"mean\", \"max\" or \"last\"\n", mode)
+	}
+
+	return nil
+}
+
+// PackedBatch is a batch of variable-length sequences padded to the same
+// length.
+//
+// Padded has shape [batch_size, max_len, ...] and Lengths holds the valid
+// length of each sequence. Timesteps beyond a sequence length are padding.
+type PackedBatch struct {
+	Padded  *ts.Tensor
+	Lengths []int64
+}
+
+// ConcatPacked merges two batches into a single batch along the batch
+// dimension. Sequences are re-padded with zeros to the longest length of the
+// merged batch.
+func ConcatPacked(a, b PackedBatch) PackedBatch {
+	var maxLen int64
+	for _, batch := range []PackedBatch{a, b} {
+		size := batch.Padded.MustSize()
+		if len(size) < 2 {
+			log.Fatalf("ConcatPacked - Expected padded tensors of at least 2 dimensions, got shape %v\n", size)
+		}
+		if int64(len(batch.Lengths)) != size[0] {
+			log.Fatalf("ConcatPacked - Expected %v lengths, got %v\n", size[0], len(batch.Lengths))
+		}
+		for _, l := range batch.Lengths {
+			if l < 0 || l > size[1] {
+				log.Fatalf("ConcatPacked - Expected lengths in [0, %v], got %v\n", size[1], batch.Lengths)
+			}
+			if l > maxLen {
+				maxLen = l
+			}
+		}
+	}
+
+	aSize, bSize := a.Padded.MustSize(), b.Padded.MustSize()
+	if !reflect.DeepEqual(aSize[2:], bSize[2:]) {
+		log.Fatalf("ConcatPacked - Mismatched feature dimensions: %v and %v\n", aSize, bSize)
+	}
+
+	aPadded := repad(a.Padded, maxLen)
+	bPadded := repad(b.Padded, maxLen)
+	padded := ts.MustCat([]ts.Tensor{*aPadded, *bPadded}, 0)
+	aPadded.MustDrop()
+	bPadded.MustDrop()
+
+	lengths := make([]int64, 0, len(a.Lengths)+len(b.Lengths))
+	lengths = append(lengths, a.Lengths...)
+	lengths = append(lengths, b.Lengths...)
+
+	return PackedBatch{
+		Padded:  padded,
+		Lengths: lengths,
+	}
+}
+
+// repad truncates or zero-pads a [batch_size, len, ...] tensor along its
+// time (second) dimension to length seqLen.
+func repad(padded *ts.Tensor, seqLen int64) *ts.Tensor {
+	size := padded.MustSize()
+	switch {
+	case size[1] > seqLen:
+		return padded.MustNarrow(1, 0, seqLen, false)
+	case size[1] < seqLen:
+		// Padding sizes are given from the last dimension backward.
+		pad := make([]int64, 2*(len(size)-1))
+		pad[len(pad)-1] = seqLen - size[1]
+		return padded.MustConstantPadNd(pad, false)
+	}
+
+	return padded.MustShallowClone()
+}
+
+// SlidingWindows splits sequences into overlapping windows of windowSize
+// timesteps, starting every stride timesteps. Trailing timesteps which do not
+// fill a whole window are dropped.
+//
+// input has shape [batch_size, seq_len, features] if batchFirst, [seq_len,
+// batch_size, features] otherwise. Windows are stacked along a new leading
+// dimension: the result has shape [num_windows, batch_size, window_size,
+// features] if batchFirst, [num_windows, window_size, batch_size, features]
+// otherwise, with num_windows = (seq_len - window_size) / stride + 1.
+func SlidingWindows(input *ts.Tensor, windowSize, stride int64, batchFirst bool) (*ts.Tensor, error) {
+	size := input.MustSize()
+	if len(size) != 3 {
+		err := fmt.Errorf("SlidingWindows - Expected a 3D input, got shape %v\n", size)
+		return nil, err
+	}
+	if stride < 1 {
+		err := fmt.Errorf("SlidingWindows - Expected a positive stride, got %v\n", stride)
+		return nil, err
+	}
+
+	timeDim := int64(0)
+	if batchFirst {
+		timeDim = 1
+	}
+	if seqLen := size[timeDim]; windowSize < 1 || windowSize > seqLen {
+		err := fmt.Errorf("SlidingWindows - Expected a window size in [1, %v], got %v\n", seqLen, windowSize)
+		return nil, err
+	}
+
+	// Unfold replaces the time dimension by the window index and appends the
+	// window timesteps as last dimension.
+	windows, err := input.Unfold(timeDim, windowSize, stride, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// [batch, windows, features, window] or [windows, batch, features, window]
+	dims := []int64{1, 0, 3, 2}
+	if !batchFirst {
+		dims = []int64{0, 3, 1, 2}
+	}
+
+	return windows.Permute(dims, true)
+}
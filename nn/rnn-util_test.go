@@ -0,0 +1,312 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestConcatOutputs(t *testing.T) {
+	a := ts.MustRandn([]int64{2, 5, 3}, gotch.Float, gotch.CPU)
+	b := ts.MustRandn([]int64{2, 5, 3}, gotch.Float, gotch.CPU)
+
+	output, err := nn.ConcatOutputs(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{2, 5, 6}
+	got := output.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape: %v\n", want)
+		t.Errorf("Got output shape: %v\n", got)
+	}
+
+	c := ts.MustRandn([]int64{2, 4, 3}, gotch.Float, gotch.CPU)
+	if _, err := nn.ConcatOutputs(a, c); err == nil {
+		t.Errorf("Expected error for mismatched sequence lengths\n")
+	}
+}
+
+func TestConcatStates(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm1 := nn.NewLSTM(vs.Root(), 2, 3, nn.DefaultRNNConfig())
+	lstm2 := nn.NewLSTM(vs.Root(), 2, 4, nn.DefaultRNNConfig())
+
+	input := ts.MustRandn([]int64{5, 7, 2}, gotch.Float, gotch.CPU)
+	_, s1 := lstm1.Seq(input)
+	_, s2 := lstm2.Seq(input)
+
+	state, err := nn.ConcatStates(s1, s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{1, 5, 7}
+	got := state.(*nn.LSTMState).Tensor2.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected cell state shape: %v\n", want)
+		t.Errorf("Got cell state shape: %v\n", got)
+	}
+
+	gru := nn.NewGRU(vs.Root(), 2, 3, nn.DefaultRNNConfig())
+	_, s3 := gru.Seq(input)
+	if _, err := nn.ConcatStates(s1, s3); err == nil {
+		t.Errorf("Expected error for mixed state types\n")
+	}
+}
+
+func TestStackStates(t *testing.T) {
+	var (
+		batchDim  int64 = 3
+		layerDim  int64 = 2
+		hiddenDim int64 = 4
+	)
+
+	var states []nn.State
+	for i := int64(0); i < batchDim; i++ {
+		states = append(states, &nn.LSTMState{
+			Tensor1: ts.MustRandn([]int64{layerDim, 1, hiddenDim}, gotch.Float, gotch.CPU),
+			Tensor2: ts.MustRandn([]int64{layerDim, 1, hiddenDim}, gotch.Float, gotch.CPU),
+		})
+	}
+
+	stacked, err := nn.StackStates(states)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{layerDim, batchDim, hiddenDim}
+	got := stacked.(*nn.LSTMState).Tensor1.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected stacked state shape: %v\n", want)
+		t.Errorf("Got stacked state shape: %v\n", got)
+	}
+
+	unstacked := nn.UnstackStates(stacked, batchDim)
+	if int64(len(unstacked)) != batchDim {
+		t.Fatalf("Expected %v states, got %v\n", batchDim, len(unstacked))
+	}
+
+	for i := range states {
+		orig := states[i].(*nn.LSTMState)
+		s := unstacked[i].(*nn.LSTMState)
+		if diff := maxAbsDiff(orig.Tensor1, s.Tensor1); diff != 0 {
+			t.Errorf("Expected H of sample %v to be recovered, got max difference %v\n", i, diff)
+		}
+		if diff := maxAbsDiff(orig.Tensor2, s.Tensor2); diff != 0 {
+			t.Errorf("Expected C of sample %v to be recovered, got max difference %v\n", i, diff)
+		}
+	}
+
+	states = append(states, &nn.GRUState{Tensor: ts.MustRandn([]int64{layerDim, 1, hiddenDim}, gotch.Float, gotch.CPU)})
+	if _, err := nn.StackStates(states); err == nil {
+		t.Errorf("Expected error for mixed state types\n")
+	}
+}
+
+func TestLastTimestep(t *testing.T) {
+	// output[b, t, f] = 10 * b + t
+	data := make([]float32, 0)
+	for b := 0; b < 3; b++ {
+		for s := 0; s < 3; s++ {
+			data = append(data, float32(10*b+s), float32(10*b+s))
+		}
+	}
+	output := ts.MustOfSlice(data).MustView([]int64{3, 3, 2}, true)
+	lengths := []int64{3, 1, 2}
+
+	want := []float64{2, 2, 10, 10, 21, 21}
+
+	last := nn.LastTimestep(output, lengths, true)
+	if got := last.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected last timesteps %v, got %v\n", want, got)
+	}
+
+	seqFirst := output.MustTranspose(0, 1, false)
+	last = nn.LastTimestep(seqFirst, lengths, false)
+	if got := last.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected last timesteps %v (seq first), got %v\n", want, got)
+	}
+}
+
+func TestConcatPacked(t *testing.T) {
+	a := nn.PackedBatch{
+		Padded:  ts.MustRandn([]int64{2, 3, 2}, gotch.Float, gotch.CPU),
+		Lengths: []int64{3, 2},
+	}
+	b := nn.PackedBatch{
+		Padded:  ts.MustRandn([]int64{2, 4, 2}, gotch.Float, gotch.CPU),
+		Lengths: []int64{4, 1},
+	}
+
+	merged := nn.ConcatPacked(a, b)
+
+	if want := []int64{3, 2, 4, 1}; !reflect.DeepEqual(want, merged.Lengths) {
+		t.Errorf("Expected lengths %v, got %v\n", want, merged.Lengths)
+	}
+	if want, got := []int64{4, 4, 2}, merged.Padded.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected padded shape %v, got %v\n", want, got)
+	}
+
+	sources := []struct {
+		batch nn.PackedBatch
+		index int64
+	}{{a, 0}, {a, 1}, {b, 0}, {b, 1}}
+	for i, src := range sources {
+		length := merged.Lengths[i]
+		row := merged.Padded.MustSelect(0, int64(i), false)
+
+		valid := row.MustNarrow(0, 0, length, false).Float64Values()
+		want := src.batch.Padded.MustSelect(0, src.index, false).MustNarrow(0, 0, length, true).Float64Values()
+		if !reflect.DeepEqual(want, valid) {
+			t.Errorf("Sequence %v - Expected data %v, got %v\n", i, want, valid)
+		}
+
+		if length < 4 {
+			padding := row.MustNarrow(0, length, 4-length, false).MustAbs(true).MustSum(gotch.Double, true).Float64Values()[0]
+			if padding != 0 {
+				t.Errorf("Sequence %v - Expected zero padding, got sum %v\n", i, padding)
+			}
+		}
+		row.MustDrop()
+	}
+}
+
+func TestSlidingWindows(t *testing.T) {
+	// 2 sequences of length 5 with 2 features: x[b][t] = [10b + t, -(10b + t)].
+	var data []float32
+	for b := 0; b < 2; b++ {
+		for i := 0; i < 5; i++ {
+			v := float32(10*b + i)
+			data = append(data, v, -v)
+		}
+	}
+	input := ts.MustOfSlice(data).MustView([]int64{2, 5, 2}, true)
+
+	windows, err := nn.SlidingWindows(input, 3, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []int64{3, 2, 3, 2}, windows.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected windows shape %v, got %v\n", want, got)
+	}
+
+	// Window w of sequence b holds timesteps w, w+1, w+2.
+	for w := int64(0); w < 3; w++ {
+		for b := int64(0); b < 2; b++ {
+			got := windows.MustSelect(0, w, false).MustSelect(0, b, true).Float64Values()
+			var want []float64
+			for i := w; i < w+3; i++ {
+				v := float64(10*b + i)
+				want = append(want, v, -v)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("Window %v, sequence %v - Expected %v, got %v\n", w, b, want, got)
+			}
+		}
+	}
+
+	// Time-major layout gives the same windows.
+	timeMajor, err := nn.SlidingWindows(input.MustTranspose(0, 1, false), 3, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := windows.Float64Values(), timeMajor.MustTranspose(1, 2, true).Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected time-major windows to match batch-first windows\n")
+	}
+
+	if _, err := nn.SlidingWindows(input, 6, 1, true); err == nil {
+		t.Errorf("Expected an error for a window longer than the sequence\n")
+	}
+}
+
+func TestMergeBidirState(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	encCfg := nn.DefaultRNNConfig()
+	encCfg.NumLayers = 2
+	encCfg.Bidirectional = true
+	encoder := nn.NewLSTM(vs.Root().Sub("encoder"), 3, 4, encCfg)
+
+	_, state := encoder.Seq(ts.MustRandn([]int64{5, 7, 3}, gotch.Float, gotch.CPU))
+
+	merged := nn.MergeBidirState(state, 2, "concat").(*nn.LSTMState)
+	for _, x := range []*ts.Tensor{merged.Tensor1, merged.Tensor2} {
+		if want, got := []int64{2, 5, 8}, x.MustSize(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Concat - Expected merged state shape %v, got %v\n", want, got)
+		}
+	}
+
+	// The merged state initializes a unidirectional 2-layer decoder.
+	decCfg := nn.DefaultRNNConfig()
+	decCfg.NumLayers = 2
+	decoder := nn.NewLSTM(vs.Root().Sub("decoder"), 3, 8, decCfg)
+	output, _ := decoder.SeqInit(ts.MustRandn([]int64{5, 2, 3}, gotch.Float, gotch.CPU), merged)
+	if want, got := []int64{5, 2, 8}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected decoder output shape %v, got %v\n", want, got)
+	}
+
+	// Layer 1 of the summed state adds state slots 2 (forward) and 3 (backward).
+	summed := nn.MergeBidirState(state, 2, "sum").(*nn.LSTMState)
+	if want, got := []int64{2, 5, 4}, summed.Tensor1.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Sum - Expected merged state shape %v, got %v\n", want, got)
+	}
+	h := state.(*nn.LSTMState).Tensor1
+	want := h.MustSelect(0, 2, false).MustAdd(h.MustSelect(0, 3, false), true)
+	if diff := maxAbsDiff(summed.Tensor1.MustSelect(0, 1, false), want); diff > 1e-6 {
+		t.Errorf("Sum - Expected layer 1 to add both directions, got max difference %v\n", diff)
+	}
+}
+
+func TestSequenceEmbedding(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), 2, 6, nn.DefaultRNNConfig())
+
+	// Sequences 0 and 1 only differ in their padding.
+	valid := ts.MustRandn([]int64{1, 3, 2}, gotch.Float, gotch.CPU)
+	pad0 := ts.MustZeros([]int64{1, 2, 2}, gotch.Float, gotch.CPU)
+	pad1 := ts.MustRandn([]int64{1, 2, 2}, gotch.Float, gotch.CPU)
+	seq0 := ts.MustCat([]ts.Tensor{*valid, *pad0}, 1)
+	seq1 := ts.MustCat([]ts.Tensor{*valid, *pad1}, 1)
+	seq2 := ts.MustRandn([]int64{1, 5, 2}, gotch.Float, gotch.CPU)
+	input := ts.MustCat([]ts.Tensor{*seq0, *seq1, *seq2}, 0)
+	lengths := []int64{3, 3, 5}
+
+	for _, mode := range []string{"mean", "max", "last"} {
+		embedding := nn.SequenceEmbedding(lstm, input, lengths, mode)
+		if want, got := []int64{3, 6}, embedding.MustSize(); !reflect.DeepEqual(want, got) {
+			t.Errorf("%v - Expected embedding shape %v, got %v\n", mode, want, got)
+			continue
+		}
+
+		e0 := embedding.MustSelect(0, 0, false)
+		e1 := embedding.MustSelect(0, 1, false)
+		if diff := maxAbsDiff(e0, e1); diff > 1e-6 {
+			t.Errorf("%v - Expected identical embeddings for identical sequences, got max difference %v\n", mode, diff)
+		}
+	}
+}
+
+func TestSequenceLayout(t *testing.T) {
+	input := ts.MustArange(ts.IntScalar(30), gotch.Float, gotch.CPU).MustView([]int64{2, 5, 3}, true)
+
+	seqFirst := nn.ToSeqFirst(input)
+	if want, got := []int64{5, 2, 3}, seqFirst.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected seq-first shape %v, got %v\n", want, got)
+	}
+	// Timestep 4 of sample 1 starts at (1 * 5 + 4) * 3 in the batch-first input.
+	if got := seqFirst.MustSelect(0, 4, false).MustSelect(0, 1, true).Float64Values(); !reflect.DeepEqual([]float64{27, 28, 29}, got) {
+		t.Errorf("Expected features [27 28 29] at timestep 4 of sample 1, got %v\n", got)
+	}
+
+	batchFirst := nn.ToBatchFirstLayout(seqFirst)
+	if want, got := input.MustSize(), batchFirst.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected round trip shape %v, got %v\n", want, got)
+	}
+	if want, got := input.Float64Values(), batchFirst.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected round trip values %v, got %v\n", want, got)
+	}
+}
@@ -1,6 +1,10 @@
 package nn
 
 import (
+	"fmt"
+	"log"
+	"reflect"
+
 	"github.com/sugarme/gotch"
 	ts "github.com/sugarme/gotch/tensor"
 )
@@ -54,6 +58,70 @@ type RNNConfig struct {
 	Train         bool
 	Bidirectional bool
 	BatchFirst    bool
+
+	// InputNoiseStd is the standard deviation of a zero-mean Gaussian noise
+	// added to the input when training. No noise is added if it is 0.
+	InputNoiseStd float64
+
+	// WeightNorm reparameterizes w_ih and w_hh weights with `WeightNorm`.
+	WeightNorm bool
+
+	// WeightRank factorizes w_ih and w_hh weights into two low-rank matrices
+	// of rank `WeightRank` (see `LowRankWeight`). Not factorized if it is 0.
+	// A LSTM with factorized weights runs through the step-by-step path.
+	WeightRank int64
+
+	// GateClip clamps the LSTM gate pre-activations to [-GateClip, GateClip]
+	// before the sigmoid/tanh so that gates never fully saturate. Not clamped
+	// if it is 0. A LSTM with clipped gates runs through the step-by-step
+	// path. It is ignored by GRU.
+	GateClip float64
+
+	// Reversed runs a unidirectional RNN from right to left: the input is
+	// reversed along the time axis and the output is reversed back. It is
+	// ignored if `Bidirectional` is set.
+	Reversed bool
+
+	// LearnedInitState makes the initial state returned by `ZeroState` a
+	// trainable variable (h0, and c0 for LSTM) instead of zeros. It is
+	// initialized with zeros and shared by all samples of a batch.
+	LearnedInitState bool
+
+	// ShareLayerWeights reuses the weights of the first layer for all
+	// `NumLayers` layers, so that the number of parameters does not depend on
+	// the depth. It requires the input dimension to be hidden_dim *
+	// num_directions.
+	ShareLayerWeights bool
+
+	// DType is the dtype of the weights and of the states returned by
+	// `ZeroState`, e.g. gotch.BFloat16. Inputs should have the same dtype.
+	// Float is used if it is not set.
+	DType gotch.DType
+
+	// OutputActivation is applied to the output sequence returned by
+	// `SeqInit`, e.g. tanh for bounded outputs. It should not delete its
+	// input. States are not affected. No activation is applied if it is nil.
+	OutputActivation func(*ts.Tensor) *ts.Tensor
+
+	// DebugCheckFinite checks output and state tensors for NaN/Inf values
+	// after each `SeqInit` and panics if any is found.
+	DebugCheckFinite bool
+
+	// Generator is the random number generator used for input noise and
+	// inter-layer dropout, so that they are reproducible from its seed. The
+	// global RNG is used if it is nil. A LSTM with a generator and dropout
+	// runs through the step-by-step path, a GRU one layer at a time.
+	Generator *ts.Generator
+
+	// DeferDevice allocates weights (and learned initial states) on CPU and
+	// moves them to the var-store device on the first forward pass, so that
+	// many models can be built cheaply before choosing where to run them.
+	DeferDevice bool
+
+	// OutputDType is the dtype of the output sequence returned by `SeqInit`,
+	// e.g. gotch.Float to feed a loss from a gotch.Half network. States keep
+	// the network dtype. The output is not cast if it is not set.
+	OutputDType gotch.DType
 }
 
 // Default creates default RNN configuration
@@ -65,6 +133,154 @@ func DefaultRNNConfig() *RNNConfig {
 		Train:         true,
 		Bidirectional: false,
 		BatchFirst:    true,
+		InputNoiseStd: 0.0,
+		WeightNorm:    false,
+		WeightRank:    0,
+		GateClip:      0.0,
+		Reversed:      false,
+
+		LearnedInitState:  false,
+		ShareLayerWeights: false,
+		DType:             gotch.Float,
+		OutputActivation:  nil,
+
+		DebugCheckFinite: false,
+		Generator:        nil,
+		DeferDevice:      false,
+		OutputDType:      gotch.DType{},
+	}
+}
+
+// dtype returns `DType`, Float if it is not set.
+func (c *RNNConfig) dtype() gotch.DType {
+	if c.DType.Type == nil {
+		return gotch.Float
+	}
+
+	return c.DType
+}
+
+// toDType converts variables to the config dtype in place.
+func (c *RNNConfig) toDType(vars []ts.Tensor) {
+	dtype := c.dtype()
+	ts.NoGrad(func() {
+		for i := range vars {
+			if vars[i].DType() == dtype {
+				continue
+			}
+
+			data := vars[i].MustTotype(dtype, false)
+			vars[i].MustSetData(data)
+			data.MustDrop()
+		}
+	})
+}
+
+// addInputNoise returns the input with an added zero-mean Gaussian noise when
+// training with `InputNoiseStd` set, a shallow clone of the input otherwise.
+func (c *RNNConfig) addInputNoise(input *ts.Tensor) *ts.Tensor {
+	if !c.Train || c.InputNoiseStd == 0 {
+		return input.MustShallowClone()
+	}
+
+	var noise *ts.Tensor
+	if c.Generator != nil {
+		noise = ts.MustRandnWithGenerator(input.MustSize(), input.DType(), input.MustDevice(), c.Generator)
+	} else {
+		noise = input.MustRandnLike(false)
+	}
+	noise.MustMul1_(ts.FloatScalar(c.InputNoiseStd))
+	retVal := input.MustAdd(noise, false)
+	noise.MustDrop()
+
+	return retVal
+}
+
+// dropout applies inter-layer dropout to xs when training with `Dropout` set,
+// drawing the mask from `Generator` if any. It deletes xs.
+func (c *RNNConfig) dropout(xs *ts.Tensor) *ts.Tensor {
+	if !c.Train || c.Dropout == 0 {
+		return xs
+	}
+
+	if c.Generator == nil {
+		retVal := ts.MustDropout(xs, c.Dropout, c.Train)
+		xs.MustDrop()
+		return retVal
+	}
+
+	keep := 1 - c.Dropout
+	mask := ts.MustBernoulliWithGenerator(xs.MustSize(), keep, xs.DType(), xs.MustDevice(), c.Generator)
+	mask.MustDiv1_(ts.FloatScalar(keep))
+	retVal := xs.MustMul(mask, true)
+	mask.MustDrop()
+
+	return retVal
+}
+
+// flipTime reverses xs along the time axis if `Reversed` is set (and
+// `Bidirectional` is not). Otherwise, it returns a shallow clone of xs.
+func (c *RNNConfig) flipTime(xs *ts.Tensor, del bool) *ts.Tensor {
+	if !c.Reversed || c.Bidirectional {
+		if del {
+			return xs
+		}
+		return xs.MustShallowClone()
+	}
+
+	timeDim := int64(1)
+	if !c.BatchFirst {
+		timeDim = 0
+	}
+
+	return xs.MustFlip([]int64{timeDim}, del)
+}
+
+// activateOutput applies `OutputActivation` to output, if any, and deletes
+// output.
+func (c *RNNConfig) activateOutput(output *ts.Tensor) *ts.Tensor {
+	if c.OutputActivation == nil {
+		return output
+	}
+
+	retVal := c.OutputActivation(output)
+	output.MustDrop()
+
+	return retVal
+}
+
+// castOutput casts output to `OutputDType`, if set, and deletes output.
+func (c *RNNConfig) castOutput(output *ts.Tensor) *ts.Tensor {
+	if c.OutputDType.Type == nil || output.DType() == c.OutputDType {
+		return output
+	}
+
+	return output.MustTotype(c.OutputDType, true)
+}
+
+// batchDim returns the batch size of a sequence input, laid out according to
+// `BatchFirst`, or of a step input of shape [batch_size, features].
+func (c *RNNConfig) batchDim(input *ts.Tensor) int64 {
+	size := input.MustSize()
+	if len(size) == 3 && !c.BatchFirst {
+		return size[1]
+	}
+
+	return size[0]
+}
+
+// checkFinite panics with a descriptive error if `DebugCheckFinite` is set and
+// one of the named tensors contains NaN/Inf values.
+func (c *RNNConfig) checkFinite(layer string, names []string, tensors ...*ts.Tensor) {
+	if !c.DebugCheckFinite {
+		return
+	}
+
+	for i, x := range tensors {
+		if hasNonFinite(x) {
+			err := fmt.Errorf("%v - SeqInit: non-finite values (NaN/Inf) found in %v tensor of shape %v\n", layer, names[i], x.MustSize())
+			panic(err)
+		}
 	}
 }
 
@@ -73,75 +289,405 @@ func DefaultRNNConfig() *RNNConfig {
 // https://en.wikipedia.org/wiki/Long_short-term_memory
 type LSTM struct {
 	flatWeights []ts.Tensor
+	reparams    []reparamWeight
 	hiddenDim   int64
+	inDim       int64
 	config      *RNNConfig
 	device      gotch.Device
+	initStates  []*ts.Tensor // learned h0 and c0, nil if not `LearnedInitState`
+	deferred    bool         // variables are still on CPU, see `DeferDevice`
+
+	// vs is only kept for a lazy LSTM whose weights have not been allocated
+	// yet. It is a CPU path if `DeferDevice` is set (see `rnnWeightPath`).
+	vs *Path
 }
 
 // NewLSTM creates a LSTM layer.
 func NewLSTM(vs *Path, inDim, hiddenDim int64, cfg *RNNConfig) *LSTM {
+	wvs, deferred := rnnWeightPath(vs, cfg)
+	flatWeights, reparams := rnnFlatWeights(wvs, inDim, hiddenDim, 4, cfg)
 
-	var numDirections int64 = 1
-	if cfg.Bidirectional {
-		numDirections = 2
+	return &LSTM{
+		flatWeights: flatWeights,
+		reparams:    reparams,
+		hiddenDim:   hiddenDim,
+		inDim:       inDim,
+		config:      cfg,
+		device:      vs.Device(),
+		initStates:  rnnInitStates(wvs, hiddenDim, cfg, "h0", "c0"),
+		deferred:    deferred,
 	}
+}
 
-	gateDim := 4 * hiddenDim
+// NewLazyLSTM creates a LSTM layer whose input dimension is inferred from
+// the input of the first `SeqInit` or `Step` call.
+//
+// NOTE: weights are not registered in the var-store until the first forward
+// pass. Hence, an optimizer should be built after that.
+func NewLazyLSTM(vs *Path, hiddenDim int64, cfg *RNNConfig) *LSTM {
+	wvs, deferred := rnnWeightPath(vs, cfg)
+
+	return &LSTM{
+		hiddenDim:  hiddenDim,
+		config:     cfg,
+		device:     vs.Device(),
+		initStates: rnnInitStates(wvs, hiddenDim, cfg, "h0", "c0"),
+		deferred:   deferred,
+		vs:         wvs,
+	}
+}
+
+// rnnWeightPath returns the path RNN variables are created in: a CPU path
+// if `DeferDevice` is set and vs is not on CPU, vs otherwise. deferred
+// reports whether variables will have to be moved to the vs device.
+func rnnWeightPath(vs *Path, cfg *RNNConfig) (wvs *Path, deferred bool) {
+	if !cfg.DeferDevice || vs.Device() == gotch.CPU {
+		return vs, false
+	}
+
+	return vs.onDevice(gotch.CPU), true
+}
+
+// rnnInitStates registers learned initial states of shape
+// [num_layers * num_directions, 1, hidden_dim] if `LearnedInitState` is set.
+func rnnInitStates(vs *Path, hiddenDim int64, cfg *RNNConfig, names ...string) []*ts.Tensor {
+	if !cfg.LearnedInitState {
+		return nil
+	}
+
+	shape := []int64{cfg.NumLayers * cfg.numDirections(), 1, hiddenDim}
+	initStates := make([]*ts.Tensor, len(names))
+	for i, name := range names {
+		initStates[i] = vs.Zeros(name, shape)
+	}
+	cfg.toDType(rnnVariables(nil, nil, initStates))
+
+	return initStates
+}
+
+// initState returns a state tensor of the given shape: the learned initial
+// state init expanded along the batch dimension or zeros if init is nil.
+func initState(init *ts.Tensor, shape []int64, dtype gotch.DType, device gotch.Device) *ts.Tensor {
+	if init == nil {
+		return ts.MustZeros(shape, dtype, device)
+	}
+
+	retVal := init.MustExpand(shape, false, false)
+	if retVal.DType() != dtype {
+		retVal = retVal.MustTotype(dtype, true)
+	}
+
+	return retVal
+}
+
+// reparamWeight is a weight computed from other variables, e.g. `WeightNorm`
+// or `LowRankWeight`.
+type reparamWeight interface {
+	// Weight computes the effective weight.
+	Weight() *ts.Tensor
+
+	// reset re-initializes the underlying variables in place.
+	reset()
+
+	// variables returns the underlying variables.
+	variables() []ts.Tensor
+}
+
+// rnnFlatWeights creates weights in the order expected by `ts.Lstm` and
+// `ts.Gru`. numGates is 4 for LSTM and 3 for GRU.
+//
+// If `WeightNorm` or `WeightRank` is set, w_ih and w_hh are reparameterized
+// and the returned slice holds their corresponding `reparamWeight` at the
+// same index.
+func rnnFlatWeights(vs *Path, inDim, hiddenDim, numGates int64, cfg *RNNConfig) ([]ts.Tensor, []reparamWeight) {
+	if cfg.WeightNorm && cfg.WeightRank > 0 {
+		log.Fatalf("RNNConfig - WeightNorm and WeightRank cannot be both set\n")
+	}
+
+	numDirections := cfg.numDirections()
+	if cfg.ShareLayerWeights && inDim != hiddenDim*numDirections {
+		log.Fatalf("RNNConfig - ShareLayerWeights requires an input dimension of %v, got %v\n", hiddenDim*numDirections, inDim)
+	}
+
+	gateDim := numGates * hiddenDim
 	flatWeights := make([]ts.Tensor, 0)
 
+	var reparams []reparamWeight
+	if cfg.WeightNorm || cfg.WeightRank > 0 {
+		reparams = make([]reparamWeight, 0)
+	}
+
 	for i := 0; i < int(cfg.NumLayers); i++ {
+		// Shared layers reuse the first layer entries. Reparameterized weights
+		// are computed separately for each layer as they are recomputed (and
+		// dropped) independently.
+		if cfg.ShareLayerWeights && i > 0 {
+			layerLen := int(4 * numDirections)
+			for j := 0; j < layerLen; j++ {
+				if reparams != nil && reparams[j] != nil {
+					flatWeights = append(flatWeights, *reparams[j].Weight())
+				} else {
+					flatWeights = append(flatWeights, flatWeights[j])
+				}
+				if reparams != nil {
+					reparams = append(reparams, reparams[j])
+				}
+			}
+			continue
+		}
+
 		for n := 0; n < int(numDirections); n++ {
-			if i != 0 {
-				inDim = hiddenDim * numDirections
+			var inputDim int64
+			if i == 0 {
+				inputDim = inDim
+			} else {
+				inputDim = hiddenDim * numDirections
 			}
 
-			wIh := vs.KaimingUniform("w_ih", []int64{gateDim, inDim})
-			wHh := vs.KaimingUniform("w_hh", []int64{gateDim, hiddenDim})
+			var wIh, wHh *ts.Tensor
+			switch {
+			case cfg.WeightNorm:
+				wnIh := NewWeightNorm(vs, "w_ih", []int64{gateDim, inputDim}, 0)
+				wnHh := NewWeightNorm(vs, "w_hh", []int64{gateDim, hiddenDim}, 0)
+				wIh, wHh = wnIh.Weight(), wnHh.Weight()
+				reparams = append(reparams, wnIh, wnHh, nil, nil)
+			case cfg.WeightRank > 0:
+				lrIh := NewLowRankWeight(vs, "w_ih", []int64{gateDim, inputDim}, cfg.WeightRank)
+				lrHh := NewLowRankWeight(vs, "w_hh", []int64{gateDim, hiddenDim}, cfg.WeightRank)
+				wIh, wHh = lrIh.Weight(), lrHh.Weight()
+				reparams = append(reparams, lrIh, lrHh, nil, nil)
+			default:
+				wIh = vs.KaimingUniform("w_ih", []int64{gateDim, inputDim})
+				wHh = vs.KaimingUniform("w_hh", []int64{gateDim, hiddenDim})
+			}
 			bIh := vs.Zeros("b_ih", []int64{gateDim})
 			bHh := vs.Zeros("b_hh", []int64{gateDim})
 
+			// Names of torch.nn.LSTM and torch.nn.GRU parameters.
+			if reparams == nil {
+				suffix := fmt.Sprintf("l%v", i)
+				if n == 1 {
+					suffix += "_reverse"
+				}
+				vs.setPyTorchName(wIh, "weight_ih_"+suffix)
+				vs.setPyTorchName(wHh, "weight_hh_"+suffix)
+				vs.setPyTorchName(bIh, "bias_ih_"+suffix)
+				vs.setPyTorchName(bHh, "bias_hh_"+suffix)
+			}
+
 			flatWeights = append(flatWeights, *wIh, *wHh, *bIh, *bHh)
 		}
 	}
 
+	if cfg.dtype() != gotch.Float {
+		cfg.toDType(rnnVariables(flatWeights, reparams, nil))
+		refreshWeights(flatWeights, reparams)
+	}
+
+	flattenWeights(flatWeights, reparams, inDim, hiddenDim, numGates, cfg, vs.Device())
+
+	return flatWeights, reparams
+}
+
+// flattenWeights flattens flatWeights into a contiguous cuDNN buffer if device
+// is a CUDA device.
+func flattenWeights(flatWeights []ts.Tensor, reparams []reparamWeight, inDim, hiddenDim, numGates int64, cfg *RNNConfig, device gotch.Device) {
+	// NOTE. reparameterized weights are recomputed at every forward pass,
+	// hence cannot be flattened once for all. Neither can shared weights which
+	// appear several times.
 	// if vs.Device().IsCuda() && gotch.Cuda.CudnnIsAvailable() {
 	// TODO: check if Cudnn is available here!!!
-	if vs.Device().IsCuda() {
-		// NOTE. 2 is for LSTM
+	if device.IsCuda() && reparams == nil && !cfg.ShareLayerWeights {
+		// NOTE. 2 is for LSTM, 3 is for GRU
 		// ref. rnn.cpp in Pytorch
-		ts.Must_CudnnRnnFlattenWeight(flatWeights, 4, inDim, 2, hiddenDim, cfg.NumLayers, cfg.BatchFirst, cfg.Bidirectional)
+		var mode int64 = 2
+		if numGates == 3 {
+			mode = 3
+		}
+		ts.Must_CudnnRnnFlattenWeight(flatWeights, 4, inDim, mode, hiddenDim, cfg.NumLayers, cfg.BatchFirst, cfg.Bidirectional)
 	}
+}
 
-	return &LSTM{
-		flatWeights: flatWeights,
-		hiddenDim:   hiddenDim,
-		config:      cfg,
-		device:      vs.Device(),
+// moveDeferred moves RNN variables created on CPU because of `DeferDevice` to
+// device in place, then flattens the weights for cuDNN. flatWeights is nil for
+// a lazy RNN whose weights are not allocated yet, in which case only the
+// learned initial states are moved.
+func moveDeferred(flatWeights []ts.Tensor, reparams []reparamWeight, initStates []*ts.Tensor, hiddenDim, numGates int64, cfg *RNNConfig, device gotch.Device) {
+	vars := rnnVariables(flatWeights, reparams, initStates)
+	ts.NoGrad(func() {
+		for i := range vars {
+			// Shared weights appear several times.
+			if vars[i].MustDevice() == device {
+				continue
+			}
+
+			data := vars[i].MustTo(device, false)
+			vars[i].MustSetData(data)
+			data.MustDrop()
+		}
+	})
+
+	if len(flatWeights) == 0 {
+		return
 	}
 
+	refreshWeights(flatWeights, reparams)
+	inDim := flatWeights[0].MustSize()[1]
+	flattenWeights(flatWeights, reparams, inDim, hiddenDim, numGates, cfg, device)
+}
+
+// refreshWeights recomputes the reparameterized entries of flatWeights from
+// their current underlying variables.
+func refreshWeights(flatWeights []ts.Tensor, reparams []reparamWeight) {
+	for i, r := range reparams {
+		if r == nil {
+			continue
+		}
+
+		flatWeights[i].MustDrop()
+		flatWeights[i] = *r.Weight()
+	}
+}
+
+// rnnVariables returns the variables flatWeights are computed from, i.e.
+// flatWeights themselves except for reparameterized weights, followed by the
+// learned initial states if any.
+func rnnVariables(flatWeights []ts.Tensor, reparams []reparamWeight, initStates []*ts.Tensor) []ts.Tensor {
+	var vars []ts.Tensor
+	for i := range flatWeights {
+		if reparams != nil && reparams[i] != nil {
+			vars = append(vars, reparams[i].variables()...)
+		} else {
+			vars = append(vars, flatWeights[i])
+		}
+	}
+	for _, s := range initStates {
+		vars = append(vars, *s)
+	}
+
+	return vars
+}
+
+// resetWeights re-initializes flatWeights in place: Kaiming uniform for w_ih
+// and w_hh, zeros for b_ih and b_hh. Reparameterized weights re-initialize
+// their underlying variables.
+func resetWeights(flatWeights []ts.Tensor, reparams []reparamWeight) {
+	ts.NoGrad(func() {
+		for i := range flatWeights {
+			switch {
+			case i%4 >= 2:
+				NewConstInit(0.0).Set(&flatWeights[i])
+			case reparams != nil && reparams[i] != nil:
+				reparams[i].reset()
+			default:
+				NewKaimingUniformInit().Set(&flatWeights[i])
+			}
+		}
+	})
+
+	refreshWeights(flatWeights, reparams)
+}
+
+// validateFlatWeights checks that flatWeights hold w_ih, w_hh, b_ih and b_hh
+// for each layer and direction of cfg, with the shapes expected by `ts.Lstm`
+// and `ts.Gru`. numGates is 4 for LSTM and 3 for GRU.
+//
+// NOTE. biases are allocated even if `HasBiases` is false.
+func validateFlatWeights(layer string, flatWeights []ts.Tensor, inDim, hiddenDim, numGates int64, cfg *RNNConfig) error {
+	numDirections := cfg.numDirections()
+	if want, got := int(4*cfg.NumLayers*numDirections), len(flatWeights); want != got {
+		err := fmt.Errorf("%v - Expected %v flat weights for %v layer(s) and %v direction(s), got %v\n", layer, want, cfg.NumLayers, numDirections, got)
+		return err
+	}
+
+	gateDim := numGates * hiddenDim
+	names := []string{"w_ih", "w_hh", "b_ih", "b_hh"}
+	for i := int64(0); i < cfg.NumLayers; i++ {
+		inputDim := inDim
+		if i > 0 {
+			inputDim = hiddenDim * numDirections
+		}
+		shapes := [][]int64{{gateDim, inputDim}, {gateDim, hiddenDim}, {gateDim}, {gateDim}}
+
+		for d := int64(0); d < numDirections; d++ {
+			for k, want := range shapes {
+				idx := (i*numDirections+d)*4 + int64(k)
+				if got := flatWeights[idx].MustSize(); !reflect.DeepEqual(want, got) {
+					err := fmt.Errorf("%v - Expected %v of layer %v direction %v (flat weight %v) to have shape %v, got %v\n", layer, names[k], i, d, idx, want, got)
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// initWeights prepares weights for a forward pass.
+//
+// It allocates weights of a lazy LSTM on its first forward pass, checks that
+// the input feature dimension matches the one weights were created with and
+// recomputes weight-normalized weights.
+func (l *LSTM) initWeights(input *ts.Tensor) {
+	size := input.MustSize()
+	featureDim := size[len(size)-1]
+
+	if l.flatWeights == nil {
+		l.flatWeights, l.reparams = rnnFlatWeights(l.vs, featureDim, l.hiddenDim, 4, l.config)
+		l.inDim = featureDim
+		l.vs = nil
+		l.placeWeights()
+		return
+	}
+	l.placeWeights()
+
+	if featureDim != l.inDim {
+		log.Fatalf("LSTM - Expected input feature dimension %v, got %v\n", l.inDim, featureDim)
+	}
+
+	refreshWeights(l.flatWeights, l.reparams)
+}
+
+// placeWeights moves the variables of a LSTM built with `DeferDevice` to its
+// device, once its weights are allocated.
+func (l *LSTM) placeWeights() {
+	if !l.deferred {
+		return
+	}
+
+	moveDeferred(l.flatWeights, l.reparams, l.initStates, l.hiddenDim, 4, l.config, l.device)
+	l.deferred = l.flatWeights == nil
 }
 
 // Implement RNN interface for LSTM:
 // =================================
 
 func (l *LSTM) ZeroState(batchDim int64) State {
-	var numDirections int64 = 1
-	if l.config.Bidirectional {
-		numDirections = 2
-	}
+	return l.zeroState(batchDim, l.config.dtype(), l.device)
+}
 
-	layerDim := l.config.NumLayers * numDirections
+// ZeroStateLike creates a zero state with the batch size, dtype and device of
+// input. input is either a sequence (see `SeqInit`) or a step input of shape
+// [batch_size, features].
+func (l *LSTM) ZeroStateLike(input *ts.Tensor) State {
+	return l.zeroState(l.config.batchDim(input), input.DType(), input.MustDevice())
+}
+
+func (l *LSTM) zeroState(batchDim int64, dtype gotch.DType, device gotch.Device) State {
+	l.placeWeights()
+
+	layerDim := l.config.NumLayers * l.config.numDirections()
 	shape := []int64{layerDim, batchDim, l.hiddenDim}
-	zeros := ts.MustZeros(shape, gotch.Float, l.device)
 
-	retVal := &LSTMState{
-		Tensor1: zeros.MustShallowClone(),
-		Tensor2: zeros.MustShallowClone(),
+	var h0, c0 *ts.Tensor
+	if l.initStates != nil {
+		h0, c0 = l.initStates[0], l.initStates[1]
 	}
 
-	zeros.MustDrop()
-
-	return retVal
+	return &LSTMState{
+		Tensor1: initState(h0, shape, dtype, device),
+		Tensor2: initState(c0, shape, dtype, device),
+	}
 }
 
 func (l *LSTM) Step(input *ts.Tensor, inState State) State {
@@ -157,8 +703,7 @@ func (l *LSTM) Step(input *ts.Tensor, inState State) State {
 }
 
 func (l *LSTM) Seq(input *ts.Tensor) (*ts.Tensor, State) {
-	batchDim := input.MustSize()[0]
-	inState := l.ZeroState(batchDim)
+	inState := l.ZeroStateLike(input)
 
 	output, state := l.SeqInit(input, inState)
 
@@ -170,8 +715,47 @@ func (l *LSTM) Seq(input *ts.Tensor) (*ts.Tensor, State) {
 }
 
 func (l *LSTM) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
+	return l.seqInit(input, nil, inState)
+}
+
+// MaskedSeqInit is `SeqInit` for padded sequences. mask has shape
+// [batch_size, seq_len] (or [seq_len, batch_size] if not `BatchFirst`) with 1
+// for valid timesteps and 0 for padding.
+//
+// The hidden and cell states are left unchanged at padded timesteps, where
+// the output is zero. Hence, the final state of each sequence is the state at
+// its last valid timestep. It runs through the step-by-step path.
+func (l *LSTM) MaskedSeqInit(input, mask *ts.Tensor, inState State) (*ts.Tensor, State) {
+	return l.seqInit(input, mask, inState)
+}
+
+// seqInit implements `SeqInit` and `MaskedSeqInit`. mask is nil for
+// unmasked sequences.
+func (l *LSTM) seqInit(input, mask *ts.Tensor, inState State) (*ts.Tensor, State) {
+	input = l.config.addInputNoise(input)
+	input = l.config.flipTime(input, true)
+	defer input.MustDrop()
+	if mask != nil {
+		mask = l.config.flipTime(mask, false)
+		defer mask.MustDrop()
+	}
 
-	output, h, c := input.MustLstm([]ts.Tensor{*inState.(*LSTMState).Tensor1, *inState.(*LSTMState).Tensor2}, l.flatWeights, l.config.HasBiases, l.config.NumLayers, l.config.Dropout, l.config.Train, l.config.Bidirectional, l.config.BatchFirst)
+	var output, h, c *ts.Tensor
+	seededDropout := l.config.Generator != nil && l.config.Dropout > 0 && l.config.NumLayers > 1
+	if l.config.WeightRank > 0 || l.config.GateClip > 0 || seededDropout || mask != nil {
+		// Factorized weights, clipped gates, seeded dropout and masks run
+		// through the step-by-step path.
+		var state State
+		output, state, _ = l.manualSeqInit(input, mask, inState, false)
+		h, c = state.(*LSTMState).Tensor1, state.(*LSTMState).Tensor2
+	} else {
+		l.initWeights(input)
+		output, h, c = input.MustLstm([]ts.Tensor{*inState.(*LSTMState).Tensor1, *inState.(*LSTMState).Tensor2}, l.flatWeights, l.config.HasBiases, l.config.NumLayers, l.config.Dropout, l.config.Train, l.config.Bidirectional, l.config.BatchFirst)
+	}
+	output = l.config.flipTime(output, true)
+	output = l.config.activateOutput(output)
+	output = l.config.castOutput(output)
+	l.config.checkFinite("LSTM", []string{"output", "hidden state", "cell state"}, output, h, c)
 
 	return output, &LSTMState{
 		Tensor1: h,
@@ -179,6 +763,61 @@ func (l *LSTM) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
 	}
 }
 
+// GateActivations runs the LSTM step by step and returns the input (i),
+// forget (f), cell (g) and output (o) gate activations of the last layer at
+// each timestep.
+//
+// The gates are laid out as the output, i.e. [batch_size, seq_len, hidden_dim]
+// for a unidirectional LSTM with `BatchFirst` set.
+func (l *LSTM) GateActivations(input *ts.Tensor, inState State) (i, f, g, o *ts.Tensor) {
+	output, state, gates := l.manualSeqInit(input, nil, inState, true)
+	output.MustDrop()
+	state.(*LSTMState).Tensor1.MustDrop()
+	state.(*LSTMState).Tensor2.MustDrop()
+
+	return &gates[0], &gates[1], &gates[2], &gates[3]
+}
+
+// ResetParameters re-initializes the LSTM weights in place, keeping their
+// shapes and devices. It is a no-op for a lazy LSTM not run yet.
+func (l *LSTM) ResetParameters() {
+	resetWeights(l.flatWeights, l.reparams)
+}
+
+// Validate checks that the LSTM weights match its config, e.g. after the
+// config was modified. It returns a descriptive error otherwise. A lazy LSTM
+// not run yet is valid.
+func (l *LSTM) Validate() error {
+	if l.flatWeights == nil {
+		return nil
+	}
+
+	return validateFlatWeights("LSTM", l.flatWeights, l.inDim, l.hiddenDim, 4, l.config)
+}
+
+// layerInfo implements rnnLayer. inDim is 0 for a lazy LSTM not run yet.
+func (l *LSTM) layerInfo() (int64, *RNNConfig, gotch.Device) {
+	return l.inDim, l.config, l.device
+}
+
+// Dropout returns the dropout probability between layers.
+func (l *LSTM) Dropout() float64 {
+	return l.config.Dropout
+}
+
+// SetDropout sets the dropout probability between layers, e.g. to anneal it
+// during training. It takes effect on the next forward pass.
+//
+// NOTE. the config is shared by all layers built from it, which are also
+// affected.
+func (l *LSTM) SetDropout(p float64) {
+	if p < 0 || p > 1 {
+		log.Fatalf("LSTM.SetDropout - Expected a probability in [0, 1], got %v\n", p)
+	}
+
+	l.config.Dropout = p
+}
+
 // GRUState is a GRU state. It contains a single tensor.
 type GRUState struct {
 	Tensor *ts.Tensor
@@ -193,68 +832,101 @@ func (gs *GRUState) Value() *ts.Tensor {
 // https://en.wikipedia.org/wiki/Gated_recurrent_unit
 type GRU struct {
 	flatWeights []ts.Tensor
+	reparams    []reparamWeight
 	hiddenDim   int64
+	inDim       int64
 	config      *RNNConfig
 	device      gotch.Device
+	initStates  []*ts.Tensor // learned h0, nil if not `LearnedInitState`
+	deferred    bool         // variables are still on CPU, see `DeferDevice`
 }
 
 // NewGRU create a new GRU layer
 func NewGRU(vs *Path, inDim, hiddenDim int64, cfg *RNNConfig) (retVal *GRU) {
-	var numDirections int64 = 1
-	if cfg.Bidirectional {
-		numDirections = 2
+	wvs, deferred := rnnWeightPath(vs, cfg)
+	flatWeights, reparams := rnnFlatWeights(wvs, inDim, hiddenDim, 3, cfg)
+
+	return &GRU{
+		flatWeights: flatWeights,
+		reparams:    reparams,
+		hiddenDim:   hiddenDim,
+		inDim:       inDim,
+		config:      cfg,
+		device:      vs.Device(),
+		initStates:  rnnInitStates(wvs, hiddenDim, cfg, "h0"),
+		deferred:    deferred,
 	}
+}
 
-	gateDim := 3 * hiddenDim
-	flatWeights := make([]ts.Tensor, 0)
+// placeWeights moves the variables of a GRU built with `DeferDevice` to its
+// device.
+func (g *GRU) placeWeights() {
+	if !g.deferred {
+		return
+	}
 
-	for i := 0; i < int(cfg.NumLayers); i++ {
-		for n := 0; n < int(numDirections); n++ {
-			var inputDim int64
-			if i == 0 {
-				inputDim = inDim
-			} else {
-				inputDim = hiddenDim * numDirections
-			}
+	moveDeferred(g.flatWeights, g.reparams, g.initStates, g.hiddenDim, 3, g.config, g.device)
+	g.deferred = false
+}
 
-			wIh := vs.KaimingUniform("w_ih", []int64{gateDim, inputDim})
-			wHh := vs.KaimingUniform("w_hh", []int64{gateDim, hiddenDim})
-			bIh := vs.Zeros("b_ih", []int64{gateDim})
-			bHh := vs.Zeros("b_hh", []int64{gateDim})
+// ResetParameters re-initializes the GRU weights in place, keeping their
+// shapes and devices.
+func (g *GRU) ResetParameters() {
+	resetWeights(g.flatWeights, g.reparams)
+}
 
-			flatWeights = append(flatWeights, *wIh, *wHh, *bIh, *bHh)
-		}
-	}
+// Validate checks that the GRU weights match its config, e.g. after the
+// config was modified. It returns a descriptive error otherwise.
+func (g *GRU) Validate() error {
+	return validateFlatWeights("GRU", g.flatWeights, g.inDim, g.hiddenDim, 3, g.config)
+}
 
-	if vs.Device().IsCuda() {
-		// NOTE. 3 is for GRU
-		// ref. rnn.cpp in Pytorch
-		ts.Must_CudnnRnnFlattenWeight(flatWeights, 4, inDim, 3, hiddenDim, cfg.NumLayers, cfg.BatchFirst, cfg.Bidirectional)
-	}
+// layerInfo implements rnnLayer.
+func (g *GRU) layerInfo() (int64, *RNNConfig, gotch.Device) {
+	return g.inDim, g.config, g.device
+}
 
-	return &GRU{
-		flatWeights: flatWeights,
-		hiddenDim:   hiddenDim,
-		config:      cfg,
-		device:      vs.Device(),
+// Dropout returns the dropout probability between layers.
+func (g *GRU) Dropout() float64 {
+	return g.config.Dropout
+}
+
+// SetDropout sets the dropout probability between layers. See
+// `LSTM.SetDropout`.
+func (g *GRU) SetDropout(p float64) {
+	if p < 0 || p > 1 {
+		log.Fatalf("GRU.SetDropout - Expected a probability in [0, 1], got %v\n", p)
 	}
+
+	g.config.Dropout = p
 }
 
 // Implement RNN interface for GRU:
 // ================================
 
 func (g *GRU) ZeroState(batchDim int64) State {
-	var numDirections int64 = 1
-	if g.config.Bidirectional {
-		numDirections = 2
-	}
+	return g.zeroState(batchDim, g.config.dtype(), g.device)
+}
+
+// ZeroStateLike creates a zero state with the batch size, dtype and device of
+// input. input is either a sequence (see `SeqInit`) or a step input of shape
+// [batch_size, features].
+func (g *GRU) ZeroStateLike(input *ts.Tensor) State {
+	return g.zeroState(g.config.batchDim(input), input.DType(), input.MustDevice())
+}
 
-	layerDim := g.config.NumLayers * numDirections
+func (g *GRU) zeroState(batchDim int64, dtype gotch.DType, device gotch.Device) State {
+	g.placeWeights()
+
+	layerDim := g.config.NumLayers * g.config.numDirections()
 	shape := []int64{layerDim, batchDim, g.hiddenDim}
 
-	tensor := ts.MustZeros(shape, gotch.Float, g.device)
+	var h0 *ts.Tensor
+	if g.initStates != nil {
+		h0 = g.initStates[0]
+	}
 
-	return &GRUState{Tensor: tensor}
+	return &GRUState{Tensor: initState(h0, shape, dtype, device)}
 }
 
 func (g *GRU) Step(input *ts.Tensor, inState State) State {
@@ -270,8 +942,7 @@ func (g *GRU) Step(input *ts.Tensor, inState State) State {
 }
 
 func (g *GRU) Seq(input *ts.Tensor) (*ts.Tensor, State) {
-	batchDim := input.MustSize()[0]
-	inState := g.ZeroState(batchDim)
+	inState := g.ZeroStateLike(input)
 
 	output, state := g.SeqInit(input, inState)
 
@@ -282,8 +953,53 @@ func (g *GRU) Seq(input *ts.Tensor) (*ts.Tensor, State) {
 }
 
 func (g *GRU) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
-
-	output, h := input.MustGru(inState.(*GRUState).Tensor, g.flatWeights, g.config.HasBiases, g.config.NumLayers, g.config.Dropout, g.config.Train, g.config.Bidirectional, g.config.BatchFirst)
+	g.placeWeights()
+	refreshWeights(g.flatWeights, g.reparams)
+
+	input = g.config.addInputNoise(input)
+	input = g.config.flipTime(input, true)
+	defer input.MustDrop()
+
+	var output, h *ts.Tensor
+	if g.config.Generator != nil && g.config.Dropout > 0 && g.config.NumLayers > 1 {
+		output, h = g.layerwiseSeqInit(input, inState.(*GRUState).Tensor)
+	} else {
+		output, h = input.MustGru(inState.(*GRUState).Tensor, g.flatWeights, g.config.HasBiases, g.config.NumLayers, g.config.Dropout, g.config.Train, g.config.Bidirectional, g.config.BatchFirst)
+	}
+	output = g.config.flipTime(output, true)
+	output = g.config.activateOutput(output)
+	output = g.config.castOutput(output)
+	g.config.checkFinite("GRU", []string{"output", "hidden state"}, output, h)
 
 	return output, &GRUState{Tensor: h}
 }
+
+// layerwiseSeqInit runs the GRU one layer at a time, applying inter-layer
+// dropout with masks drawn from `Generator` in between.
+func (g *GRU) layerwiseSeqInit(input, h0 *ts.Tensor) (output, h *ts.Tensor) {
+	numDirections := g.config.numDirections()
+	layerLen := 4 * numDirections
+
+	xs := input.MustShallowClone()
+	hs := make([]ts.Tensor, g.config.NumLayers)
+	for layer := int64(0); layer < g.config.NumLayers; layer++ {
+		layerH0 := h0.MustNarrow(0, layer*numDirections, numDirections, false)
+		weights := g.flatWeights[layer*layerLen : (layer+1)*layerLen]
+		layerOutput, layerH := xs.MustGru(layerH0, weights, g.config.HasBiases, 1, 0, g.config.Train, g.config.Bidirectional, g.config.BatchFirst)
+		layerH0.MustDrop()
+		xs.MustDrop()
+
+		hs[layer] = *layerH
+		xs = layerOutput
+		if layer < g.config.NumLayers-1 {
+			xs = g.config.dropout(xs)
+		}
+	}
+
+	h = ts.MustCat(hs, 0)
+	for i := range hs {
+		hs[i].MustDrop()
+	}
+
+	return xs, h
+}
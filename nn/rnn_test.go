@@ -1,8 +1,13 @@
 package nn_test
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"os"
+	"os/exec"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sugarme/gotch"
@@ -147,3 +152,811 @@ func TestLSTM(t *testing.T) {
 	cfg.Bidirectional = true
 	lstmTest(cfg, t)
 }
+
+func TestLazyLSTM(t *testing.T) {
+	var (
+		batchDim  int64 = 5
+		seqLen    int64 = 3
+		inputDim  int64 = 7
+		outputDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLazyLSTM(vs.Root(), outputDim, nn.DefaultRNNConfig())
+
+	if vs.Len() != 0 {
+		t.Errorf("Expected no variables before first forward, got %v\n", vs.Len())
+	}
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+	output, _ := lstm.Seq(input)
+
+	wantSeq := []int64{batchDim, seqLen, outputDim}
+	gotSeq := output.MustSize()
+	if !reflect.DeepEqual(wantSeq, gotSeq) {
+		t.Errorf("Expected ouput shape: %v\n", wantSeq)
+		t.Errorf("Got output shape: %v\n", gotSeq)
+	}
+
+	if vs.Len() != 4 {
+		t.Errorf("Expected 4 variables after first forward, got %v\n", vs.Len())
+	}
+
+	wantWIh := []int64{4 * outputDim, inputDim}
+	gotWIh := vs.Variables()["w_ih"].MustSize()
+	if !reflect.DeepEqual(wantWIh, gotWIh) {
+		t.Errorf("Expected w_ih shape: %v\n", wantWIh)
+		t.Errorf("Got w_ih shape: %v\n", gotWIh)
+	}
+
+	// Weights are reused on subsequent calls.
+	_, _ = lstm.Seq(input)
+	if vs.Len() != 4 {
+		t.Errorf("Expected weights to be reused, got %v variables\n", vs.Len())
+	}
+}
+
+func TestLazyLSTMInputMismatch(t *testing.T) {
+	// The mismatch is fatal, hence checked in a child process.
+	if os.Getenv("GOTCH_TEST_LAZY_LSTM_MISMATCH") == "1" {
+		vs := nn.NewVarStore(gotch.CPU)
+		lstm := nn.NewLazyLSTM(vs.Root(), 4, nn.DefaultRNNConfig())
+		lstm.Seq(ts.MustRandn([]int64{2, 3, 7}, gotch.Float, gotch.CPU))
+		lstm.Seq(ts.MustRandn([]int64{2, 3, 5}, gotch.Float, gotch.CPU))
+		return
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLazyLSTMInputMismatch$")
+	cmd.Env = append(os.Environ(), "GOTCH_TEST_LAZY_LSTM_MISMATCH=1")
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("Expected the second forward pass to fail, got error %v\n", err)
+	}
+	if want := "Expected input feature dimension 7, got 5"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("Expected error %q, got %q\n", want, stderr.String())
+	}
+}
+
+func TestLSTMGateActivations(t *testing.T) {
+	var (
+		batchDim  int64 = 5
+		seqLen    int64 = 3
+		inputDim  int64 = 2
+		outputDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	lstm := nn.NewLSTM(vs.Root(), inputDim, outputDim, cfg)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+	i, f, g, o := lstm.GateActivations(input, lstm.ZeroState(batchDim))
+
+	want := []int64{batchDim, seqLen, outputDim}
+	for name, gate := range map[string]*ts.Tensor{"i": i, "f": f, "g": g, "o": o} {
+		got := gate.MustSize()
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("Expected %v gate shape: %v\n", name, want)
+			t.Errorf("Got %v gate shape: %v\n", name, got)
+		}
+	}
+
+	for name, gate := range map[string]*ts.Tensor{"i": i, "f": f, "o": o} {
+		min := gate.MustMin(false).Float64Values()[0]
+		max := gate.MustMax(false).Float64Values()[0]
+		if min < 0 || max > 1 {
+			t.Errorf("Expected %v gate in [0, 1], got [%v, %v]\n", name, min, max)
+		}
+	}
+
+	min := g.MustMin(false).Float64Values()[0]
+	max := g.MustMax(false).Float64Values()[0]
+	if min < -1 || max > 1 {
+		t.Errorf("Expected g gate in [-1, 1], got [%v, %v]\n", min, max)
+	}
+}
+
+// maxAbsDiff returns the largest absolute element-wise difference of 2 tensors.
+func maxAbsDiff(a, b *ts.Tensor) float64 {
+	diff := a.MustSub(b, false).MustAbs(true)
+	retVal := diff.MustMax(true).Float64Values()[0]
+
+	return retVal
+}
+
+func TestRNNInputNoise(t *testing.T) {
+	var (
+		batchDim  int64 = 5
+		seqLen    int64 = 3
+		inputDim  int64 = 2
+		outputDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.InputNoiseStd = 0.5
+	lstm := nn.NewLSTM(vs.Root(), inputDim, outputDim, cfg)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+
+	ts.ManualSeed(42)
+	trainOut1, _ := lstm.Seq(input)
+	ts.ManualSeed(42)
+	trainOut2, _ := lstm.Seq(input)
+
+	if diff := maxAbsDiff(trainOut1, trainOut2); diff != 0 {
+		t.Errorf("Expected identical outputs with the same seed, got max difference %v\n", diff)
+	}
+
+	cfg.Train = false
+	evalOut1, _ := lstm.Seq(input)
+	ts.ManualSeed(7)
+	evalOut2, _ := lstm.Seq(input)
+
+	if diff := maxAbsDiff(evalOut1, evalOut2); diff != 0 {
+		t.Errorf("Expected no noise in eval mode, got max difference %v\n", diff)
+	}
+
+	if diff := maxAbsDiff(trainOut1, evalOut1); diff == 0 {
+		t.Errorf("Expected noise to be applied in train mode\n")
+	}
+}
+
+func TestRNNDebugCheckFinite(t *testing.T) {
+	var (
+		batchDim  int64 = 5
+		seqLen    int64 = 3
+		inputDim  int64 = 2
+		outputDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.DebugCheckFinite = true
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), inputDim, outputDim, cfg)
+	gru := nn.NewGRU(vs.Root().Sub("gru"), inputDim, outputDim, cfg)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+
+	// Finite input: no panic.
+	lstm.Seq(input)
+	gru.Seq(input)
+
+	nanInput := input.MustMul1(ts.FloatScalar(math.NaN()), false)
+
+	for _, rnn := range []nn.RNN{lstm, gru} {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Errorf("%T - Expected a panic on NaN input\n", rnn)
+					return
+				}
+				if !strings.Contains(fmt.Sprint(r), "output") {
+					t.Errorf("%T - Expected error naming the output tensor, got: %v\n", rnn, r)
+				}
+			}()
+			rnn.Seq(nanInput)
+		}()
+	}
+}
+
+func TestRNNResetParameters(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 2, 4, cfg)
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 2, 4, cfg)
+
+	ts.NoGrad(func() {
+		for name, v := range vs.Vars.NamedVariables {
+			if strings.Contains(name, "b_") {
+				v.MustFill_(ts.FloatScalar(1.0))
+			}
+		}
+	})
+
+	type snapshot struct {
+		values []float64
+		shape  []int64
+		device gotch.Device
+	}
+	before := make(map[string]snapshot)
+	for name, v := range vs.Vars.NamedVariables {
+		before[name] = snapshot{v.Float64Values(), v.MustSize(), v.MustDevice()}
+	}
+
+	lstm.ResetParameters()
+	gru.ResetParameters()
+
+	for name, v := range vs.Vars.NamedVariables {
+		b := before[name]
+		if got := v.MustSize(); !reflect.DeepEqual(b.shape, got) {
+			t.Errorf("%v - Expected shape %v, got %v\n", name, b.shape, got)
+		}
+		if got := v.MustDevice(); !reflect.DeepEqual(b.device, got) {
+			t.Errorf("%v - Expected device %v, got %v\n", name, b.device, got)
+		}
+		if reflect.DeepEqual(b.values, v.Float64Values()) {
+			t.Errorf("%v - Expected values to change after reset\n", name)
+		}
+		if strings.Contains(name, "b_") {
+			if m := v.MustAbs(false).MustMax(true).Float64Values()[0]; m != 0 {
+				t.Errorf("%v - Expected zero biases after reset, got max %v\n", name, m)
+			}
+		}
+	}
+
+	// Layers still run after a reset.
+	input := ts.MustRandn([]int64{3, 5, 2}, gotch.Float, gotch.CPU)
+	lstm.Seq(input)
+	gru.Seq(input)
+}
+
+func TestRNNZeroStateLike(t *testing.T) {
+	var (
+		batchDim  int64 = 5
+		seqLen    int64 = 3
+		inputDim  int64 = 2
+		outputDim int64 = 4
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), inputDim, outputDim, nn.DefaultRNNConfig())
+	gru := nn.NewGRU(vs.Root().Sub("gru"), inputDim, outputDim, nn.DefaultRNNConfig())
+	vs.ToDType(gotch.Double)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Double, gotch.CPU)
+
+	lstmState := lstm.ZeroStateLike(input).(*nn.LSTMState)
+	if got := lstmState.Tensor1.DType(); got != gotch.Double {
+		t.Errorf("Expected LSTM state dtype %v, got %v\n", gotch.Double, got)
+	}
+	if want, got := []int64{1, batchDim, outputDim}, lstmState.Tensor2.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected LSTM state shape %v, got %v\n", want, got)
+	}
+	output, _ := lstm.SeqInit(input, lstmState)
+	if got := output.DType(); got != gotch.Double {
+		t.Errorf("Expected LSTM output dtype %v, got %v\n", gotch.Double, got)
+	}
+
+	gruState := gru.ZeroStateLike(input).(*nn.GRUState)
+	if got := gruState.Tensor.DType(); got != gotch.Double {
+		t.Errorf("Expected GRU state dtype %v, got %v\n", gotch.Double, got)
+	}
+
+	// Seq creates its initial state from the input.
+	_, state := gru.Seq(input)
+	if got := state.(*nn.GRUState).Tensor.DType(); got != gotch.Double {
+		t.Errorf("Expected GRU state dtype %v after Seq, got %v\n", gotch.Double, got)
+	}
+}
+
+func TestRNNReversed(t *testing.T) {
+	var (
+		batchDim  int64 = 5
+		seqLen    int64 = 4
+		inputDim  int64 = 2
+		outputDim int64 = 3
+	)
+
+	input := ts.MustRandn([]int64{batchDim, seqLen, inputDim}, gotch.Float, gotch.CPU)
+	flipped := input.MustFlip([]int64{1}, false)
+
+	newRNNs := map[string]func(vs *nn.VarStore, cfg *nn.RNNConfig) nn.RNN{
+		"LSTM": func(vs *nn.VarStore, cfg *nn.RNNConfig) nn.RNN {
+			return nn.NewLSTM(vs.Root(), inputDim, outputDim, cfg)
+		},
+		"GRU": func(vs *nn.VarStore, cfg *nn.RNNConfig) nn.RNN {
+			return nn.NewGRU(vs.Root(), inputDim, outputDim, cfg)
+		},
+	}
+
+	for name, newRNN := range newRNNs {
+		cfg := nn.DefaultRNNConfig()
+		cfg.Reversed = true
+		reversedVs := nn.NewVarStore(gotch.CPU)
+		reversed := newRNN(reversedVs, cfg)
+
+		forwardVs := nn.NewVarStore(gotch.CPU)
+		forward := newRNN(forwardVs, nn.DefaultRNNConfig())
+		if err := forwardVs.Copy(*reversedVs); err != nil {
+			t.Fatal(err)
+		}
+
+		got, _ := reversed.Seq(input)
+		forwardOut, _ := forward.Seq(flipped)
+		want := forwardOut.MustFlip([]int64{1}, true)
+
+		if diff := maxAbsDiff(want, got); diff > 1e-6 {
+			t.Errorf("%v - Expected reversed output to match flipped forward output, got max difference %v\n", name, diff)
+		}
+	}
+}
+
+func TestRNNLearnedInitState(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	cfg.Bidirectional = true
+	cfg.LearnedInitState = true
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 2, 4, cfg)
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 2, 4, cfg)
+
+	initVars, err := vs.TrainableVariablesMatching("\\.(h0|c0)$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(initVars) != 3 {
+		t.Fatalf("Expected 3 trainable init-state variables, got %v\n", len(initVars))
+	}
+	for _, v := range initVars {
+		if got, want := v.MustSize(), []int64{4, 1, 4}; !reflect.DeepEqual(want, got) {
+			t.Errorf("Expected init-state shape %v, got %v\n", want, got)
+		}
+	}
+
+	state := lstm.ZeroState(3).(*nn.LSTMState)
+	if got, want := state.H().MustSize(), []int64{4, 3, 4}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected LSTM state shape %v, got %v\n", want, got)
+	}
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := make([][]float64, len(initVars))
+	for i, v := range initVars {
+		before[i] = v.Float64Values()
+	}
+
+	input := ts.MustRandn([]int64{3, 5, 2}, gotch.Float, gotch.CPU)
+	lstmOut, _ := lstm.Seq(input)
+	gruOut, _ := gru.Seq(input)
+	loss := lstmOut.MustSum(gotch.Float, true).MustAdd(gruOut.MustSum(gotch.Float, true), true)
+	opt.BackwardStep(loss)
+
+	for i, v := range initVars {
+		if reflect.DeepEqual(before[i], v.Float64Values()) {
+			t.Errorf("Expected init-state variable %v to change after an optimizer step\n", i)
+		}
+	}
+}
+
+func TestRNNShareLayerWeights(t *testing.T) {
+	newRNNs := func(numLayers int64, share bool) (*nn.VarStore, *nn.LSTM, *nn.GRU) {
+		vs := nn.NewVarStore(gotch.CPU)
+		cfg := nn.DefaultRNNConfig()
+		cfg.NumLayers = numLayers
+		cfg.ShareLayerWeights = share
+		lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 4, 4, cfg)
+		gru := nn.NewGRU(vs.Root().Sub("gru"), 4, 4, cfg)
+		return vs, lstm, gru
+	}
+
+	singleVs, _, _ := newRNNs(1, false)
+	want := numParameters(singleVs)
+
+	for _, numLayers := range []int64{1, 2, 5} {
+		vs, lstm, gru := newRNNs(numLayers, true)
+		if got := numParameters(vs); got != want {
+			t.Errorf("%v layers - Expected %v parameters, got %v\n", numLayers, want, got)
+		}
+
+		input := ts.MustRandn([]int64{3, 5, 4}, gotch.Float, gotch.CPU)
+		lstmOut, lstmState := lstm.Seq(input)
+		if got, want := lstmOut.MustSize(), []int64{3, 5, 4}; !reflect.DeepEqual(want, got) {
+			t.Errorf("%v layers - Expected LSTM output shape %v, got %v\n", numLayers, want, got)
+		}
+		if got, want := lstmState.(*nn.LSTMState).H().MustSize(), []int64{numLayers, 3, 4}; !reflect.DeepEqual(want, got) {
+			t.Errorf("%v layers - Expected LSTM state shape %v, got %v\n", numLayers, want, got)
+		}
+		gruOut, _ := gru.Seq(input)
+		if got, want := gruOut.MustSize(), []int64{3, 5, 4}; !reflect.DeepEqual(want, got) {
+			t.Errorf("%v layers - Expected GRU output shape %v, got %v\n", numLayers, want, got)
+		}
+	}
+}
+
+func TestRNNOutputActivation(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 2, 4, cfg)
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 2, 4, cfg)
+
+	// Large inputs so that raw outputs are not already squashed.
+	input := ts.MustRandn([]int64{3, 5, 2}, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(10), true)
+
+	rawLSTM, rawLSTMState := lstm.Seq(input)
+	rawGRU, rawGRUState := gru.Seq(input)
+
+	cfg.OutputActivation = func(xs *ts.Tensor) *ts.Tensor {
+		return xs.MustMul1(ts.FloatScalar(5), false).MustTanh(true)
+	}
+	lstmOut, lstmState := lstm.Seq(input)
+	gruOut, gruState := gru.Seq(input)
+
+	tests := []struct {
+		name         string
+		raw, output  *ts.Tensor
+		rawState, st *ts.Tensor
+	}{
+		{"LSTM", rawLSTM, lstmOut, rawLSTMState.(*nn.LSTMState).H(), lstmState.(*nn.LSTMState).H()},
+		{"GRU", rawGRU, gruOut, rawGRUState.(*nn.GRUState).Value(), gruState.(*nn.GRUState).Value()},
+	}
+	for _, tt := range tests {
+		if m := tt.output.MustAbs(false).MustMax(true).Float64Values()[0]; m > 1 {
+			t.Errorf("%v - Expected output in [-1, 1], got max magnitude %v\n", tt.name, m)
+		}
+
+		want := cfg.OutputActivation(tt.raw)
+		if diff := maxAbsDiff(want, tt.output); diff > 1e-6 {
+			t.Errorf("%v - Expected activated output, got max difference %v\n", tt.name, diff)
+		}
+		if diff := maxAbsDiff(tt.rawState, tt.st); diff > 1e-6 {
+			t.Errorf("%v - Expected state to be unaffected, got max difference %v\n", tt.name, diff)
+		}
+	}
+}
+
+func TestRNNBFloat16(t *testing.T) {
+	if !gotch.CUDA.IsAvailable() {
+		t.Skip("CUDA is not available")
+	}
+	device := gotch.CudaBuilder(0)
+
+	vs := nn.NewVarStore(device)
+	cfg := nn.DefaultRNNConfig()
+	cfg.DType = gotch.BFloat16
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 4, 8, cfg)
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 4, 8, cfg)
+
+	for name, v := range vs.Vars.NamedVariables {
+		if got := v.DType(); got != gotch.BFloat16 {
+			t.Errorf("%v - Expected bfloat16 weights, got %v\n", name, got)
+		}
+	}
+	if got := lstm.ZeroState(2).(*nn.LSTMState).H().DType(); got != gotch.BFloat16 {
+		t.Errorf("Expected a bfloat16 zero state, got %v\n", got)
+	}
+
+	input := ts.MustRandn([]int64{2, 5, 4}, gotch.BFloat16, device)
+	lstmOut, _ := lstm.Seq(input)
+	gruOut, _ := gru.Seq(input)
+	for name, output := range map[string]*ts.Tensor{"LSTM": lstmOut, "GRU": gruOut} {
+		if got := output.DType(); got != gotch.BFloat16 {
+			t.Errorf("%v - Expected bfloat16 output, got %v\n", name, got)
+		}
+	}
+
+	loss := lstmOut.MustSum(gotch.Float, false).MustAdd(gruOut.MustSum(gotch.Float, false), true)
+	loss.MustBackward()
+	for name, v := range vs.Vars.NamedVariables {
+		if !v.MustGrad(false).MustDefined() {
+			t.Errorf("%v - Expected a gradient after backward\n", name)
+		}
+	}
+}
+
+func TestRNNOutputDType(t *testing.T) {
+	if !gotch.CUDA.IsAvailable() {
+		t.Skip("CUDA is not available")
+	}
+	device := gotch.CudaBuilder(0)
+
+	vs32 := nn.NewVarStore(device)
+	lstm32 := nn.NewLSTM(vs32.Root(), 4, 8, nn.DefaultRNNConfig())
+
+	vs16 := nn.NewVarStore(device)
+	cfg := nn.DefaultRNNConfig()
+	cfg.DType = gotch.Half
+	cfg.OutputDType = gotch.Float
+	lstm16 := nn.NewLSTM(vs16.Root(), 4, 8, cfg)
+	if err := vs16.Copy(*vs32); err != nil {
+		t.Fatal(err)
+	}
+
+	input := ts.MustRandn([]int64{2, 5, 4}, gotch.Float, device)
+	want, _ := lstm32.Seq(input)
+	input16 := input.MustTotype(gotch.Half, false)
+	got, state := lstm16.Seq(input16)
+
+	if dtype := got.DType(); dtype != gotch.Float {
+		t.Errorf("Expected float output, got %v\n", dtype)
+	}
+	if dtype := state.(*nn.LSTMState).Tensor1.DType(); dtype != gotch.Half {
+		t.Errorf("Expected the state to keep the half dtype, got %v\n", dtype)
+	}
+	if diff := maxAbsDiff(got, want); diff > 1e-2 {
+		t.Errorf("Expected output close to the float run, got max difference %v\n", diff)
+	}
+}
+
+func TestRNNGateClip(t *testing.T) {
+	const clip = 2.0
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.GateClip = clip
+	lstm := nn.NewLSTM(vs.Root(), 2, 4, cfg)
+
+	// Huge inputs would saturate the gates to exactly 0 or 1 without clipping.
+	input := ts.MustRandn([]int64{3, 5, 2}, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(1000), true)
+	i, f, g, o := lstm.GateActivations(input, lstm.ZeroState(3))
+
+	sigmoidClip := 1 / (1 + math.Exp(-clip))
+	for name, gate := range map[string]*ts.Tensor{"i": i, "f": f, "o": o} {
+		min := gate.MustMin(false).Float64Values()[0]
+		max := gate.MustMax(false).Float64Values()[0]
+		if min < 1-sigmoidClip-1e-6 || max > sigmoidClip+1e-6 || min == 0 || max == 1 {
+			t.Errorf("Expected %v gate in [%v, %v], got [%v, %v]\n", name, 1-sigmoidClip, sigmoidClip, min, max)
+		}
+	}
+	if m := g.MustAbs(false).MustMax(true).Float64Values()[0]; m > math.Tanh(clip)+1e-6 {
+		t.Errorf("Expected g gate magnitude below %v, got %v\n", math.Tanh(clip), m)
+	}
+
+	// The fused path is not used with clipped gates.
+	output, _ := lstm.Seq(input)
+	if m := output.MustAbs(false).MustMax(true).Float64Values()[0]; m >= 1 {
+		t.Errorf("Expected an unsaturated output, got max magnitude %v\n", m)
+	}
+}
+
+func TestRNNGenerator(t *testing.T) {
+	gen := ts.MustNewGenerator(42)
+	defer gen.Drop()
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	cfg.Dropout = 0.5
+	cfg.InputNoiseStd = 0.1
+	cfg.Generator = gen
+	lstm := nn.NewLSTM(vs.Root(), 2, 4, cfg)
+
+	input := ts.MustRandn([]int64{3, 5, 2}, gotch.Float, gotch.CPU)
+	out1, _ := lstm.Seq(input)
+	gen.ManualSeed(42)
+	out2, _ := lstm.Seq(input)
+	gen.ManualSeed(7)
+	out3, _ := lstm.Seq(input)
+
+	if !reflect.DeepEqual(out1.Float64Values(), out2.Float64Values()) {
+		t.Errorf("Expected identical outputs with the same seed\n")
+	}
+	if reflect.DeepEqual(out1.Float64Values(), out3.Float64Values()) {
+		t.Errorf("Expected different outputs with different seeds\n")
+	}
+
+	// GRU inter-layer dropout.
+	gruCfg := nn.DefaultRNNConfig()
+	gruCfg.NumLayers = 2
+	gruCfg.Dropout = 0.5
+	gruCfg.Generator = gen
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 2, 4, gruCfg)
+
+	gen.ManualSeed(42)
+	gruOut1, _ := gru.Seq(input)
+	gen.ManualSeed(42)
+	gruOut2, _ := gru.Seq(input)
+	gen.ManualSeed(7)
+	gruOut3, _ := gru.Seq(input)
+
+	if !reflect.DeepEqual(gruOut1.Float64Values(), gruOut2.Float64Values()) {
+		t.Errorf("GRU - Expected identical outputs with the same seed\n")
+	}
+	if reflect.DeepEqual(gruOut1.Float64Values(), gruOut3.Float64Values()) {
+		t.Errorf("GRU - Expected different outputs with different seeds\n")
+	}
+
+	// Without dropout, running one layer at a time matches the fused kernel.
+	gruCfg.Train = false
+	layerwise, _ := gru.Seq(input)
+	gruCfg.Generator = nil
+	fused, _ := gru.Seq(input)
+	if diff := maxAbsDiff(layerwise, fused); diff > 1e-6 {
+		t.Errorf("GRU - Expected layer-wise output to match the fused one, got max difference %v\n", diff)
+	}
+}
+
+func TestRNNDeferDevice(t *testing.T) {
+	if !gotch.CUDA.IsAvailable() {
+		t.Skip("CUDA is not available")
+	}
+	device := gotch.CudaBuilder(0)
+
+	vs := nn.NewVarStore(device)
+	cfg := nn.DefaultRNNConfig()
+	cfg.DeferDevice = true
+	cfg.LearnedInitState = true
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 4, 8, cfg)
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 4, 8, cfg)
+
+	for name, v := range vs.Vars.NamedVariables {
+		if got := v.MustDevice(); got != gotch.CPU {
+			t.Errorf("%v - Expected variable on CPU before the first forward, got %v\n", name, got)
+		}
+	}
+
+	lazyLSTM := nn.NewLazyLSTM(vs.Root().Sub("lazy_lstm"), 8, cfg)
+
+	input := ts.MustRandn([]int64{2, 5, 4}, gotch.Float, device)
+	lstmOut, _ := lstm.Seq(input)
+	gruOut, _ := gru.Seq(input)
+	lazyOut, _ := lazyLSTM.Seq(input)
+
+	for name, v := range vs.Vars.NamedVariables {
+		if got := v.MustDevice(); got != device {
+			t.Errorf("%v - Expected variable on %v after the first forward, got %v\n", name, device, got)
+		}
+	}
+	for name, output := range map[string]*ts.Tensor{"LSTM": lstmOut, "GRU": gruOut, "lazy LSTM": lazyOut} {
+		if got := output.MustDevice(); got != device {
+			t.Errorf("%v - Expected output on %v, got %v\n", name, device, got)
+		}
+	}
+}
+
+func TestRNNSetDropout(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	lstm := nn.NewLSTM(vs.Root(), 3, 8, cfg)
+	input := ts.MustRandn([]int64{4, 6, 3}, gotch.Float, gotch.CPU)
+
+	// deterministic reports whether two training forward passes agree.
+	deterministic := func() bool {
+		out1, _ := lstm.Seq(input)
+		out2, _ := lstm.Seq(input)
+		return maxAbsDiff(out1, out2) == 0
+	}
+
+	if !deterministic() {
+		t.Errorf("Expected no dropout by default\n")
+	}
+
+	lstm.SetDropout(0.9)
+	if got := lstm.Dropout(); got != 0.9 {
+		t.Errorf("Expected dropout 0.9, got %v\n", got)
+	}
+	if deterministic() {
+		t.Errorf("Expected the new dropout to be used on the next forward pass\n")
+	}
+
+	lstm.SetDropout(0)
+	if !deterministic() {
+		t.Errorf("Expected dropout to be disabled again\n")
+	}
+}
+
+func TestRNNValidate(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	lstmCfg := nn.DefaultRNNConfig()
+	lstmCfg.NumLayers = 2
+	lstmCfg.Bidirectional = true
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 3, 4, lstmCfg)
+	gruCfg := nn.DefaultRNNConfig()
+	gru := nn.NewGRU(vs.Root().Sub("gru"), 3, 4, gruCfg)
+
+	for name, rnn := range map[string]interface{ Validate() error }{"LSTM": lstm, "GRU": gru} {
+		if err := rnn.Validate(); err != nil {
+			t.Errorf("%v - Expected valid weights, got error: %v\n", name, err)
+		}
+	}
+
+	// Count mismatch: 1 layer of weights for a 2-layer config.
+	gruCfg.NumLayers = 2
+	if err := gru.Validate(); err == nil || !strings.Contains(err.Error(), "Expected 8 flat weights") {
+		t.Errorf("GRU - Expected a weight count error, got %v\n", err)
+	}
+
+	// Same count, wrong shapes: the backward direction of layer 0 is read as
+	// layer 1, whose w_ih should take the [4 * hidden_dim] outputs of layer 0.
+	lstmCfg.NumLayers = 4
+	lstmCfg.Bidirectional = false
+	if err := lstm.Validate(); err == nil || !strings.Contains(err.Error(), "w_ih of layer 1") {
+		t.Errorf("LSTM - Expected a weight shape error, got %v\n", err)
+	}
+}
+
+func TestLSTMMaskedSeqInit(t *testing.T) {
+	lengths := []int64{5, 3}
+
+	for _, bidirectional := range []bool{false, true} {
+		vs := nn.NewVarStore(gotch.CPU)
+		cfg := nn.DefaultRNNConfig()
+		cfg.NumLayers = 2
+		cfg.Bidirectional = bidirectional
+		lstm := nn.NewLSTM(vs.Root(), 3, 4, cfg)
+
+		// The second sequence is padded with random values.
+		input := ts.MustRandn([]int64{2, 5, 3}, gotch.Float, gotch.CPU)
+		mask := ts.MustOfSlice([]float32{1, 1, 1, 1, 1, 1, 1, 1, 0, 0}).MustView([]int64{2, 5}, true)
+
+		inState := lstm.ZeroState(2)
+		output, state := lstm.MaskedSeqInit(input, mask, inState)
+		h, c := state.(*nn.LSTMState).H(), state.(*nn.LSTMState).C()
+
+		for i, length := range lengths {
+			seq := input.MustNarrow(0, int64(i), 1, false).MustNarrow(1, 0, length, true)
+			wantOutput, wantState := lstm.Seq(seq)
+
+			for name, pair := range map[string][2]*ts.Tensor{
+				"hidden state": {wantState.(*nn.LSTMState).H(), h.MustNarrow(1, int64(i), 1, false)},
+				"cell state":   {wantState.(*nn.LSTMState).C(), c.MustNarrow(1, int64(i), 1, false)},
+				"output":       {wantOutput, output.MustNarrow(0, int64(i), 1, false).MustNarrow(1, 0, length, true)},
+			} {
+				if diff := maxAbsDiff(pair[0], pair[1]); diff > 1e-5 {
+					t.Errorf("Bidirectional %v, sequence %v - Expected %v to match the unpadded run, got max difference %v\n", bidirectional, i, name, diff)
+				}
+			}
+		}
+
+		// Padded outputs are zero.
+		padded := output.MustNarrow(0, 1, 1, false).MustNarrow(1, 3, 2, true)
+		if m := padded.MustAbs(true).MustMax(true).Float64Values()[0]; m != 0 {
+			t.Errorf("Bidirectional %v - Expected zero padded outputs, got max magnitude %v\n", bidirectional, m)
+		}
+	}
+}
+
+func TestRNNNoGrad(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	lstm := nn.NewLSTM(vs.Root(), 2, 4, nn.DefaultRNNConfig())
+	input := ts.MustRandn([]int64{3, 5, 2}, gotch.Float, gotch.CPU)
+
+	output, _ := lstm.Seq(input)
+	if !output.MustRequiresGrad() {
+		t.Fatalf("Expected output to require gradients outside NoGrad\n")
+	}
+
+	// Backward functions of the graph hold the activations saved for the
+	// backward pass.
+	gradNodes, _, err := output.AutogradGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backwardFns int
+	for _, node := range gradNodes {
+		if !strings.HasSuffix(node, "AccumulateGrad") {
+			backwardFns++
+		}
+	}
+	if backwardFns == 0 {
+		t.Fatalf("Expected backward functions outside NoGrad, got nodes %v\n", gradNodes)
+	}
+
+	// No autograd graph is recorded, hence no activations are held for
+	// backward.
+	var noGradOutput *ts.Tensor
+	ts.NoGrad(func() {
+		noGradOutput, _ = lstm.Seq(input)
+	})
+	if noGradOutput.MustRequiresGrad() {
+		t.Errorf("Expected output not to require gradients inside NoGrad\n")
+	}
+	nodes, _, err := noGradOutput.AutogradGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 0 {
+		t.Errorf("Expected no autograd graph inside NoGrad, got nodes %v (vs %v backward functions with gradients)\n", nodes, backwardFns)
+	}
+	if diff := maxAbsDiff(output, noGradOutput); diff > 1e-6 {
+		t.Errorf("Expected NoGrad to give the same output, got max difference %v\n", diff)
+	}
+
+	// Gradient tracking is restored even if the closure panics.
+	func() {
+		defer func() { recover() }()
+		ts.NoGrad(func() { panic("inference error") })
+	}()
+	if output, _ := lstm.Seq(input); !output.MustRequiresGrad() {
+		t.Errorf("Expected gradient tracking to be restored after a panic\n")
+	}
+}
@@ -0,0 +1,42 @@
+package nn
+
+// A generic scan over the time axis for custom recurrences.
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// Scan applies stepFn to each timestep of input in order, threading the state
+// from one step to the next, and stacks the step outputs along the time axis.
+// It returns the stacked outputs and the final state. It mirrors JAX's
+// `lax.scan`.
+//
+// The time axis is the leading dimension of input, i.e. input has shape
+// [seq_len, ...] and stepFn receives slices of shape [...]. Batch-first
+// sequences should be transposed first. stepFn should not delete its input
+// slice nor the state it receives: the caller owns the initial state and each
+// intermediate state is kept as is.
+func Scan(init State, input *ts.Tensor, stepFn func(inputSlice *ts.Tensor, state State) (*ts.Tensor, State)) (*ts.Tensor, State) {
+	steps := input.MustUnbind(0, false)
+	if len(steps) == 0 {
+		log.Fatalf("Scan - Expected a non-empty time axis, got input shape %v\n", input.MustSize())
+	}
+
+	state := init
+	outputs := make([]ts.Tensor, len(steps))
+	for t := range steps {
+		var output *ts.Tensor
+		output, state = stepFn(&steps[t], state)
+		outputs[t] = *output
+		steps[t].MustDrop()
+	}
+
+	retVal := ts.MustStack(outputs, 0)
+	for _, o := range outputs {
+		o.MustDrop()
+	}
+
+	return retVal, state
+}
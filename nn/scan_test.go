@@ -0,0 +1,31 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestScan(t *testing.T) {
+	// 4 timesteps, batch of 2: x[t] = [t+1, 10(t+1)].
+	input := ts.MustOfSlice([]float64{1, 10, 2, 20, 3, 30, 4, 40}).MustView([]int64{4, 2}, true)
+	init := ts.MustOfSlice([]float64{0, 0})
+
+	// Cumulative sum: s[t] = s[t-1] + x[t], output y[t] = s[t].
+	output, state := nn.Scan(init, input, func(x *ts.Tensor, state nn.State) (*ts.Tensor, nn.State) {
+		s := state.(*ts.Tensor).MustAdd(x, false)
+		return s.MustShallowClone(), s
+	})
+
+	if want, got := []int64{4, 2}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("Expected output shape %v, got %v\n", want, got)
+	}
+	if want, got := []float64{1, 10, 3, 30, 6, 60, 10, 100}, output.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output %v, got %v\n", want, got)
+	}
+	if want, got := []float64{10, 100}, state.(*ts.Tensor).Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected final state %v, got %v\n", want, got)
+	}
+}
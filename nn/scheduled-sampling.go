@@ -0,0 +1,64 @@
+package nn
+
+// Scheduled sampling for training sequence models.
+
+import (
+	"log"
+	"math"
+)
+
+// ScheduledSampling decays the teacher forcing ratio over training steps.
+//
+// Ref. Bengio et al., "Scheduled Sampling for Sequence Prediction with
+// Recurrent Neural Networks", 2015. https://arxiv.org/abs/1506.03099
+type ScheduledSampling struct {
+	mode string
+	k    float64
+}
+
+// NewScheduledSampling creates a scheduled sampling curriculum.
+//
+// mode is one of:
+// - "linear": ratio = max(0, 1 - k * step), k > 0 is the decay slope.
+// - "exponential": ratio = k^step, 0 < k < 1.
+// - "inverse-sigmoid": ratio = k / (k + exp(step / k)), k >= 1.
+func NewScheduledSampling(mode string, k float64) *ScheduledSampling {
+	switch mode {
+	case "linear":
+		if k <= 0 {
+			log.Fatalf("ScheduledSampling - Expected k > 0 for linear mode, got %v\n", k)
+		}
+	case "exponential":
+		if k <= 0 || k >= 1 {
+			log.Fatalf("ScheduledSampling - Expected 0 < k < 1 for exponential mode, got %v\n", k)
+		}
+	case "inverse-sigmoid":
+		if k < 1 {
+			log.Fatalf("ScheduledSampling - Expected k >= 1 for inverse-sigmoid mode, got %v\n", k)
+		}
+	default:
+		log.Fatalf("ScheduledSampling - Unsupported mode: %q\n", mode)
+	}
+
+	return &ScheduledSampling{
+		mode: mode,
+		k:    k,
+	}
+}
+
+// Ratio returns the probability of feeding the ground truth (teacher forcing)
+// rather than the model prediction at the given training step.
+func (ss *ScheduledSampling) Ratio(step int64) float64 {
+	i := float64(step)
+
+	switch ss.mode {
+	case "linear":
+		return math.Max(0, 1-ss.k*i)
+	case "exponential":
+		return math.Pow(ss.k, i)
+	case "inverse-sigmoid":
+		return ss.k / (ss.k + math.Exp(i/ss.k))
+	}
+
+	return 0
+}
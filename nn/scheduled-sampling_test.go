@@ -0,0 +1,35 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch/nn"
+)
+
+func TestScheduledSampling(t *testing.T) {
+	schedules := map[string]*nn.ScheduledSampling{
+		"linear":          nn.NewScheduledSampling("linear", 1e-3),
+		"exponential":     nn.NewScheduledSampling("exponential", 0.999),
+		"inverse-sigmoid": nn.NewScheduledSampling("inverse-sigmoid", 100),
+	}
+
+	for mode, ss := range schedules {
+		first := ss.Ratio(0)
+		if first < 0.99 {
+			t.Errorf("%v - Expected initial ratio near 1.0, got %v\n", mode, first)
+		}
+
+		prev := first
+		for step := int64(100); step <= 10000; step += 100 {
+			curr := ss.Ratio(step)
+			if curr > prev {
+				t.Errorf("%v - Expected ratio to decay, got %v at step %v after %v\n", mode, curr, step, prev)
+			}
+			prev = curr
+		}
+
+		if prev > 0.01 {
+			t.Errorf("%v - Expected ratio to decay toward 0, got %v\n", mode, prev)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package nn
+
+// Softmax variants for large vocabularies.
+
+import (
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// ChunkedLogSoftmax computes log-softmax of logits along dim, processing the
+// vocabulary in chunks of chunkSize to bound peak memory.
+//
+// The log-sum-exp of each chunk is computed separately and then combined,
+// which gives the same result as `logits.MustLogSoftmax(dim, ...)`.
+func ChunkedLogSoftmax(logits *ts.Tensor, chunkSize int64, dim int64) *ts.Tensor {
+	if chunkSize <= 0 {
+		log.Fatalf("ChunkedLogSoftmax - Expected a positive chunk size, got %v\n", chunkSize)
+	}
+
+	size := logits.MustSize()
+	if dim < 0 {
+		dim += int64(len(size))
+	}
+	vocabSize := size[dim]
+
+	var lses []ts.Tensor
+	for start := int64(0); start < vocabSize; start += chunkSize {
+		length := chunkSize
+		if start+length > vocabSize {
+			length = vocabSize - start
+		}
+
+		chunk := logits.MustNarrow(dim, start, length, false)
+		lses = append(lses, *chunk.MustLogsumexp([]int64{dim}, true, true))
+	}
+
+	chunkLses := ts.MustCat(lses, dim)
+	for _, l := range lses {
+		l.MustDrop()
+	}
+	lse := chunkLses.MustLogsumexp([]int64{dim}, true, true)
+
+	retVal := logits.MustSub(lse, false)
+	lse.MustDrop()
+
+	return retVal
+}
+
+// SampledSoftmaxLoss computes an approximation of the softmax cross-entropy
+// loss over a large vocabulary using numSampled negative classes.
+//
+// hidden has shape [batch_size, dim], weights [vocab_size, dim], bias
+// [vocab_size] and targets [batch_size] (int64 class ids). Negative classes
+// are sampled from a log-uniform (Zipfian) distribution, which suits
+// vocabularies sorted by decreasing frequency. As in TensorFlow
+// `sampled_softmax_loss`, logits are corrected by the log expected count of
+// each class and sampled classes equal to the target ("accidental hits") are
+// removed. It returns the mean loss over the batch.
+//
+// NOTE. It should only be used for training. Use the full softmax for
+// evaluation.
+func SampledSoftmaxLoss(hidden, weights, bias, targets *ts.Tensor, numSampled int64) *ts.Tensor {
+	vocabSize := weights.MustSize()[0]
+	if numSampled <= 0 {
+		log.Fatalf("SampledSoftmaxLoss - Expected a positive number of samples, got %v\n", numSampled)
+	}
+
+	sampled := logUniformSample(numSampled, vocabSize, hidden.MustDevice())
+	defer sampled.MustDrop()
+
+	// True logits: [batch_size, 1]
+	trueW := weights.MustIndexSelect(0, targets, false)
+	trueB := bias.MustIndexSelect(0, targets, false)
+	trueLogits := hidden.MustMul(trueW, false).MustSum1([]int64{1}, false, hidden.DType(), true).MustAdd(trueB, true)
+	trueW.MustDrop()
+	trueB.MustDrop()
+	trueLogQ := logUniformExpectedCount(targets, numSampled, vocabSize)
+	trueLogits = trueLogits.MustSub(trueLogQ, true).MustUnsqueeze(1, true)
+	trueLogQ.MustDrop()
+
+	// Sampled logits: [batch_size, num_sampled]
+	sampledW := weights.MustIndexSelect(0, sampled, false)
+	sampledB := bias.MustIndexSelect(0, sampled, false)
+	sampledLogits := ts.MustLinear(hidden, sampledW, sampledB)
+	sampledW.MustDrop()
+	sampledB.MustDrop()
+	sampledLogQ := logUniformExpectedCount(sampled, numSampled, vocabSize)
+	sampledLogits = sampledLogits.MustSub(sampledLogQ, true)
+	sampledLogQ.MustDrop()
+
+	// Remove accidental hits.
+	targetIds := targets.MustUnsqueeze(1, false)
+	hits := targetIds.MustEq1(sampled, true)
+	sampledLogits = sampledLogits.MustMaskedFill(hits, ts.FloatScalar(-1e9), true)
+	hits.MustDrop()
+
+	// The true class is at index 0.
+	logits := ts.MustCat([]ts.Tensor{*trueLogits, *sampledLogits}, 1)
+	trueLogits.MustDrop()
+	sampledLogits.MustDrop()
+
+	logProbs := logits.MustLogSoftmax(1, hidden.DType(), true)
+	nll := logProbs.MustSelect(1, 0, true).MustNeg(true)
+
+	return nll.MustMean(hidden.DType(), true)
+}
+
+// logUniformSample samples numSampled class ids in [0, rangeMax) with
+// P(k) = (log(k + 2) - log(k + 1)) / log(rangeMax + 1).
+func logUniformSample(numSampled, rangeMax int64, device gotch.Device) *ts.Tensor {
+	u := ts.MustRand([]int64{numSampled}, gotch.Double, device)
+	ids := u.MustMul1(ts.FloatScalar(math.Log(float64(rangeMax)+1)), true).MustExp(true).MustFloor(true)
+	ids = ids.MustSub1(ts.FloatScalar(1.0), true).MustClamp(ts.FloatScalar(0), ts.FloatScalar(float64(rangeMax-1)), true)
+
+	return ids.MustTotype(gotch.Int64, true)
+}
+
+// logUniformExpectedCount returns log(numSampled * P(ids)) for the
+// log-uniform distribution of `logUniformSample`.
+func logUniformExpectedCount(ids *ts.Tensor, numSampled, rangeMax int64) *ts.Tensor {
+	// log(k + 2) - log(k + 1) = log1p(1 / (k + 1))
+	k := ids.MustTotype(gotch.Float, false)
+	p := k.MustAdd1(ts.FloatScalar(1.0), true).MustReciprocal(true).MustLog1p(true)
+	p = p.MustMul1(ts.FloatScalar(float64(numSampled)/math.Log(float64(rangeMax)+1)), true)
+
+	return p.MustLog(true)
+}
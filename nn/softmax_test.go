@@ -0,0 +1,59 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestChunkedLogSoftmax(t *testing.T) {
+	logits := ts.MustRandn([]int64{4, 100000}, gotch.Float, gotch.CPU).MustMul1(ts.FloatScalar(10.0), true)
+
+	want := logits.MustLogSoftmax(1, gotch.Float, false)
+	got := nn.ChunkedLogSoftmax(logits, 7919, 1)
+	if diff := maxAbsDiff(want, got); diff > 1e-4 {
+		t.Errorf("Expected chunked log-softmax to match full one, got max difference %v\n", diff)
+	}
+
+	// Along the first dimension.
+	logitsT := logits.MustT(false)
+	want = logitsT.MustLogSoftmax(0, gotch.Float, false)
+	got = nn.ChunkedLogSoftmax(logitsT, 30000, 0)
+	if diff := maxAbsDiff(want, got); diff > 1e-4 {
+		t.Errorf("Expected chunked log-softmax along dim 0 to match full one, got max difference %v\n", diff)
+	}
+}
+
+func TestSampledSoftmaxLoss(t *testing.T) {
+	var (
+		batchDim   int64 = 4
+		hiddenDim  int64 = 16
+		vocabSize  int64 = 1000
+		numSampled int64 = 20
+	)
+
+	hidden := ts.MustRandn([]int64{batchDim, hiddenDim}, gotch.Float, gotch.CPU)
+	weights := ts.MustRandn([]int64{vocabSize, hiddenDim}, gotch.Float, gotch.CPU)
+	bias := ts.MustZeros([]int64{vocabSize}, gotch.Float, gotch.CPU)
+	targets := ts.MustOfSlice([]int64{3, 42, 500, 999})
+
+	ts.ManualSeed(42)
+	loss := nn.SampledSoftmaxLoss(hidden, weights, bias, targets, numSampled)
+	if got := loss.MustSize(); len(got) != 0 {
+		t.Errorf("Expected a scalar loss, got shape %v\n", got)
+	}
+
+	// Raise the target logits.
+	raised := bias.MustIndexFill(0, targets, ts.FloatScalar(10.0), false)
+
+	ts.ManualSeed(42)
+	raisedLoss := nn.SampledSoftmaxLoss(hidden, weights, raised, targets, numSampled)
+
+	before := loss.Float64Values()[0]
+	after := raisedLoss.Float64Values()[0]
+	if after >= before {
+		t.Errorf("Expected loss to decrease when raising target logits, got %v -> %v\n", before, after)
+	}
+}
@@ -3,6 +3,9 @@ package nn
 // Sparse layers
 
 import (
+	"fmt"
+	"reflect"
+
 	ts "github.com/sugarme/gotch/tensor"
 )
 
@@ -40,6 +43,43 @@ func NewEmbedding(vs *Path, numEmbeddings int64, embeddingDim int64, config *Emb
 	}
 }
 
+// LoadPretrained copies a pretrained matrix (e.g. GloVe vectors) into the
+// embedding weight.
+//
+// The matrix should have shape [numEmbeddings, embeddingDim]. If freeze is
+// true, the weight does not require gradients anymore so it will not be
+// updated by optimizers.
+func (e *Embedding) LoadPretrained(matrix *ts.Tensor, freeze bool) error {
+	want := e.Ws.MustSize()
+	got := matrix.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		err := fmt.Errorf("Embedding - LoadPretrained method call error: expected matrix of shape %v, got %v\n", want, got)
+		return err
+	}
+
+	ts.NoGrad(func() {
+		e.Ws.Copy_(matrix)
+	})
+
+	if freeze {
+		e.Freeze()
+	}
+
+	return nil
+}
+
+// Freeze stops tracking gradients of the embedding weight so that optimizers
+// no longer update it, e.g. to train a RNN on top of fixed embeddings.
+func (e *Embedding) Freeze() {
+	e.Ws.MustRequiresGrad_(false)
+}
+
+// Unfreeze tracks gradients of the embedding weight again. The row at
+// `PaddingIdx`, if any, still receives no gradient.
+func (e *Embedding) Unfreeze() {
+	e.Ws.MustRequiresGrad_(true)
+}
+
 // Implement Module, ModuleT interfaces for Embedding:
 // =========================================
 
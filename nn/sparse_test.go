@@ -62,3 +62,103 @@ func TestEmbedding(t *testing.T) {
 	cfg.PaddingIdx = 0
 	embeddingTest(cfg, t)
 }
+
+func TestEmbeddingLoadPretrained(t *testing.T) {
+	var (
+		numEmbeddings int64 = 5
+		embeddingDim  int64 = 3
+	)
+
+	data := make([]float32, numEmbeddings*embeddingDim)
+	for i := range data {
+		data[i] = float32(i)
+	}
+	matrix, err := ts.NewTensorFromData(data, []int64{numEmbeddings, embeddingDim})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs := nn.NewVarStore(gotch.CPU)
+	embeddings := nn.NewEmbedding(vs.Root().Sub("embedding"), numEmbeddings, embeddingDim, nn.DefaultEmbeddingConfig())
+	linear := nn.NewLinear(vs.Root().Sub("linear"), embeddingDim, 1, nn.DefaultLinearConfig())
+
+	wrongMatrix := ts.MustZeros([]int64{numEmbeddings + 1, embeddingDim}, gotch.Float, gotch.CPU)
+	if err := embeddings.LoadPretrained(wrongMatrix, false); err == nil {
+		t.Errorf("Expected error for mismatched matrix shape\n")
+	}
+
+	if err := embeddings.LoadPretrained(matrix, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lookups match the matrix rows.
+	input := ts.MustOfSlice([]int64{4, 0})
+	output := embeddings.Forward(input)
+	want := []float64{12, 13, 14, 0, 1, 2}
+	got := output.Float64Values()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected lookup values: %v\n", want)
+		t.Errorf("Got lookup values: %v\n", got)
+	}
+
+	// Frozen embedding is not updated by an optimizer step.
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loss := embeddings.Forward(input).Apply(linear).MustSum(gotch.Float, true)
+	opt.BackwardStep(loss)
+
+	if diff := maxAbsDiff(embeddings.Ws, matrix); diff != 0 {
+		t.Errorf("Expected frozen embedding to be unchanged, got max difference %v\n", diff)
+	}
+}
+
+func TestEmbeddingFreeze(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultEmbeddingConfig()
+	cfg.PaddingIdx = 0
+	embeddings := nn.NewEmbedding(vs.Root().Sub("embedding"), 6, 3, cfg)
+	lstm := nn.NewLSTM(vs.Root().Sub("lstm"), 3, 4, nn.DefaultRNNConfig())
+	embeddings.Freeze()
+
+	opt, err := nn.DefaultSGDConfig().Build(vs, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := make(map[string][]float64)
+	for name, v := range vs.Vars.NamedVariables {
+		before[name] = v.Float64Values()
+	}
+
+	input := ts.MustOfSlice([]int64{1, 2, 3, 0, 4, 5, 0, 0}).MustView([]int64{2, 4}, true)
+	step := func() {
+		output, _ := lstm.Seq(embeddings.Forward(input))
+		opt.BackwardStep(output.MustSum(gotch.Float, true))
+	}
+	step()
+
+	for name, v := range vs.Vars.NamedVariables {
+		changed := !reflect.DeepEqual(before[name], v.Float64Values())
+		switch {
+		case name == "embedding.weight" && changed:
+			t.Errorf("Expected frozen embedding weight to stay fixed\n")
+		case name != "embedding.weight" && !changed:
+			t.Errorf("%v - Expected RNN variable to change\n", name)
+		}
+	}
+
+	// Once unfrozen, all rows but the padding one are updated.
+	embeddings.Unfreeze()
+	step()
+	after := embeddings.Ws.Float64Values()
+	want := before["embedding.weight"]
+	if !reflect.DeepEqual(want[:3], after[:3]) {
+		t.Errorf("Expected padding row %v to stay fixed, got %v\n", want[:3], after[:3])
+	}
+	if reflect.DeepEqual(want[3:], after[3:]) {
+		t.Errorf("Expected unfrozen embedding weight to change\n")
+	}
+}
@@ -0,0 +1,226 @@
+package nn
+
+// A Simple Recurrent Unit (SRU) layer.
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// sruLayer holds the parameters of a SRU layer.
+type sruLayer struct {
+	proj   *Linear    // input -> [x~, forget, reset(, highway)] pre-activations
+	vF, vR *ts.Tensor // element-wise recurrent weights of the forget and reset gates
+	bF, bR *ts.Tensor // forget and reset gate biases
+}
+
+// SRU is a Simple Recurrent Unit layer:
+//
+//	x~_t = W x_t
+//	f_t = sigmoid(W_f x_t + v_f * c_{t-1} + b_f)
+//	c_t = f_t * c_{t-1} + (1 - f_t) * x~_t
+//	r_t = sigmoid(W_r x_t + v_r * c_{t-1} + b_r)
+//	h_t = r_t * c_t + (1 - r_t) * x_t
+//
+// All matrix multiplications only depend on the input and are computed for
+// all timesteps at once, the recurrence being element-wise. The last term is
+// a highway connection: if the input and hidden dimensions differ, x_t is
+// projected with an extra W_h matrix.
+//
+// It uses `GRUState` states holding c. `Bidirectional` is not supported.
+//
+// Ref. Lei et al., "Simple Recurrent Units for Highly Parallelizable
+// Recurrence", 2018. https://arxiv.org/abs/1709.02755
+type SRU struct {
+	layers    []sruLayer
+	hiddenDim int64
+	config    *RNNConfig
+	device    gotch.Device
+}
+
+// NewSRU creates a SRU layer. The parameters of layer i are stored under
+// sub-path "i".
+func NewSRU(vs *Path, inDim, hiddenDim int64, cfg *RNNConfig) *SRU {
+	if cfg.Bidirectional {
+		log.Fatalf("NewSRU - Bidirectional is not supported\n")
+	}
+
+	linearCfg := DefaultLinearConfig()
+	linearCfg.Bias = false
+	bound := 1.0 / math.Sqrt(float64(hiddenDim))
+
+	layers := make([]sruLayer, cfg.NumLayers)
+	for i := range layers {
+		layerInDim := inDim
+		if i > 0 {
+			layerInDim = hiddenDim
+		}
+		numProj := int64(3)
+		if layerInDim != hiddenDim {
+			numProj = 4
+		}
+
+		lvs := vs.Sub(fmt.Sprint(i))
+		layers[i] = sruLayer{
+			proj: NewLinear(lvs.Sub("proj"), layerInDim, numProj*hiddenDim, linearCfg),
+			vF:   lvs.NewVar("v_f", []int64{hiddenDim}, NewUniformInit(-bound, bound)),
+			vR:   lvs.NewVar("v_r", []int64{hiddenDim}, NewUniformInit(-bound, bound)),
+			bF:   lvs.Zeros("b_f", []int64{hiddenDim}),
+			bR:   lvs.Zeros("b_r", []int64{hiddenDim}),
+		}
+	}
+
+	return &SRU{
+		layers:    layers,
+		hiddenDim: hiddenDim,
+		config:    cfg,
+		device:    vs.Device(),
+	}
+}
+
+// forward runs layer l over xs of shape [batch_size, seq_len, features] from
+// cell state c0. It returns the output sequence and the final cell state.
+func (l *sruLayer) forward(xs, c0 *ts.Tensor) (*ts.Tensor, *ts.Tensor) {
+	proj := l.proj.Forward(xs)
+	numProj := proj.MustSize()[2] / c0.MustSize()[1]
+	chunks := proj.MustChunk(numProj, 2, true)
+
+	// Highway input, projected if dimensions differ.
+	var highway *ts.Tensor
+	if numProj == 4 {
+		highway = &chunks[3]
+	} else {
+		highway = xs.MustShallowClone()
+	}
+
+	candidates := chunks[0].MustUnbind(1, true)
+	forgets := chunks[1].MustAdd(l.bF, true).MustUnbind(1, true)
+	resets := chunks[2].MustAdd(l.bR, true).MustUnbind(1, true)
+	highways := highway.MustUnbind(1, true)
+
+	c := c0.MustShallowClone()
+	outputs := make([]ts.Tensor, len(candidates))
+	for t := range candidates {
+		fc := l.vF.MustMul(c, false)
+		f := forgets[t].MustAdd(fc, true).MustSigmoid(true)
+		fc.MustDrop()
+		rc := l.vR.MustMul(c, false)
+		r := resets[t].MustAdd(rc, true).MustSigmoid(true)
+		rc.MustDrop()
+
+		// c = c~ + f * (c - c~), with c~ = x~
+		diff := c.MustSub(&candidates[t], true).MustMul(f, true)
+		f.MustDrop()
+		c = candidates[t].MustAdd(diff, false)
+		diff.MustDrop()
+
+		// h = x + r * (c - x)
+		diff = c.MustSub(&highways[t], false).MustMul(r, true)
+		r.MustDrop()
+		outputs[t] = *highways[t].MustAdd(diff, false)
+		diff.MustDrop()
+
+		candidates[t].MustDrop()
+		highways[t].MustDrop()
+	}
+
+	retVal := ts.MustStack(outputs, 1)
+	for _, o := range outputs {
+		o.MustDrop()
+	}
+
+	return retVal, c
+}
+
+// layerInfo implements rnnLayer.
+func (s *SRU) layerInfo() (int64, *RNNConfig, gotch.Device) {
+	// Linear weights are stored transposed: [in_dim, out_dim]
+	return s.layers[0].proj.Ws.MustSize()[0], s.config, s.device
+}
+
+// Implement RNN interface for SRU:
+// ================================
+
+func (s *SRU) ZeroState(batchDim int64) State {
+	return &GRUState{Tensor: ts.MustZeros([]int64{s.config.NumLayers, batchDim, s.hiddenDim}, s.config.dtype(), s.device)}
+}
+
+// ZeroStateLike creates a zero state with the batch size, dtype and device of
+// input. input is either a sequence (see `SeqInit`) or a step input of shape
+// [batch_size, features].
+func (s *SRU) ZeroStateLike(input *ts.Tensor) State {
+	shape := []int64{s.config.NumLayers, s.config.batchDim(input), s.hiddenDim}
+	return &GRUState{Tensor: ts.MustZeros(shape, input.DType(), input.MustDevice())}
+}
+
+func (s *SRU) Step(input *ts.Tensor, inState State) State {
+	unsqueezedInput := input.MustUnsqueeze(1, false)
+	if !s.config.BatchFirst {
+		unsqueezedInput = unsqueezedInput.MustTranspose(0, 1, true)
+	}
+	output, state := s.SeqInit(unsqueezedInput, inState)
+	output.MustDrop()
+	unsqueezedInput.MustDrop()
+
+	return state
+}
+
+func (s *SRU) Seq(input *ts.Tensor) (*ts.Tensor, State) {
+	inState := s.ZeroStateLike(input)
+
+	output, state := s.SeqInit(input, inState)
+
+	// Delete intermediate tensors in inState
+	inState.(*GRUState).Tensor.MustDrop()
+
+	return output, state
+}
+
+func (s *SRU) SeqInit(input *ts.Tensor, inState State) (*ts.Tensor, State) {
+	input = s.config.addInputNoise(input)
+	input = s.config.flipTime(input, true)
+	defer input.MustDrop()
+
+	// Work on [batch_size, seq_len, features] layout.
+	var xs *ts.Tensor
+	if s.config.BatchFirst {
+		xs = input.MustShallowClone()
+	} else {
+		xs = input.MustTranspose(0, 1, false)
+	}
+	c0 := inState.(*GRUState).Tensor
+
+	cs := make([]ts.Tensor, len(s.layers))
+	for i := range s.layers {
+		layerC0 := c0.MustSelect(0, int64(i), false)
+		output, c := s.layers[i].forward(xs, layerC0)
+		layerC0.MustDrop()
+		xs.MustDrop()
+
+		cs[i] = *c
+		xs = output
+		if i < len(s.layers)-1 {
+			xs = s.config.dropout(xs)
+		}
+	}
+
+	output := xs
+	if !s.config.BatchFirst {
+		output = xs.MustTranspose(0, 1, true)
+	}
+	output = s.config.flipTime(output, true)
+	output = s.config.activateOutput(output)
+	output = s.config.castOutput(output)
+
+	c := ts.MustStack(cs, 0)
+	for _, t := range cs {
+		t.MustDrop()
+	}
+	s.config.checkFinite("SRU", []string{"output", "cell state"}, output, c)
+
+	return output, &GRUState{Tensor: c}
+}
@@ -0,0 +1,87 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestSRU(t *testing.T) {
+	var (
+		batchDim  int64 = 3
+		seqLen    int64 = 7
+		hiddenDim int64 = 4
+	)
+
+	// Stacked layers with a projected highway (input dim 2 != hidden dim 4).
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	sru := nn.NewSRU(vs.Root(), 2, hiddenDim, cfg)
+
+	output, state := sru.Seq(ts.MustRandn([]int64{batchDim, seqLen, 2}, gotch.Float, gotch.CPU))
+	if want, got := []int64{batchDim, seqLen, hiddenDim}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape %v, got %v\n", want, got)
+	}
+	if want, got := []int64{2, batchDim, hiddenDim}, state.(*nn.GRUState).Tensor.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected state shape %v, got %v\n", want, got)
+	}
+
+	// With the reset gate closed (r = 0), the highway connection passes the
+	// input through unchanged. With the reset gate open (r = 1), it does not.
+	vs = nn.NewVarStore(gotch.CPU)
+	sru = nn.NewSRU(vs.Root(), hiddenDim, hiddenDim, nn.DefaultRNNConfig())
+	bR, err := vs.Root().Get("0.b_r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := ts.MustRandn([]int64{batchDim, seqLen, hiddenDim}, gotch.Float, gotch.CPU)
+
+	ts.NoGrad(func() { bR.MustFill_(ts.FloatScalar(-30)) })
+	closed, _ := sru.Seq(input)
+	if diff := maxAbsDiff(closed, input); diff > 1e-5 {
+		t.Errorf("Expected closed reset gate to output the input, got max difference %v\n", diff)
+	}
+
+	ts.NoGrad(func() { bR.MustFill_(ts.FloatScalar(30)) })
+	open, _ := sru.Seq(input)
+	if diff := maxAbsDiff(open, input); diff < 1e-2 {
+		t.Errorf("Expected open reset gate to transform the input, got max difference %v\n", diff)
+	}
+}
+
+func benchmarkLongSequence(b *testing.B, newRNN func(vs *nn.Path) nn.RNN) {
+	vs := nn.NewVarStore(gotch.CPU)
+	rnn := newRNN(vs.Root())
+	input := ts.MustRandn([]int64{8, 500, 64}, gotch.Float, gotch.CPU)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts.NoGrad(func() {
+			output, state := rnn.Seq(input)
+			output.MustDrop()
+			switch s := state.(type) {
+			case *nn.LSTMState:
+				s.Tensor1.MustDrop()
+				s.Tensor2.MustDrop()
+			case *nn.GRUState:
+				s.Tensor.MustDrop()
+			}
+		})
+	}
+}
+
+func BenchmarkSRULongSequence(b *testing.B) {
+	benchmarkLongSequence(b, func(vs *nn.Path) nn.RNN {
+		return nn.NewSRU(vs, 64, 64, nn.DefaultRNNConfig())
+	})
+}
+
+func BenchmarkLSTMLongSequence(b *testing.B) {
+	benchmarkLongSequence(b, func(vs *nn.Path) nn.RNN {
+		return nn.NewLSTM(vs, 64, 64, nn.DefaultRNNConfig())
+	})
+}
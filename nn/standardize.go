@@ -0,0 +1,62 @@
+package nn
+
+// Input feature standardization.
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// FeatureStandardizer standardizes input features to zero mean and unit
+// variance using statistics computed over a training dataset.
+//
+// Mean and Std are registered as non-trainable variables "mean" and "std" so
+// that they are saved and loaded with the var-store.
+type FeatureStandardizer struct {
+	Mean *ts.Tensor
+	Std  *ts.Tensor
+
+	// Eps is the lower bound of Std, so that constant features do not cause
+	// a division by zero.
+	Eps float64
+}
+
+// NewFeatureStandardizer creates a FeatureStandardizer for numFeatures
+// features. It is the identity transform until `Fit` is called.
+func NewFeatureStandardizer(vs *Path, numFeatures int64) *FeatureStandardizer {
+	return &FeatureStandardizer{
+		Mean: vs.ZerosNoTrain("mean", []int64{numFeatures}),
+		Std:  vs.OnesNoTrain("std", []int64{numFeatures}),
+		Eps:  1e-8,
+	}
+}
+
+// Fit computes the per-feature mean and standard deviation of dataset.
+//
+// dataset has features on its last axis, e.g. [num_samples, seq_len,
+// features]. Statistics are computed over all other axes.
+func (fs *FeatureStandardizer) Fit(dataset *ts.Tensor) {
+	numFeatures := fs.Mean.MustSize()[0]
+	size := dataset.MustSize()
+	if size[len(size)-1] != numFeatures {
+		log.Fatalf("FeatureStandardizer Fit - Expected %v features on the last axis, got shape %v\n", numFeatures, size)
+	}
+
+	xs := dataset.MustReshape([]int64{-1, numFeatures}, false).MustTotype(fs.Mean.DType(), true)
+	ts.NoGrad(func() {
+		mean := xs.MustMean1([]int64{0}, false, fs.Mean.DType(), false)
+		std := xs.MustStd1([]int64{0}, false, false, false).MustClampMin(ts.FloatScalar(fs.Eps), true)
+		fs.Mean.Copy_(mean)
+		fs.Std.Copy_(std)
+		mean.MustDrop()
+		std.MustDrop()
+	})
+	xs.MustDrop()
+}
+
+// Transform returns (x - mean) / std. x has features on its last axis.
+func (fs *FeatureStandardizer) Transform(x *ts.Tensor) *ts.Tensor {
+	centered := x.MustSub(fs.Mean, false)
+	return centered.MustDiv(fs.Std, true)
+}
@@ -0,0 +1,56 @@
+package nn_test
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestFeatureStandardizer(t *testing.T) {
+	// 2 features with mean (5, -3) and std (2, 0.5), laid out as [samples, seq_len, features].
+	scale := ts.MustOfSlice([]float32{2, 0.5})
+	shift := ts.MustOfSlice([]float32{5, -3})
+	data := ts.MustRandn([]int64{200, 10, 2}, gotch.Float, gotch.CPU).MustMul(scale, true).MustAdd(shift, true)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	fs := nn.NewFeatureStandardizer(vs.Root(), 2)
+	fs.Fit(data)
+
+	out := fs.Transform(data).MustView([]int64{-1, 2}, true)
+	mean := out.MustMean1([]int64{0}, false, gotch.Double, false).Float64Values()
+	std := out.MustStd1([]int64{0}, false, false, false).Float64Values()
+	for i := range mean {
+		if math.Abs(mean[i]) > 1e-4 || math.Abs(std[i]-1) > 1e-4 {
+			t.Errorf("Feature %v - Expected zero mean and unit std, got mean %v and std %v\n", i, mean[i], std[i])
+		}
+	}
+
+	// Stats are saved and loaded with the var-store.
+	dir, err := ioutil.TempDir("", "gotch-standardizer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.gt")
+	if err := vs.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	vs2 := nn.NewVarStore(gotch.CPU)
+	fs2 := nn.NewFeatureStandardizer(vs2.Root(), 2)
+	if err := vs2.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := fs.Mean.Float64Values(), fs2.Mean.Float64Values(); math.Abs(want[0]-got[0]) > 1e-6 || math.Abs(want[1]-got[1]) > 1e-6 {
+		t.Errorf("Expected loaded mean %v, got %v\n", want, got)
+	}
+	if want, got := fs.Std.Float64Values(), fs2.Std.Float64Values(); math.Abs(want[0]-got[0]) > 1e-6 || math.Abs(want[1]-got[1]) > 1e-6 {
+		t.Errorf("Expected loaded std %v, got %v\n", want, got)
+	}
+}
@@ -0,0 +1,90 @@
+package nn
+
+// Stochastic weight averaging.
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// SWA maintains a running average of the floating point variables of a var
+// store, typically over the last epochs of training.
+//
+// Ref. Izmailov et al., "Averaging Weights Leads to Wider Optima and Better
+// Generalization", 2018. https://arxiv.org/abs/1803.05407
+type SWA struct {
+	vs       *VarStore
+	averages map[string]*ts.Tensor
+	n        int64
+}
+
+// NewSWA creates a SWA helper for the given var store. No weights are averaged
+// until the first call to `Update`.
+func NewSWA(vs *VarStore) *SWA {
+	return &SWA{
+		vs:       vs,
+		averages: make(map[string]*ts.Tensor),
+	}
+}
+
+// NumUpdates returns the number of weight snapshots averaged so far.
+func (s *SWA) NumUpdates() int64 {
+	return s.n
+}
+
+// Update adds the current weights to the running average:
+// avg = avg + (w - avg) / (n + 1).
+func (s *SWA) Update() {
+	s.vs.Vars.mutex.Lock()
+	defer s.vs.Vars.mutex.Unlock()
+
+	ts.NoGrad(func() {
+		for name, v := range s.vs.Vars.NamedVariables {
+			if kind := v.DType(); kind != gotch.Float && kind != gotch.Double {
+				continue
+			}
+
+			avg, ok := s.averages[name]
+			if !ok {
+				if s.n > 0 {
+					log.Fatalf("SWA - Variable %q was added after the first update\n", name)
+				}
+				avg = v.MustZerosLike(false)
+				avg.Copy_(v)
+				s.averages[name] = avg
+				continue
+			}
+
+			delta := v.MustSub(avg, false).MustDiv1(ts.FloatScalar(float64(s.n+1)), true)
+			avg.MustAdd_(delta)
+			delta.MustDrop()
+		}
+	})
+
+	s.n++
+}
+
+// Apply copies the averaged weights into the var store variables.
+//
+// NOTE. Statistics computed from the weights, e.g. `BatchNorm` running
+// statistics, should be recomputed afterward.
+func (s *SWA) Apply() {
+	if s.n == 0 {
+		log.Fatalf("SWA - Apply called before any update\n")
+	}
+
+	s.vs.Vars.mutex.Lock()
+	defer s.vs.Vars.mutex.Unlock()
+
+	ts.NoGrad(func() {
+		for name, avg := range s.averages {
+			v, ok := s.vs.Vars.NamedVariables[name]
+			if !ok {
+				log.Fatalf("SWA - Cannot find variable %q in the var store\n", name)
+			}
+			v.Copy_(avg)
+		}
+	})
+}
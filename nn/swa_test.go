@@ -0,0 +1,43 @@
+package nn_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestSWA(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	linear := nn.NewLinear(vs.Root(), 3, 2, nn.DefaultLinearConfig())
+	swa := nn.NewSWA(vs)
+
+	snapshots := [][]float64{}
+	for i := 0; i < 4; i++ {
+		ts.NoGrad(func() {
+			nn.NewKaimingUniformInit().Set(linear.Ws)
+		})
+		snapshots = append(snapshots, linear.Ws.Float64Values())
+		swa.Update()
+	}
+	if got := swa.NumUpdates(); got != 4 {
+		t.Errorf("Expected 4 updates, got %v\n", got)
+	}
+
+	swa.Apply()
+
+	got := linear.Ws.Float64Values()
+	for j := range got {
+		var mean float64
+		for _, s := range snapshots {
+			mean += s[j]
+		}
+		mean /= float64(len(snapshots))
+
+		if math.Abs(got[j]-mean) > 1e-6 {
+			t.Errorf("Expected averaged weight %v to be %v, got %v\n", j, mean, got[j])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package nn
+
+// Truncated back-propagation through time (TBPTT).
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// detachState returns a copy of a LSTM, GRU or RNNSequential state detached
+// from the autograd graph and deletes state.
+func detachState(state State) State {
+	detach := func(x *ts.Tensor) *ts.Tensor {
+		return x.MustDetach(true)
+	}
+
+	switch st := state.(type) {
+	case *LSTMState:
+		return &LSTMState{Tensor1: detach(st.Tensor1), Tensor2: detach(st.Tensor2)}
+	case *GRUState:
+		return &GRUState{Tensor: detach(st.Tensor)}
+	case *RNNSequentialState:
+		states := make([]State, len(st.States))
+		for i, s := range st.States {
+			states[i] = detachState(s)
+		}
+		return &RNNSequentialState{States: states}
+	default:
+		log.Fatalf("Unsupported state type: %T\n", state)
+	}
+
+	return nil
+}
+
+// SeqChunked runs rnn over input split into chunks of chunkLen timesteps (the
+// last chunk may be shorter) and calls onChunk with the output of each chunk.
+// The state is detached from the autograd graph between chunks, so that
+// gradients do not flow across chunk boundaries. It returns the final state.
+//
+// input has shape [batch_size, seq_len, features] if batchFirst, [seq_len,
+// batch_size, features] otherwise. onChunk should not delete output.
+func SeqChunked(rnn RNN, input *ts.Tensor, inState State, chunkLen int64, batchFirst bool, onChunk func(chunk int, output *ts.Tensor)) State {
+	if chunkLen <= 0 {
+		log.Fatalf("SeqChunked - Expected a positive chunk length, got %v\n", chunkLen)
+	}
+
+	timeDim := int64(1)
+	if !batchFirst {
+		timeDim = 0
+	}
+
+	chunks := input.MustSplit(chunkLen, timeDim, false)
+	state := inState
+	for i := range chunks {
+		output, newState := rnn.SeqInit(&chunks[i], state)
+		onChunk(i, output)
+		output.MustDrop()
+		chunks[i].MustDrop()
+
+		if i > 0 {
+			dropState(state)
+		}
+		if i < len(chunks)-1 {
+			state = detachState(newState)
+		} else {
+			state = newState
+		}
+	}
+
+	return state
+}
+
+// ChunkedTrainer trains a RNN on long sequences with truncated
+// back-propagation through time: sequences are processed in chunks of
+// ChunkLen timesteps with the state detached between chunks, gradients are
+// accumulated over all chunks and the optimizer steps once per sequence.
+type ChunkedTrainer struct {
+	RNN        RNN
+	Optimizer  *Optimizer
+	ChunkLen   int64
+	BatchFirst bool
+}
+
+// NewChunkedTrainer creates a ChunkedTrainer for batch-first sequences.
+func NewChunkedTrainer(rnn RNN, opt *Optimizer, chunkLen int64) *ChunkedTrainer {
+	return &ChunkedTrainer{
+		RNN:        rnn,
+		Optimizer:  opt,
+		ChunkLen:   chunkLen,
+		BatchFirst: true,
+	}
+}
+
+// Step trains on a batch of full sequences and returns the loss.
+//
+// lossFn computes the mean loss of a chunk from the RNN output and target
+// chunks, e.g. applying an output layer first. target is split along the time
+// axis as input. Chunk losses are weighted by their share of the sequence
+// length, so that the returned loss and accumulated gradients are those of
+// the mean loss over the whole sequence, up to truncation.
+func (c *ChunkedTrainer) Step(input, target *ts.Tensor, lossFn func(output, target *ts.Tensor) *ts.Tensor) float64 {
+	timeDim := int64(1)
+	if !c.BatchFirst {
+		timeDim = 0
+	}
+	seqLen := input.MustSize()[timeDim]
+	targets := target.MustSplit(c.ChunkLen, timeDim, false)
+
+	var inState State
+	if rz, ok := c.RNN.(interface{ ZeroStateLike(*ts.Tensor) State }); ok {
+		inState = rz.ZeroStateLike(input)
+	} else {
+		inState = c.RNN.ZeroState(input.MustSize()[1-timeDim])
+	}
+
+	c.Optimizer.ZeroGrad()
+	var total float64
+	state := SeqChunked(c.RNN, input, inState, c.ChunkLen, c.BatchFirst, func(i int, output *ts.Tensor) {
+		weight := float64(output.MustSize()[timeDim]) / float64(seqLen)
+		loss := lossFn(output, &targets[i]).MustMul1(ts.FloatScalar(weight), true)
+		loss.MustBackward()
+		total += loss.Float64Values()[0]
+		loss.MustDrop()
+		targets[i].MustDrop()
+	})
+	c.Optimizer.Step()
+
+	dropState(inState)
+	dropState(state)
+
+	return total
+}
@@ -0,0 +1,62 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestChunkedTrainer(t *testing.T) {
+	var hiddenDim int64 = 4
+
+	// Two identical short-memory LSTMs (see TestEffectiveContext), for which no
+	// gradient flows across timesteps: truncating back-propagation at chunk
+	// boundaries does not change the gradients.
+	newModel := func() (*nn.VarStore, *nn.LSTM, *nn.Linear) {
+		vs := nn.NewVarStore(gotch.CPU)
+		lstm := newShortMemoryLSTM(vs, 2, hiddenDim)
+		linear := nn.NewLinear(vs.Root().Sub("linear"), hiddenDim, 1, nn.DefaultLinearConfig())
+		return vs, lstm, linear
+	}
+	vs1, lstm1, linear1 := newModel()
+	vs2, lstm2, linear2 := newModel()
+	if err := vs2.Copy(*vs1); err != nil {
+		t.Fatal(err)
+	}
+
+	input := ts.MustRandn([]int64{3, 10, 2}, gotch.Float, gotch.CPU)
+	target := ts.MustRandn([]int64{3, 10, 1}, gotch.Float, gotch.CPU)
+	lossFn := func(linear *nn.Linear) func(output, target *ts.Tensor) *ts.Tensor {
+		return func(output, target *ts.Tensor) *ts.Tensor {
+			return output.Apply(linear).MustMseLoss(target, int64(ts.ReductionMean), true)
+		}
+	}
+
+	// Chunks of 4, 4 and 2 timesteps.
+	opt1, err := nn.DefaultSGDConfig().Build(vs1, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trainer := nn.NewChunkedTrainer(lstm1, opt1, 4)
+	chunkedLoss := trainer.Step(input, target, lossFn(linear1))
+
+	opt2, err := nn.DefaultSGDConfig().Build(vs2, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _ := lstm2.Seq(input)
+	loss := lossFn(linear2)(output, target)
+	fullLoss := loss.Float64Values()[0]
+	opt2.BackwardStep(loss)
+
+	if diff := chunkedLoss - fullLoss; diff > 1e-5 || diff < -1e-5 {
+		t.Errorf("Expected chunked loss %v to match full-sequence loss %v\n", chunkedLoss, fullLoss)
+	}
+	for name, v := range vs1.Vars.NamedVariables {
+		if diff := maxAbsDiff(v, vs2.Vars.NamedVariables[name]); diff > 1e-5 {
+			t.Errorf("%v - Expected chunked update to match full-sequence update, got max difference %v\n", name, diff)
+		}
+	}
+}
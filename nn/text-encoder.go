@@ -0,0 +1,86 @@
+package nn
+
+// A text encoder block: embedding, dropout and LSTM.
+
+import (
+	"log"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// TextEncoder encodes padded batches of token indices with an embedding
+// layer, dropout on the embeddings and a LSTM.
+type TextEncoder struct {
+	Embedding *Embedding
+	Dropout   *Dropout
+	LSTM      *LSTM
+
+	batchFirst bool
+}
+
+// NewTextEncoder creates a new TextEncoder. The embeddings are dropped out
+// with probability `cfg.Dropout` when `cfg.Train` is set, which can be changed
+// through the `Dropout` layer. The LSTM is configured with cfg.
+func NewTextEncoder(vs *Path, vocabSize, embedDim, hiddenDim int64, cfg *RNNConfig) *TextEncoder {
+	dropout := NewDropout(cfg.Dropout)
+	dropout.Train = cfg.Train
+
+	return &TextEncoder{
+		Embedding:  NewEmbedding(vs.Sub("embedding"), vocabSize, embedDim, DefaultEmbeddingConfig()),
+		Dropout:    dropout,
+		LSTM:       NewLSTM(vs.Sub("lstm"), embedDim, hiddenDim, cfg),
+		batchFirst: cfg.BatchFirst,
+	}
+}
+
+// Forward encodes indices of shape [batch_size, seq_len] (or [seq_len,
+// batch_size] if not `BatchFirst`) padded beyond lengths, the valid length of
+// each sequence. If lengths is nil, all timesteps are valid.
+//
+// Padded timesteps are skipped: the output is zero there and the final state
+// of each sequence is the state at its last valid timestep (see
+// `LSTM.MaskedSeqInit`).
+func (te *TextEncoder) Forward(indices *ts.Tensor, lengths []int64) (*ts.Tensor, State) {
+	size := indices.MustSize()
+	if len(size) != 2 {
+		log.Fatalf("TextEncoder - Expected 2D indices, got shape %v\n", size)
+	}
+	batchDim, seqLen := size[0], size[1]
+	if !te.batchFirst {
+		batchDim, seqLen = seqLen, batchDim
+	}
+
+	embedded := te.Embedding.Forward(indices)
+	dropped := te.Dropout.Forward(embedded)
+	embedded.MustDrop()
+	defer dropped.MustDrop()
+
+	if lengths == nil {
+		return te.LSTM.Seq(dropped)
+	}
+
+	if int64(len(lengths)) != batchDim {
+		log.Fatalf("TextEncoder - Expected %v lengths, got %v\n", batchDim, len(lengths))
+	}
+	for _, l := range lengths {
+		if l < 1 || l > seqLen {
+			log.Fatalf("TextEncoder - Expected lengths in [1, %v], got %v\n", seqLen, lengths)
+		}
+	}
+
+	device := indices.MustDevice()
+	lens := ts.MustOfSlice(lengths).MustTo(device, true)
+	mask := lengthsMask(lens, seqLen, device).MustTotype(dropped.DType(), true)
+	lens.MustDrop()
+	if !te.batchFirst {
+		mask = mask.MustTranspose(0, 1, true)
+	}
+	defer mask.MustDrop()
+
+	inState := te.LSTM.ZeroState(batchDim)
+	output, state := te.LSTM.MaskedSeqInit(dropped, mask, inState)
+	inState.(*LSTMState).Tensor1.MustDrop()
+	inState.(*LSTMState).Tensor2.MustDrop()
+
+	return output, state
+}
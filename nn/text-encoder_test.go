@@ -0,0 +1,36 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestTextEncoder(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	encoder := nn.NewTextEncoder(vs.Root(), 10, 4, 6, nn.DefaultRNNConfig())
+
+	// The second sequence has 3 tokens, then padding.
+	indices := ts.MustOfSlice([]int64{1, 2, 3, 4, 5, 6, 7, 8, 0, 0}).MustView([]int64{2, 5}, true)
+	output, state := encoder.Forward(indices, []int64{5, 3})
+
+	if want, got := []int64{2, 5, 6}, output.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected output shape %v, got %v\n", want, got)
+	}
+	padded := output.MustSelect(0, 1, false).MustNarrow(0, 3, 2, true)
+	if diff := maxAbsDiff(padded, padded.MustZerosLike(false)); diff != 0 {
+		t.Errorf("Expected zero output at padded timesteps, got max value %v\n", diff)
+	}
+
+	// The final state of the second sequence matches encoding it alone,
+	// without padding.
+	unpadded := ts.MustOfSlice([]int64{6, 7, 8}).MustView([]int64{1, 3}, true)
+	_, want := encoder.Forward(unpadded, nil)
+	got := state.(*nn.LSTMState).Tensor1.MustNarrow(1, 1, 1, false)
+	if diff := maxAbsDiff(got, want.(*nn.LSTMState).Tensor1); diff > 1e-5 {
+		t.Errorf("Expected the final state to ignore padding, got max difference %v\n", diff)
+	}
+}
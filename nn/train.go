@@ -0,0 +1,97 @@
+package nn
+
+// Switching a whole model between training and evaluation mode.
+
+import (
+	"reflect"
+)
+
+// SetTrain sets the training mode of all known layers found in module.
+//
+// module can be a layer or any struct (pointer), slice, Sequential or
+// RNNSequential containing layers. It is walked recursively through exported
+// fields and LSTM, GRU, Dropout and BatchNorm layers have their train flag
+// set. For RNN layers, this updates `config.Train`.
+//
+// Example: calling SetTrain(model, false) before evaluation disables
+// dropout across the whole model.
+func SetTrain(module interface{}, train bool) {
+	setTrain(reflect.ValueOf(module), train, make(map[uintptr]bool))
+}
+
+func setTrain(v reflect.Value, train bool, visited map[uintptr]bool) {
+	if !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		setTrain(v.Elem(), train, visited)
+		return
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+	}
+
+	if v.CanInterface() {
+		switch m := v.Interface().(type) {
+		case *LSTM:
+			m.config.Train = train
+			return
+		case *GRU:
+			m.config.Train = train
+			return
+		case *MinGRU:
+			m.config.Train = train
+			return
+		case *SRU:
+			m.config.Train = train
+			return
+		case *Dropout:
+			m.Train = train
+			return
+		case *BatchNorm:
+			m.Train = train
+			return
+		case *Sequential:
+			for _, l := range m.layers {
+				setTrain(reflect.ValueOf(l), train, visited)
+			}
+			return
+		case *SequentialT:
+			for _, l := range m.layers {
+				setTrain(reflect.ValueOf(l), train, visited)
+			}
+			return
+		case *RNNSequential:
+			for _, l := range m.layers {
+				setTrain(reflect.ValueOf(l), train, visited)
+			}
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		setTrain(v.Elem(), train, visited)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				// unexported field
+				continue
+			}
+			setTrain(v.Field(i), train, visited)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			setTrain(v.Index(i), train, visited)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			setTrain(iter.Value(), train, visited)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package nn_test
+
+import (
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+type trainTestModel struct {
+	Encoder *nn.LSTM
+	Decoder *nn.GRU
+	Fast    *nn.MinGRU
+	Simple  *nn.SRU
+	Drop    *nn.Dropout
+	Norm    *nn.BatchNorm
+	Head    *nn.SequentialT
+}
+
+func TestSetTrain(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	path := vs.Root()
+
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	cfg.Dropout = 0.5
+
+	gruCfg := nn.DefaultRNNConfig()
+	gruCfg.NumLayers = 2
+	gruCfg.Dropout = 0.5
+
+	minGRUCfg := nn.DefaultRNNConfig()
+	sruCfg := nn.DefaultRNNConfig()
+
+	headDrop := nn.NewDropout(0.5)
+	head := nn.SeqT()
+	head.Add(headDrop)
+
+	model := &trainTestModel{
+		Encoder: nn.NewLSTM(path.Sub("encoder"), 4, 8, cfg),
+		Decoder: nn.NewGRU(path.Sub("decoder"), 8, 8, gruCfg),
+		Fast:    nn.NewMinGRU(path.Sub("fast"), 8, 8, minGRUCfg),
+		Simple:  nn.NewSRU(path.Sub("simple"), 8, 8, sruCfg),
+		Drop:    nn.NewDropout(0.5),
+		Norm:    nn.BatchNorm1D(path.Sub("norm"), 8, nn.DefaultBatchNormConfig()),
+		Head:    head,
+	}
+
+	nn.SetTrain(model, false)
+
+	if cfg.Train || gruCfg.Train || minGRUCfg.Train || sruCfg.Train {
+		t.Errorf("Expected RNN configs in eval mode, got LSTM: %v, GRU: %v, MinGRU: %v, SRU: %v\n", cfg.Train, gruCfg.Train, minGRUCfg.Train, sruCfg.Train)
+	}
+	if model.Drop.Train || headDrop.Train {
+		t.Errorf("Expected dropout layers in eval mode, got %v, %v\n", model.Drop.Train, headDrop.Train)
+	}
+	if model.Norm.Train {
+		t.Errorf("Expected batch norm in eval mode\n")
+	}
+
+	// Without dropout, forward passes are deterministic.
+	input := ts.MustRandn([]int64{3, 5, 4}, gotch.Float, gotch.CPU)
+	out1, _ := model.Encoder.Seq(input)
+	out2, _ := model.Encoder.Seq(input)
+	if diff := maxAbsDiff(out1, out2); diff != 0 {
+		t.Errorf("Expected deterministic output in eval mode, got max difference %v\n", diff)
+	}
+
+	nn.SetTrain(model, true)
+
+	if !cfg.Train || !gruCfg.Train || !minGRUCfg.Train || !sruCfg.Train || !model.Drop.Train || !headDrop.Train || !model.Norm.Train {
+		t.Errorf("Expected all layers back in train mode\n")
+	}
+}
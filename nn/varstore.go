@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
@@ -22,6 +24,7 @@ type Variables struct {
 	mutex              *sync.Mutex
 	NamedVariables     map[string]*ts.Tensor
 	TrainableVariables []ts.Tensor
+	pytorchNames       map[string]string // variable name -> PyTorch name
 }
 
 // VarStore is used to store variables used by one or multiple layers.
@@ -35,6 +38,9 @@ type VarStore struct {
 type Path struct {
 	path     []string
 	varstore *VarStore
+
+	// device overrides the var-store device for new variables if not nil.
+	device *gotch.Device
 }
 
 // Entry holds an entry corresponding to a given name in Path.
@@ -50,6 +56,7 @@ func NewVarStore(device gotch.Device) *VarStore {
 		mutex:              &sync.Mutex{},
 		NamedVariables:     make(map[string]*ts.Tensor, 0),
 		TrainableVariables: make([]ts.Tensor, 0),
+		pytorchNames:       make(map[string]string, 0),
 	}
 
 	return &VarStore{
@@ -96,6 +103,55 @@ func (vs *VarStore) TrainableVariables() []ts.Tensor {
 	return retVal
 }
 
+// TrainableVariablesMatching returns trainable variables whose name matches
+// the regular expression pattern, sorted by name.
+//
+// E.g. `vs.TrainableVariablesMatching("^rnn\\.")` returns all trainable
+// variables under the "rnn" path. They can be used as an optimizer
+// parameter group (see `Optimizer.AddParamGroup`).
+func (vs *VarStore) TrainableVariablesMatching(pattern string) ([]ts.Tensor, error) {
+	_, retVal, err := vs.namedTrainableVariables(pattern)
+
+	return retVal, err
+}
+
+// namedTrainableVariables returns the names and values of the trainable
+// variables whose name matches pattern, sorted by name.
+func (vs *VarStore) namedTrainableVariables(pattern string) ([]string, []ts.Tensor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vs.Vars.mutex.Lock()
+	defer vs.Vars.mutex.Unlock()
+
+	var names []string
+	for name := range vs.Vars.NamedVariables {
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var (
+		trainableNames []string
+		retVal         []ts.Tensor
+	)
+	for _, name := range names {
+		v := *vs.Vars.NamedVariables[name]
+		for _, t := range vs.Vars.TrainableVariables {
+			if t == v {
+				trainableNames = append(trainableNames, name)
+				retVal = append(retVal, v)
+				break
+			}
+		}
+	}
+
+	return trainableNames, retVal, nil
+}
+
 // Variables returns all variables and their names in a map[variable_name]Tensor
 func (vs *VarStore) Variables() map[string]*ts.Tensor {
 	vs.Vars.mutex.Lock()
@@ -110,6 +166,35 @@ func (vs *VarStore) Variables() map[string]*ts.Tensor {
 	return namedTensors
 }
 
+// MemoryBytes returns the memory occupied by the values of all variables, in
+// bytes. Gradients and optimizer states are not counted.
+func (vs *VarStore) MemoryBytes() int64 {
+	var total int64
+	for _, n := range vs.MemoryBreakdown() {
+		total += n
+	}
+
+	return total
+}
+
+// MemoryBreakdown returns the memory occupied by the value of each variable,
+// in bytes, by variable name.
+func (vs *VarStore) MemoryBreakdown() map[string]int64 {
+	vs.Vars.mutex.Lock()
+	defer vs.Vars.mutex.Unlock()
+
+	retVal := make(map[string]int64, len(vs.Vars.NamedVariables))
+	for name, v := range vs.Vars.NamedVariables {
+		size, err := gotch.DTypeSize(v.DType())
+		if err != nil {
+			log.Fatalf("VarStore.MemoryBreakdown - variable %q: %v\n", name, err)
+		}
+		retVal[name] = int64(v.Numel()) * int64(size)
+	}
+
+	return retVal
+}
+
 // Root gets the root path for this var-store
 //
 // NOTE: Variables are named and organized using paths. This function returns
@@ -143,6 +228,39 @@ func (vs *VarStore) Save(filepath string) error {
 	return ts.SaveMultiNew(namedTensors, filepath)
 }
 
+// PyTorchStateDict returns the variables of the var-store by their names in
+// PyTorch, e.g. "lstm.weight_ih_l0" for the first LSTM layer input weights.
+// Variables without PyTorch counterparts keep their names.
+func (vs *VarStore) PyTorchStateDict() map[string]*ts.Tensor {
+	vs.Vars.mutex.Lock()
+	defer vs.Vars.mutex.Unlock()
+
+	retVal := make(map[string]*ts.Tensor, len(vs.Vars.NamedVariables))
+	for name, v := range vs.Vars.NamedVariables {
+		if pytorchName, ok := vs.Vars.pytorchNames[name]; ok {
+			name = pytorchName
+		}
+		retVal[name] = v
+	}
+
+	return retVal
+}
+
+// SavePyTorch saves the var-store variable values to a file which can be
+// loaded in Python with `torch.load`, as a state dict keyed by the PyTorch
+// names of the variables (see `PyTorchStateDict`).
+func (vs *VarStore) SavePyTorch(filepath string) error {
+	var namedTensors []ts.NamedTensor
+	for name, v := range vs.PyTorchStateDict() {
+		namedTensors = append(namedTensors, ts.NamedTensor{
+			Name:   name,
+			Tensor: v,
+		})
+	}
+
+	return ts.SavePickle(namedTensors, filepath)
+}
+
 // Load loads the var-store variable values from a file.
 //
 // NOTE: Weight values for all the tensors currently stored in the
@@ -278,6 +396,27 @@ func (vs *VarStore) Unfreeze() {
 	}
 }
 
+// ToDType converts all floating point variables of the var store to dtype,
+// e.g. gotch.Double or gotch.BFloat16, in place. Layers using these variables see the change.
+func (vs *VarStore) ToDType(dtype gotch.DType) {
+	vs.Vars.mutex.Lock()
+	defer vs.Vars.mutex.Unlock()
+
+	ts.NoGrad(func() {
+		for name, v := range vs.Vars.NamedVariables {
+			if kind := v.DType(); kind != gotch.Float && kind != gotch.Double && kind != gotch.BFloat16 {
+				continue
+			}
+
+			data := v.MustTotype(dtype, false)
+			if err := v.SetData(data); err != nil {
+				log.Fatalf("ToDType() Error: variable %q: %v\n", name, err)
+			}
+			data.MustDrop()
+		}
+	})
+}
+
 // Copy copies variable values from a source var store to this var store.
 //
 // All the variables in this var store have to exist with the same
@@ -327,15 +466,30 @@ func (p *Path) Sub(str string) *Path {
 	return &Path{
 		path:     path,
 		varstore: p.varstore,
+		device:   p.device,
 	}
 }
 
 // Device gets the device where the var-store variables are stored.
 func (p *Path) Device() gotch.Device {
+	if p.device != nil {
+		return *p.device
+	}
 
 	return p.varstore.device
 }
 
+// onDevice returns a copy of the path whose new variables are created on
+// device instead of the var-store device. Callers are responsible for moving
+// them to the var-store device later on.
+func (p *Path) onDevice(device gotch.Device) *Path {
+	return &Path{
+		path:     p.path,
+		varstore: p.varstore,
+		device:   &device,
+	}
+}
+
 // NOTE: Cannot name as `path` as having a field name `path`
 func (p *Path) getpath(name string) string {
 
@@ -382,6 +536,19 @@ func (p *Path) add(name string, newTs *ts.Tensor, trainable bool) *ts.Tensor {
 	return tensor
 }
 
+// setPyTorchName names variable t `name` under the path in PyTorch state dicts.
+func (p *Path) setPyTorchName(t *ts.Tensor, name string) {
+	p.varstore.Vars.mutex.Lock()
+	defer p.varstore.Vars.mutex.Unlock()
+
+	for path, v := range p.varstore.Vars.NamedVariables {
+		if v == t {
+			p.varstore.Vars.pytorchNames[path] = p.getpath(name)
+			return
+		}
+	}
+}
+
 func (p *Path) getOrAddWithLock(name string, tensor *ts.Tensor, trainable bool, variables Variables) *ts.Tensor {
 	path := p.getpath(name)
 
@@ -454,7 +621,7 @@ func (p *Path) OnesNoTrain(name string, dims []int64) *ts.Tensor {
 // related argument.
 func (p *Path) NewVar(name string, dims []int64, ini Init) *ts.Tensor {
 
-	v := ini.InitTensor(dims, p.varstore.device)
+	v := ini.InitTensor(dims, p.Device())
 
 	return p.add(name, v, true)
 }
@@ -589,7 +756,7 @@ func (p *Path) Entry(name string) *Entry {
 // initialized according to the init parameter.
 func (e *Entry) OrVar(dims []int64, init Init) *ts.Tensor {
 
-	v := init.InitTensor(dims, e.path.varstore.device)
+	v := init.InitTensor(dims, e.path.Device())
 	return e.path.getOrAddWithLock(e.name, v, true, *e.variables)
 }
 
@@ -1,6 +1,9 @@
 package nn_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -133,3 +136,114 @@ func TestSaveLoad(t *testing.T) {
 		t.Errorf("Failed deleting varstore saved file: %v\n", filenameAbs)
 	}
 }
+
+func TestVarStoreMemoryBytes(t *testing.T) {
+	const (
+		inDim     = 3
+		hiddenDim = 8
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	nn.NewLSTM(vs.Root(), inDim, hiddenDim, cfg)
+
+	// Per layer: w_ih [4H, in], w_hh [4H, H], b_ih [4H] and b_hh [4H].
+	gateDim := 4 * hiddenDim
+	numParams := gateDim*inDim + gateDim*hiddenDim + 2*gateDim
+	numParams += gateDim*hiddenDim + gateDim*hiddenDim + 2*gateDim
+
+	if want, got := int64(numParams*4), vs.MemoryBytes(); want != got {
+		t.Errorf("Expected %v bytes of fp32 parameters, got %v\n", want, got)
+	}
+	if want, got := int64(gateDim*inDim*4), vs.MemoryBreakdown()["w_ih"]; want != got {
+		t.Errorf("Expected %v bytes for w_ih of the first layer, got %v\n", want, got)
+	}
+}
+
+func TestVarStoreSavePyTorch(t *testing.T) {
+	const (
+		inDim     = 3
+		hiddenDim = 5
+	)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	cfg := nn.DefaultRNNConfig()
+	cfg.NumLayers = 2
+	cfg.Bidirectional = true
+	nn.NewLSTM(vs.Root().Sub("lstm"), inDim, hiddenDim, cfg)
+	nn.NewLinear(vs.Root().Sub("fc"), 2*hiddenDim, 1, nn.DefaultLinearConfig())
+
+	gateDim := int64(4 * hiddenDim)
+	want := map[string][]int64{
+		"fc.weight": {1, 2 * hiddenDim},
+		"fc.bias":   {1},
+	}
+	for _, suffix := range []string{"l0", "l0_reverse", "l1", "l1_reverse"} {
+		inputDim := int64(inDim)
+		if suffix[1] == '1' {
+			inputDim = 2 * hiddenDim
+		}
+		want["lstm.weight_ih_"+suffix] = []int64{gateDim, inputDim}
+		want["lstm.weight_hh_"+suffix] = []int64{gateDim, hiddenDim}
+		want["lstm.bias_ih_"+suffix] = []int64{gateDim}
+		want["lstm.bias_hh_"+suffix] = []int64{gateDim}
+	}
+
+	stateDict := vs.PyTorchStateDict()
+	if len(stateDict) != len(want) {
+		t.Errorf("Expected %v entries, got %v\n", len(want), len(stateDict))
+	}
+	for name, shape := range want {
+		v, ok := stateDict[name]
+		if !ok {
+			t.Errorf("Missing %q\n", name)
+			continue
+		}
+		if got := v.MustSize(); !reflect.DeepEqual(shape, got) {
+			t.Errorf("Expected shape %v for %q, got %v\n", shape, name, got)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "gotch-pytorch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "model.pt")
+	if err := vs.SavePyTorch(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// torch.load reads zip archives whose pickled data holds the keys.
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var data []byte
+	for _, f := range r.File {
+		if filepath.Base(f.Name) != "data.pkl" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data == nil {
+		t.Fatalf("Missing pickled data in %v\n", filename)
+	}
+	for name := range want {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Errorf("Missing %q in the pickled data\n", name)
+		}
+	}
+}
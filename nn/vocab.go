@@ -0,0 +1,163 @@
+package nn
+
+// Token vocabulary.
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// Special tokens, always in a Vocab with ids 0 to 3 in this order.
+const (
+	PadToken     = "<pad>"
+	UnknownToken = ts.UnknownToken
+	BosToken     = "<bos>"
+	EosToken     = "<eos>"
+)
+
+var specialTokens = []string{PadToken, UnknownToken, BosToken, EosToken}
+
+// Vocab maps tokens (words or characters) to ids and back.
+//
+// It is serializable to JSON as the list of its tokens ordered by id.
+type Vocab struct {
+	tokens []string
+	ids    map[string]int64
+}
+
+// NewVocab creates a vocabulary holding only the special tokens.
+func NewVocab() *Vocab {
+	v := &Vocab{ids: make(map[string]int64)}
+	for _, token := range specialTokens {
+		v.add(token)
+	}
+
+	return v
+}
+
+// add appends token to the vocabulary if it is missing.
+func (v *Vocab) add(token string) {
+	if _, ok := v.ids[token]; ok {
+		return
+	}
+
+	v.ids[token] = int64(len(v.tokens))
+	v.tokens = append(v.tokens, token)
+}
+
+// Build adds the tokens occurring at least minFreq times in tokens, the
+// most frequent first. Ties keep their order of first occurrence.
+func (v *Vocab) Build(tokens []string, minFreq int64) {
+	counts := make(map[string]int64)
+	var unique []string
+	for _, token := range tokens {
+		if counts[token] == 0 {
+			unique = append(unique, token)
+		}
+		counts[token]++
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		return counts[unique[i]] > counts[unique[j]]
+	})
+
+	for _, token := range unique {
+		if counts[token] >= minFreq {
+			v.add(token)
+		}
+	}
+}
+
+// Len returns the number of tokens, special tokens included.
+func (v *Vocab) Len() int64 {
+	return int64(len(v.tokens))
+}
+
+// StringToId returns the id of token, the id of `UnknownToken` if it is not
+// in the vocabulary.
+func (v *Vocab) StringToId(token string) int64 {
+	if id, ok := v.ids[token]; ok {
+		return id
+	}
+
+	return v.ids[UnknownToken]
+}
+
+// IdToString returns the token of id, `UnknownToken` if id is out of range.
+func (v *Vocab) IdToString(id int64) string {
+	if id < 0 || id >= int64(len(v.tokens)) {
+		return UnknownToken
+	}
+
+	return v.tokens[id]
+}
+
+// Encode encodes whitespace tokenized sentences into a padded index tensor.
+//
+// Each sentence is wrapped with `BosToken` and `EosToken`, then padded with
+// `PadToken` to maxLen. Sentences longer than maxLen are truncated, keeping
+// `EosToken` as their last token. It returns the indices of shape
+// [batch_size, maxLen] and the length of each sequence, special tokens
+// included.
+func (v *Vocab) Encode(sentences []string, maxLen int64) (*ts.Tensor, []int64) {
+	if maxLen < 2 {
+		log.Fatalf("Vocab.Encode - Expected maxLen to fit <bos> and <eos>, got %v\n", maxLen)
+	}
+
+	indices := make([]int64, int64(len(sentences))*maxLen)
+	lengths := make([]int64, len(sentences))
+	for i, sentence := range sentences {
+		tokens := strings.Fields(sentence)
+		if int64(len(tokens)) > maxLen-2 {
+			tokens = tokens[:maxLen-2]
+		}
+
+		row := indices[int64(i)*maxLen : int64(i+1)*maxLen]
+		row[0] = v.ids[BosToken]
+		for j, token := range tokens {
+			row[j+1] = v.StringToId(token)
+		}
+		row[len(tokens)+1] = v.ids[EosToken]
+		for j := len(tokens) + 2; j < len(row); j++ {
+			row[j] = v.ids[PadToken]
+		}
+		lengths[i] = int64(len(tokens) + 2)
+	}
+
+	return ts.MustOfSlice(indices).MustView([]int64{int64(len(sentences)), maxLen}, true), lengths
+}
+
+// Ids returns a copy of the token to id mapping, e.g. to read a
+// `ts.TextDataset`.
+func (v *Vocab) Ids() map[string]int64 {
+	ids := make(map[string]int64, len(v.ids))
+	for token, id := range v.ids {
+		ids[token] = id
+	}
+
+	return ids
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (v *Vocab) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.tokens)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (v *Vocab) UnmarshalJSON(data []byte) error {
+	var tokens []string
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return err
+	}
+
+	*v = *NewVocab()
+	for _, token := range tokens {
+		v.add(token)
+	}
+
+	return nil
+}
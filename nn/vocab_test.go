@@ -0,0 +1,70 @@
+package nn_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch/nn"
+)
+
+func TestVocab(t *testing.T) {
+	vocab := nn.NewVocab()
+	vocab.Build(strings.Fields("the cat sat on the mat the cat ran"), 2)
+
+	// Special tokens come first, then "the" (3 times) and "cat" (2 times).
+	// Tokens seen once are filtered out.
+	if got := vocab.Len(); got != 6 {
+		t.Errorf("Expected 6 tokens, got %v\n", got)
+	}
+	for token, want := range map[string]int64{nn.PadToken: 0, nn.UnknownToken: 1, nn.BosToken: 2, nn.EosToken: 3, "the": 4, "cat": 5} {
+		if got := vocab.StringToId(token); got != want {
+			t.Errorf("Expected id %v for %q, got %v\n", want, token, got)
+		}
+		if got := vocab.IdToString(want); got != token {
+			t.Errorf("Expected token %q for id %v, got %q\n", token, want, got)
+		}
+	}
+
+	for _, token := range []string{"sat", "dog"} {
+		if got, want := vocab.StringToId(token), vocab.StringToId(nn.UnknownToken); got != want {
+			t.Errorf("Expected the unknown token id %v for %q, got %v\n", want, token, got)
+		}
+	}
+
+	data, err := json.Marshal(vocab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := nn.NewVocab()
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vocab.Ids(), loaded.Ids()) {
+		t.Errorf("Expected %v after a JSON round trip, got %v\n", vocab.Ids(), loaded.Ids())
+	}
+}
+
+func TestVocabEncode(t *testing.T) {
+	vocab := nn.NewVocab()
+	vocab.Build(strings.Fields("the cat sat on the mat"), 1)
+
+	// the: 4, cat: 5, sat: 6, on: 7, mat: 8
+	xs, lengths := vocab.Encode([]string{"the cat", "the cat sat on the mat", "a mat"}, 6)
+
+	if want, got := []int64{3, 6}, xs.MustSize(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected shape %v, got %v\n", want, got)
+	}
+	want := []int64{
+		2, 4, 5, 3, 0, 0, // padded
+		2, 4, 5, 6, 7, 3, // truncated, <eos> kept
+		2, 1, 8, 3, 0, 0, // "a" is unknown
+	}
+	if got := xs.Int64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected indices %v, got %v\n", want, got)
+	}
+	if want, got := []int64{4, 6, 4}, lengths; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected lengths %v, got %v\n", want, got)
+	}
+}
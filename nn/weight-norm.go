@@ -0,0 +1,61 @@
+package nn
+
+// Weight normalization.
+
+import (
+	"fmt"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// WeightNorm reparameterizes a weight by its magnitude `G` and its direction
+// `V` as `w = G * V / ||V||`, where the norm is computed over all dimensions
+// except `Dim`.
+//
+// Ref. Salimans et al., "Weight Normalization: A Simple Reparameterization to
+// Accelerate Training of Deep Neural Networks", 2016. https://arxiv.org/abs/1602.07868
+type WeightNorm struct {
+	G   *ts.Tensor
+	V   *ts.Tensor
+	Dim int64
+}
+
+// NewWeightNorm creates a weight-normalized variable of the given shape.
+//
+// It registers `name_g` (magnitude) and `name_v` (direction) variables in the
+// var-store. `V` is initialized with Kaiming uniform and `G` with the norm of
+// `V` so that the initial effective weight equals `V`.
+func NewWeightNorm(vs *Path, name string, shape []int64, dim int64) *WeightNorm {
+	v := vs.KaimingUniform(fmt.Sprintf("%v_v", name), shape)
+
+	norm := ts.MustNormExceptDim(v, 2, dim)
+	g := vs.VarCopy(fmt.Sprintf("%v_g", name), norm)
+	norm.MustDrop()
+
+	return &WeightNorm{
+		G:   g,
+		V:   v,
+		Dim: dim,
+	}
+}
+
+// Weight computes the effective weight from the current magnitude and direction.
+func (wn *WeightNorm) Weight() *ts.Tensor {
+	return ts.Must_WeightNorm(wn.V, wn.G, wn.Dim)
+}
+
+// reset re-initializes the direction with Kaiming uniform and the magnitude
+// with its norm.
+//
+// NOTE. It should be called in a `ts.NoGrad` scope.
+func (wn *WeightNorm) reset() {
+	NewKaimingUniformInit().Set(wn.V)
+	norm := ts.MustNormExceptDim(wn.V, 2, wn.Dim)
+	wn.G.Copy_(norm)
+	norm.MustDrop()
+}
+
+// variables returns the magnitude and direction variables.
+func (wn *WeightNorm) variables() []ts.Tensor {
+	return []ts.Tensor{*wn.G, *wn.V}
+}
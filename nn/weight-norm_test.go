@@ -0,0 +1,52 @@
+package nn_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	"github.com/sugarme/gotch/nn"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestWeightNorm(t *testing.T) {
+	vs := nn.NewVarStore(gotch.CPU)
+	wn := nn.NewWeightNorm(vs.Root(), "weight", []int64{6, 4}, 0)
+
+	g := ts.MustRand([]int64{6, 1}, gotch.Float, gotch.CPU)
+	ts.NoGrad(func() {
+		wn.G.Copy_(g)
+	})
+
+	w := wn.Weight()
+	norm := ts.MustNormExceptDim(w, 2, 0)
+
+	if diff := maxAbsDiff(norm, wn.G); diff > 1e-5 {
+		t.Errorf("Expected weight norm along dim 0 to equal g, got max difference %v\n", diff)
+	}
+
+	want := []int64{6, 4}
+	got := w.MustSize()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected weight shape: %v\n", want)
+		t.Errorf("Got weight shape: %v\n", got)
+	}
+}
+
+func TestRNNWeightNorm(t *testing.T) {
+	cfg := nn.DefaultRNNConfig()
+	cfg.WeightNorm = true
+
+	lstmTest(cfg, t)
+	gruTest(cfg, t)
+
+	vs := nn.NewVarStore(gotch.CPU)
+	nn.NewLSTM(vs.Root(), 2, 4, cfg)
+
+	vars := vs.Variables()
+	for _, name := range []string{"w_ih_g", "w_ih_v", "w_hh_g", "w_hh_v"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("Expected variable %v in var-store\n", name)
+		}
+	}
+}
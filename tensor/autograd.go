@@ -0,0 +1,54 @@
+package tensor
+
+// Inspection of autograd graphs.
+
+import (
+	"fmt"
+	"strings"
+
+	lib "github.com/sugarme/gotch/libtch"
+)
+
+// AutogradGraph returns the autograd graph that computed the tensor.
+//
+// nodes holds the names of the backward functions (e.g. "MulBackward0",
+// "torch::autograd::AccumulateGrad" for leaf variables), node 0 being the
+// tensor grad_fn. edges holds pairs of node indexes [from, to], from a node
+// to the node consuming its output. It returns no nodes if the tensor does not
+// require gradients.
+func (ts *Tensor) AutogradGraph() (nodes []string, edges [][2]int, err error) {
+	graph := lib.AtAutogradGraph(ts.ctensor)
+	if err := TorchErr(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(graph), "\n") {
+		if line == "" {
+			continue
+		}
+
+		// Node names may contain spaces.
+		fields := strings.SplitN(line, " ", 3)
+		switch {
+		case fields[0] == "N" && len(fields) == 3:
+			var id int
+			if _, err := fmt.Sscan(fields[1], &id); err != nil || id != len(nodes) {
+				return nil, nil, fmt.Errorf("AutogradGraph - Invalid node line: %q\n", line)
+			}
+			nodes = append(nodes, fields[2])
+		case fields[0] == "E" && len(fields) == 3:
+			var edge [2]int
+			if _, err := fmt.Sscan(fields[1], &edge[0]); err != nil {
+				return nil, nil, fmt.Errorf("AutogradGraph - Invalid edge line: %q\n", line)
+			}
+			if _, err := fmt.Sscan(fields[2], &edge[1]); err != nil {
+				return nil, nil, fmt.Errorf("AutogradGraph - Invalid edge line: %q\n", line)
+			}
+			edges = append(edges, edge)
+		default:
+			return nil, nil, fmt.Errorf("AutogradGraph - Invalid line: %q\n", line)
+		}
+	}
+
+	return nodes, edges, nil
+}
@@ -1,6 +1,7 @@
 package tensor
 
 import (
+	"bufio"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -23,6 +24,7 @@ type Iter2 struct {
 	totalSize            int64
 	device               gotch.Device
 	returnSmallLastBatch bool
+	pinMemory            bool
 }
 
 // NewIter2 returns a new iterator.
@@ -123,6 +125,14 @@ func (it *Iter2) ReturnSmallLastBatch() *Iter2 {
 	return it
 }
 
+// PinMemory when set, returns mini-batches in pinned (page-locked) host
+// memory so that their transfer to a CUDA device can be asynchronous. It
+// requires CUDA to be available.
+func (it *Iter2) PinMemory() *Iter2 {
+	it.pinMemory = true
+	return it
+}
+
 type Iter2Item struct {
 	Data  *Tensor
 	Label *Tensor
@@ -145,10 +155,16 @@ func (it *Iter2) Next() (item Iter2Item, ok bool) {
 		// Indexing
 		narrowIndex := NewNarrow(start, start+size)
 
-		return Iter2Item{
+		item = Iter2Item{
 			Data:  it.xs.Idx(narrowIndex),
 			Label: it.ys.Idx(narrowIndex),
-		}, true
+		}
+		if it.pinMemory {
+			item.Data = item.Data.MustPinMemory(true)
+			item.Label = item.Label.MustPinMemory(true)
+		}
+
+		return item, true
 	}
 }
 
@@ -293,6 +309,117 @@ func (tdi *TextDataIter) Next() (*Tensor, bool) {
 	return retVal, true
 }
 
+// Dataset is a source of samples read one at a time.
+type Dataset interface {
+	// Next returns the next sample, false once the dataset is exhausted.
+	Next() (*Tensor, bool)
+}
+
+// TextDataset streams fixed-length sequences of token ids from a text file
+// without loading it in memory.
+//
+// The file is split into whitespace separated words, each mapped to its id
+// in the vocabulary. Words missing from the vocabulary are mapped to the id
+// of `UnknownToken` if it is in the vocabulary, skipped otherwise.
+//
+// Sequences are read through a sliding window of `SeqLen` tokens moved by
+// `Stride` tokens after each sequence: consecutive sequences overlap if
+// Stride < SeqLen and tokens are skipped in between if Stride > SeqLen.
+// A trailing window shorter than SeqLen is dropped.
+type TextDataset struct {
+	SeqLen int64
+	Stride int64
+
+	file    *os.File
+	scanner *bufio.Scanner
+	vocab   map[string]int64
+	window  []int64 // tokens of the next sequence read so far
+	skip    int64   // tokens to skip before filling the window
+}
+
+// UnknownToken is the vocabulary entry used by `TextDataset` for words
+// missing from the vocabulary.
+const UnknownToken = "<unk>"
+
+// NewTextDataset opens a text file to be read as sequences of seqLen token
+// ids, through a sliding window moved by stride tokens.
+func NewTextDataset(filename string, vocab map[string]int64, seqLen, stride int64) (*TextDataset, error) {
+	if seqLen <= 0 || stride <= 0 {
+		err := fmt.Errorf("NewTextDataset - Expected positive sequence length and stride, got %v and %v\n", seqLen, stride)
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanWords)
+
+	return &TextDataset{
+		SeqLen:  seqLen,
+		Stride:  stride,
+		file:    file,
+		scanner: scanner,
+		vocab:   vocab,
+		window:  make([]int64, 0, seqLen),
+	}, nil
+}
+
+// Next implements `Dataset` interface. It returns the next sequence of token
+// ids as an Int64 tensor of shape [seq_len].
+func (td *TextDataset) Next() (*Tensor, bool) {
+	for int64(len(td.window)) < td.SeqLen {
+		id, ok := td.nextToken()
+		if !ok {
+			return nil, false
+		}
+
+		if td.skip > 0 {
+			td.skip--
+			continue
+		}
+		td.window = append(td.window, id)
+	}
+
+	retVal := MustOfSlice(td.window)
+
+	if td.Stride < td.SeqLen {
+		td.window = append(td.window[:0], td.window[td.Stride:]...)
+	} else {
+		td.window = td.window[:0]
+		td.skip = td.Stride - td.SeqLen
+	}
+
+	return retVal, true
+}
+
+// nextToken reads the id of the next word in the vocabulary.
+func (td *TextDataset) nextToken() (int64, bool) {
+	for td.scanner.Scan() {
+		if id, ok := td.vocab[td.scanner.Text()]; ok {
+			return id, true
+		}
+
+		if id, ok := td.vocab[UnknownToken]; ok {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// Err returns the first non-EOF error encountered while reading the file.
+func (td *TextDataset) Err() error {
+	return td.scanner.Err()
+}
+
+// Close closes the underlying file.
+func (td *TextDataset) Close() error {
+	return td.file.Close()
+}
+
 func min(v1, v2 int64) int64 {
 	if v1 < v2 {
 		return v1
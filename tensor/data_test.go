@@ -4,6 +4,7 @@ import (
 	// "fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -113,3 +114,87 @@ func TestTextDataIter(t *testing.T) {
 	}
 
 }
+
+func TestTextDataset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-text-dataset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "text.txt")
+	txt := "the cat sat on the mat\nand the dog ran"
+	if err := ioutil.WriteFile(filename, []byte(txt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// "dog" is not in the vocabulary: token ids are
+	// [0 1 2 3 0 4 5 0 7 6].
+	vocab := map[string]int64{"the": 0, "cat": 1, "sat": 2, "on": 3, "mat": 4, "and": 5, "ran": 6, ts.UnknownToken: 7}
+
+	tests := []struct {
+		seqLen, stride int64
+		want           [][]int64
+	}{
+		{4, 2, [][]int64{{0, 1, 2, 3}, {2, 3, 0, 4}, {0, 4, 5, 0}, {5, 0, 7, 6}}},
+		{3, 3, [][]int64{{0, 1, 2}, {3, 0, 4}, {5, 0, 7}}},
+		{3, 5, [][]int64{{0, 1, 2}, {4, 5, 0}}},
+	}
+
+	for _, tt := range tests {
+		dataset, err := ts.NewTextDataset(filename, vocab, tt.seqLen, tt.stride)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got [][]int64
+		for {
+			xs, ok := dataset.Next()
+			if !ok {
+				break
+			}
+			got = append(got, xs.Int64Values())
+			xs.MustDrop()
+		}
+		if err := dataset.Err(); err != nil {
+			t.Fatal(err)
+		}
+		dataset.Close()
+
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("seqLen %v, stride %v - Want windows: %v\n", tt.seqLen, tt.stride, tt.want)
+			t.Errorf("seqLen %v, stride %v - Got windows: %v\n", tt.seqLen, tt.stride, got)
+		}
+	}
+}
+
+func TestIter2PinMemory(t *testing.T) {
+	if !gotch.CUDA.IsAvailable() {
+		t.Skip("CUDA is not available")
+	}
+	device := gotch.CudaBuilder(0)
+
+	xs := ts.MustArange(ts.IntScalar(12), gotch.Float, gotch.CPU).MustView([]int64{6, 2}, true)
+	ys := ts.MustArange(ts.IntScalar(6), gotch.Int64, gotch.CPU)
+	iter := ts.MustNewIter2(xs, ys, 3).PinMemory()
+
+	var got []float64
+	for {
+		item, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if !item.Data.MustIsPinned() || !item.Label.MustIsPinned() {
+			t.Errorf("Expected pinned batches\n")
+		}
+
+		data := item.Data.MustTo(device, true)
+		got = append(got, data.MustTo(gotch.CPU, true).Float64Values()...)
+		item.Label.MustDrop()
+	}
+
+	if want := xs.Float64Values(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Want data: %v\n", want)
+		t.Errorf("Got data: %v\n", got)
+	}
+}
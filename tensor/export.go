@@ -0,0 +1,136 @@
+package tensor
+
+// Export tensors to formats readable by other tools.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sugarme/gotch"
+)
+
+// SaveCSV saves a 1D or 2D tensor to a CSV file.
+//
+// A 1D tensor is written as a single row, a 2D tensor as one row per
+// element of its first dimension.
+func (ts *Tensor) SaveCSV(path string) error {
+	size := ts.MustSize()
+	var rows, cols int64
+	switch len(size) {
+	case 1:
+		rows, cols = 1, size[0]
+	case 2:
+		rows, cols = size[0], size[1]
+	default:
+		err := fmt.Errorf("SaveCSV - Expected a 1D or 2D tensor, got shape %v\n", size)
+		return err
+	}
+
+	var format func(i int) string
+	switch dtype := ts.DType(); dtype {
+	case gotch.Half, gotch.BFloat16, gotch.Float:
+		// Half and BFloat16 values are exactly representable in float32.
+		vals := ts.Float64Values()
+		format = func(i int) string { return strconv.FormatFloat(vals[i], 'g', -1, 32) }
+	case gotch.Double:
+		vals := ts.Float64Values()
+		format = func(i int) string { return strconv.FormatFloat(vals[i], 'g', -1, 64) }
+	case gotch.Uint8, gotch.Int8, gotch.Int16, gotch.Int, gotch.Int64, gotch.Bool:
+		vals := ts.Int64Values()
+		format = func(i int) string { return strconv.FormatInt(vals[i], 10) }
+	default:
+		err := fmt.Errorf("SaveCSV - Unsupported dtype: %v\n", dtype)
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	row := make([]string, cols)
+	for r := 0; r < int(rows); r++ {
+		for c := 0; c < int(cols); c++ {
+			row[c] = format(r*int(cols) + c)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, ",")); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// npyDescr returns the NumPy type descriptor of a dtype.
+func npyDescr(dtype gotch.DType) (string, error) {
+	switch dtype {
+	case gotch.Uint8:
+		return "|u1", nil
+	case gotch.Int8:
+		return "|i1", nil
+	case gotch.Int16:
+		return "<i2", nil
+	case gotch.Int:
+		return "<i4", nil
+	case gotch.Int64:
+		return "<i8", nil
+	case gotch.Float:
+		return "<f4", nil
+	case gotch.Double:
+		return "<f8", nil
+	case gotch.Bool:
+		return "|b1", nil
+	default:
+		err := fmt.Errorf("Unsupported dtype for NPY format: %v\n", dtype)
+		return "", err
+	}
+}
+
+// SaveNPY saves a tensor to a file in NumPy binary format (.npy, version
+// 1.0) so that it can be loaded with `numpy.load`.
+func (ts *Tensor) SaveNPY(path string) error {
+	descr, err := npyDescr(ts.DType())
+	if err != nil {
+		return err
+	}
+
+	size := ts.MustSize()
+	dims := make([]string, len(size))
+	for i, d := range size {
+		dims[i] = strconv.FormatInt(d, 10)
+	}
+	shape := strings.Join(dims, ", ")
+	if len(size) == 1 {
+		shape += ","
+	}
+
+	header := fmt.Sprintf("{'descr': '%v', 'fortran_order': False, 'shape': (%v), }", descr, shape)
+	// magic string (6 bytes), version (2 bytes), header length (2 bytes) and
+	// header, terminated by '\n', should be a multiple of 64 bytes.
+	pad := 64 - (10+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY")
+	buf.Write([]byte{1, 0})
+	if err := binary.Write(&buf, binary.LittleEndian, uint16(len(header))); err != nil {
+		return err
+	}
+	buf.WriteString(header)
+	if err := binary.Write(&buf, binary.LittleEndian, ts.Vals()); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
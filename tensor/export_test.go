@@ -0,0 +1,161 @@
+package tensor_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestTensor_SaveCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := []float32{1.5, -2, 3.25, 0, 1e-3, 42}
+	tensor := ts.MustOfSlice(data).MustView([]int64{2, 3}, true)
+
+	path := filepath.Join(dir, "tensor.csv")
+	if err := tensor.SaveCSV(path); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 || len(records[0]) != 3 {
+		t.Fatalf("Expected 2 rows of 3 values, got %v\n", records)
+	}
+
+	var got []float32
+	for _, row := range records {
+		for _, s := range row {
+			v, err := strconv.ParseFloat(s, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, float32(v))
+		}
+	}
+
+	if !reflect.DeepEqual(data, got) {
+		t.Errorf("Expected values: %v\n", data)
+		t.Errorf("Got values: %v\n", got)
+	}
+
+	// 3D tensors are not supported.
+	if err := tensor.MustView([]int64{1, 2, 3}, false).SaveCSV(path); err == nil {
+		t.Errorf("Expected an error saving a 3D tensor to CSV\n")
+	}
+}
+
+func TestTensor_SaveCSVHalf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Values exactly representable in half precision.
+	data := []float32{1.5, -2, 3.25, 0.125, 1000, 0.0009765625}
+	tensor := ts.MustOfSlice(data).MustTotype(gotch.Half, true)
+
+	path := filepath.Join(dir, "half.csv")
+	if err := tensor.SaveCSV(path); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected a single row, got %v\n", records)
+	}
+
+	var got []float32
+	for _, s := range records[0] {
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, float32(v))
+	}
+
+	if !reflect.DeepEqual(data, got) {
+		t.Errorf("Expected values: %v\n", data)
+		t.Errorf("Got values: %v\n", got)
+	}
+}
+
+func TestTensor_SaveNPY(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotch-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := []float32{1.5, -2, 3.25, 0, 1e-3, 42}
+	tensor := ts.MustOfSlice(data).MustView([]int64{2, 3}, true)
+
+	path := filepath.Join(dir, "tensor.npy")
+	if err := tensor.SaveNPY(path); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(b, []byte("\x93NUMPY\x01\x00")) {
+		t.Fatalf("Expected NPY magic string and version 1.0, got %q\n", b[:8])
+	}
+
+	headerLen := int(binary.LittleEndian.Uint16(b[8:10]))
+	if (10+headerLen)%64 != 0 {
+		t.Errorf("Expected header aligned to 64 bytes, got %v\n", 10+headerLen)
+	}
+
+	header := string(b[10 : 10+headerLen])
+	for _, want := range []string{"'descr': '<f4'", "'fortran_order': False", "'shape': (2, 3)"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("Expected header to contain %v, got %v\n", want, header)
+		}
+	}
+
+	got := make([]float32, len(data))
+	if err := binary.Read(bytes.NewReader(b[10+headerLen:]), binary.LittleEndian, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(data, got) {
+		t.Errorf("Expected values: %v\n", data)
+		t.Errorf("Got values: %v\n", got)
+	}
+}
@@ -0,0 +1,99 @@
+package tensor
+
+// Seeded random number generators.
+
+import (
+	"log"
+
+	"github.com/sugarme/gotch"
+	lib "github.com/sugarme/gotch/libtch"
+)
+
+// Generator is a random number generator with its own state, independent of
+// the global RNG seeded by ManualSeed.
+//
+// Random tensors are sampled on CPU then moved to the target device, so the
+// same seed gives the same values whatever the device.
+type Generator struct {
+	cgenerator lib.Cgenerator
+}
+
+// NewGenerator creates a new generator seeded with seed.
+func NewGenerator(seed int64) (*Generator, error) {
+	cgenerator := lib.AtGeneratorNew(seed)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return &Generator{cgenerator}, nil
+}
+
+// MustNewGenerator creates a new generator seeded with seed. It panics if error.
+func MustNewGenerator(seed int64) *Generator {
+	g, err := NewGenerator(seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return g
+}
+
+// ManualSeed resets the generator state from seed.
+func (g *Generator) ManualSeed(seed int64) {
+	lib.AtGeneratorManualSeed(g.cgenerator, seed)
+
+	if err := TorchErr(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Drop frees the generator.
+func (g *Generator) Drop() {
+	lib.AtGeneratorFree(g.cgenerator)
+
+	if err := TorchErr(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// RandnWithGenerator returns a tensor of the given size filled with samples
+// from the standard normal distribution drawn from generator g.
+func RandnWithGenerator(size []int64, optionsKind gotch.DType, optionsDevice gotch.Device, g *Generator) (retVal *Tensor, err error) {
+	ctensor := lib.AtRandnGenerator(size, len(size), g.cgenerator, optionsKind.CInt(), optionsDevice.CInt())
+	if err = TorchErr(); err != nil {
+		return retVal, err
+	}
+
+	return &Tensor{ctensor: ctensor}, nil
+}
+
+// MustRandnWithGenerator is RandnWithGenerator. It panics if error.
+func MustRandnWithGenerator(size []int64, optionsKind gotch.DType, optionsDevice gotch.Device, g *Generator) (retVal *Tensor) {
+	retVal, err := RandnWithGenerator(size, optionsKind, optionsDevice, g)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return retVal
+}
+
+// BernoulliWithGenerator returns a tensor of the given size filled with ones
+// with probability p and zeros otherwise, drawn from generator g.
+func BernoulliWithGenerator(size []int64, p float64, optionsKind gotch.DType, optionsDevice gotch.Device, g *Generator) (retVal *Tensor, err error) {
+	ctensor := lib.AtBernoulliGenerator(size, len(size), p, g.cgenerator, optionsKind.CInt(), optionsDevice.CInt())
+	if err = TorchErr(); err != nil {
+		return retVal, err
+	}
+
+	return &Tensor{ctensor: ctensor}, nil
+}
+
+// MustBernoulliWithGenerator is BernoulliWithGenerator. It panics if error.
+func MustBernoulliWithGenerator(size []int64, p float64, optionsKind gotch.DType, optionsDevice gotch.Device, g *Generator) (retVal *Tensor) {
+	retVal, err := BernoulliWithGenerator(size, p, optionsKind, optionsDevice, g)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return retVal
+}
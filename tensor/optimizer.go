@@ -57,6 +57,16 @@ func Sgd(lr, momentum, dampening, wd float64, nesterov bool) (*COptimizer, error
 	return &COptimizer{coptimizer}, nil
 }
 
+// Adagrad returns Adagrad optimizer
+func Adagrad(lr, lrDecay, wd, eps float64) (*COptimizer, error) {
+	coptimizer := lib.AtoAdagrad(lr, lrDecay, wd, eps)
+	if err := TorchErr(); err != nil {
+		return nil, err
+	}
+
+	return &COptimizer{coptimizer}, nil
+}
+
 // AddParameters adds parameters as a slice of tensors to optimizer
 func (co *COptimizer) AddParameters(tensors []Tensor) error {
 
@@ -73,6 +83,16 @@ func (co *COptimizer) AddParameters(tensors []Tensor) error {
 	return TorchErr()
 }
 
+// AddParameter adds a parameter to the optimizer parameter group `group`.
+//
+// Groups up to `group` are created with the optimizer default options if
+// they do not exist yet. Group 0 is the one `AddParameters` adds to.
+func (co *COptimizer) AddParameter(param *Tensor, group uint) error {
+	lib.AtoAddParameters(co.coptimizer, param.ctensor, group)
+
+	return TorchErr()
+}
+
 // SetLeanringRate sets learning rate for the optimizer
 func (co *COptimizer) SetLearningRate(lr float64) error {
 	lib.AtoSetLearningRate(co.coptimizer, lr)
@@ -80,6 +100,13 @@ func (co *COptimizer) SetLearningRate(lr float64) error {
 	return TorchErr()
 }
 
+// SetLearningRateGroup sets learning rate for an optimizer parameter group
+func (co *COptimizer) SetLearningRateGroup(group uint, lr float64) error {
+	lib.AtoSetLearningRateGroup(co.coptimizer, group, lr)
+
+	return TorchErr()
+}
+
 // SetMomentum sets a momentum for the optimizer
 func (co *COptimizer) SetMomentum(m float64) error {
 	lib.AtoSetMomentum(co.coptimizer, m)
@@ -101,6 +128,22 @@ func (co *COptimizer) Step() error {
 	return TorchErr()
 }
 
+// Save saves the optimizer state (e.g. momentum buffers, step counts) to a
+// file. Per-parameter states are stored in the order parameters were added.
+func (co *COptimizer) Save(path string) error {
+	lib.AtoSave(co.coptimizer, path)
+
+	return TorchErr()
+}
+
+// Load loads the optimizer state from a file written by `Save`. Parameters
+// have to be added in the same order and with the same shapes beforehand.
+func (co *COptimizer) Load(path string) error {
+	lib.AtoLoad(co.coptimizer, path)
+
+	return TorchErr()
+}
+
 // Drop removes optimizer and frees up memory.
 func (co *COptimizer) Drop() {
 	lib.AtoFree(co.coptimizer)
@@ -3,6 +3,8 @@ package tensor
 // Other tensor methods
 
 import (
+	"reflect"
+
 	"github.com/sugarme/gotch"
 )
 
@@ -16,6 +18,37 @@ func (ts *Tensor) CrossEntropyForLogits(targets *Tensor) (retVal *Tensor) {
 	return logSm.MustNllLoss(targets, weight, reduction, ignoreIndex, true)
 }
 
+// WeightedCrossEntropyForLogits computes the cross-entropy loss based on some
+// logits and targets, with a weight for each class, e.g. obtained from
+// `nn.ComputeClassWeights` for imbalanced classes. The loss is the weighted
+// mean over targets.
+func (ts *Tensor) WeightedCrossEntropyForLogits(targets, weight *Tensor) (retVal *Tensor) {
+	reduction := int64(1) // Mean of loss
+	ignoreIndex := int64(-100)
+
+	logSm := ts.MustLogSoftmax(-1, gotch.Float, true)
+	return logSm.MustNllLoss(targets, weight, reduction, ignoreIndex, true)
+}
+
+// AllClose returns true if a and b have the same shape and all their elements
+// satisfy `|a - b| <= atol + rtol * |b|`. NaN values are never close.
+func AllClose(a, b *Tensor, rtol, atol float64) bool {
+	if !reflect.DeepEqual(a.MustSize(), b.MustSize()) {
+		return false
+	}
+
+	a64 := a.MustTotype(gotch.Double, false)
+	b64 := b.MustTotype(gotch.Double, false)
+	diff := a64.MustSub(b64, true).MustAbs(true)
+	tol := b64.MustAbs(true).MustMul1(FloatScalar(rtol), true).MustAdd1(FloatScalar(atol), true)
+	allClose := diff.MustLe1(tol, true).MustAll(true)
+	tol.MustDrop()
+	retVal := allClose.Int64Values()[0] != 0
+	allClose.MustDrop()
+
+	return retVal
+}
+
 // AccuracyForLogits returns the average accuracy for some given logits assuming that
 // targets represent ground-truth.
 func (ts *Tensor) AccuracyForLogits(targets *Tensor) (retVal *Tensor) {
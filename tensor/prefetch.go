@@ -0,0 +1,217 @@
+package tensor
+
+// Asynchronous batch prefetching.
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// PrefetchDataLoader iterates over mini-batches of a pair of tensors like
+// `Iter2`, but assembles batches in background goroutines so that data
+// preparation overlaps with training.
+//
+// Batches are returned in order whatever the number of workers, and at most
+// `bufferSize` batches are prepared ahead. Each epoch is started with
+// `StartEpoch` and ends when `Next` returns false. Shuffling is seeded so that
+// the order of batches is reproducible across runs.
+type PrefetchDataLoader struct {
+	xs                   *Tensor
+	ys                   *Tensor
+	batchSize            int64
+	totalSize            int64
+	numWorkers           int
+	bufferSize           int
+	returnSmallLastBatch bool
+	pinMemory            bool
+	rng                  *rand.Rand // nil if not shuffling
+
+	results []chan Iter2Item // one per batch of the current epoch
+	next    int              // index of the next batch to return
+	slots   chan struct{}    // batches which may be prepared ahead
+	done    chan struct{}    // closed to stop the current epoch
+	wg      sync.WaitGroup
+}
+
+// NewPrefetchDataLoader returns a new loader over mini-batches of xs and ys
+// prepared by numWorkers goroutines, with up to bufferSize batches buffered.
+// An error is returned if `xs` and `ys` have different first dimension sizes.
+func NewPrefetchDataLoader(xs, ys *Tensor, batchSize int64, numWorkers, bufferSize int) (*PrefetchDataLoader, error) {
+	totalSize := xs.MustSize()[0]
+	if ys.MustSize()[0] != totalSize {
+		err := fmt.Errorf("Different dimension for the two inputs: %v - %v", xs.MustSize(), ys.MustSize())
+		return nil, err
+	}
+	if numWorkers <= 0 || bufferSize <= 0 {
+		err := fmt.Errorf("Expected positive number of workers and buffer size, got %v and %v", numWorkers, bufferSize)
+		return nil, err
+	}
+
+	return &PrefetchDataLoader{
+		xs:         xs.MustShallowClone(),
+		ys:         ys.MustShallowClone(),
+		batchSize:  batchSize,
+		totalSize:  totalSize,
+		numWorkers: numWorkers,
+		bufferSize: bufferSize,
+	}, nil
+}
+
+// Shuffle shuffles the dataset at the start of each epoch, from a random
+// number generator seeded with seed.
+func (dl *PrefetchDataLoader) Shuffle(seed int64) *PrefetchDataLoader {
+	dl.rng = rand.New(rand.NewSource(seed))
+	return dl
+}
+
+// ReturnSmallLastBatch when set, returns the last batch even if smaller than the batch size.
+func (dl *PrefetchDataLoader) ReturnSmallLastBatch() *PrefetchDataLoader {
+	dl.returnSmallLastBatch = true
+	return dl
+}
+
+// PinMemory when set, returns mini-batches in pinned host memory, see
+// `Iter2.PinMemory`.
+func (dl *PrefetchDataLoader) PinMemory() *PrefetchDataLoader {
+	dl.pinMemory = true
+	return dl
+}
+
+// StartEpoch starts preparing the batches of a new epoch. Batches of an
+// unfinished epoch are discarded.
+func (dl *PrefetchDataLoader) StartEpoch() {
+	dl.stop()
+
+	numBatches := dl.totalSize / dl.batchSize
+	if dl.returnSmallLastBatch && dl.totalSize%dl.batchSize != 0 {
+		numBatches++
+	}
+
+	var perm []int
+	if dl.rng != nil {
+		perm = dl.rng.Perm(int(dl.totalSize))
+	}
+
+	dl.results = make([]chan Iter2Item, numBatches)
+	for i := range dl.results {
+		dl.results[i] = make(chan Iter2Item, 1)
+	}
+	dl.next = 0
+	dl.slots = make(chan struct{}, dl.bufferSize)
+	dl.done = make(chan struct{})
+
+	// Batch indices are queued in order as buffer slots free up.
+	jobs := make(chan int)
+	dl.wg.Add(1)
+	go func() {
+		defer dl.wg.Done()
+		defer close(jobs)
+		for i := range dl.results {
+			select {
+			case dl.slots <- struct{}{}:
+			case <-dl.done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-dl.done:
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < dl.numWorkers; w++ {
+		dl.wg.Add(1)
+		go func() {
+			defer dl.wg.Done()
+			// Torch errors are reported per OS thread.
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+
+			for i := range jobs {
+				dl.results[i] <- dl.batch(int64(i), perm)
+			}
+		}()
+	}
+}
+
+// batch assembles the i-th batch, from the samples of perm if shuffling.
+func (dl *PrefetchDataLoader) batch(i int64, perm []int) Iter2Item {
+	start := i * dl.batchSize
+	end := start + dl.batchSize
+	if end > dl.totalSize {
+		end = dl.totalSize
+	}
+
+	var item Iter2Item
+	if perm == nil {
+		narrowIndex := NewNarrow(start, end)
+		item = Iter2Item{
+			Data:  dl.xs.Idx(narrowIndex),
+			Label: dl.ys.Idx(narrowIndex),
+		}
+	} else {
+		indices := make([]int64, end-start)
+		for j := range indices {
+			indices[j] = int64(perm[start+int64(j)])
+		}
+		index := MustOfSlice(indices)
+		item = Iter2Item{
+			Data:  dl.xs.MustIndexSelect(0, index, false),
+			Label: dl.ys.MustIndexSelect(0, index, false),
+		}
+		index.MustDrop()
+	}
+
+	if dl.pinMemory {
+		item.Data = item.Data.MustPinMemory(true)
+		item.Label = item.Label.MustPinMemory(true)
+	}
+
+	return item
+}
+
+// Next returns the next batch of the current epoch, false once the epoch is
+// over.
+func (dl *PrefetchDataLoader) Next() (item Iter2Item, ok bool) {
+	if dl.next >= len(dl.results) {
+		return item, false
+	}
+
+	item = <-dl.results[dl.next]
+	dl.results[dl.next] = nil
+	dl.next++
+	<-dl.slots
+
+	return item, true
+}
+
+// stop stops the workers of the current epoch and deletes the batches they
+// prepared but were not returned.
+func (dl *PrefetchDataLoader) stop() {
+	if dl.done == nil {
+		return
+	}
+
+	close(dl.done)
+	dl.wg.Wait()
+	for _, results := range dl.results[dl.next:] {
+		select {
+		case item := <-results:
+			item.Data.MustDrop()
+			item.Label.MustDrop()
+		default:
+		}
+	}
+	dl.results = nil
+	dl.done = nil
+}
+
+// Drop stops the workers and deletes the dataset.
+func (dl *PrefetchDataLoader) Drop() {
+	dl.stop()
+	dl.xs.MustDrop()
+	dl.ys.MustDrop()
+}
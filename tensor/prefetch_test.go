@@ -0,0 +1,80 @@
+package tensor_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sugarme/gotch"
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+// epochLabels returns the labels of each batch of an epoch.
+func epochLabels(dl *ts.PrefetchDataLoader) [][]float64 {
+	var labels [][]float64
+	dl.StartEpoch()
+	for {
+		item, ok := dl.Next()
+		if !ok {
+			break
+		}
+		labels = append(labels, item.Label.Float64Values())
+		item.Data.MustDrop()
+		item.Label.MustDrop()
+	}
+
+	return labels
+}
+
+func TestPrefetchDataLoader(t *testing.T) {
+	xs := ts.MustArange(ts.IntScalar(40), gotch.Float, gotch.CPU).MustView([]int64{20, 2}, true)
+	ys := ts.MustArange(ts.IntScalar(20), gotch.Int64, gotch.CPU)
+
+	// Without shuffling, batches match the synchronous iterator.
+	iter := ts.MustNewIter2(xs, ys, 3).ReturnSmallLastBatch()
+	dl, err := ts.NewPrefetchDataLoader(xs, ys, 3, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl.ReturnSmallLastBatch()
+	dl.StartEpoch()
+	for {
+		want, wantOk := iter.Next()
+		got, gotOk := dl.Next()
+		if wantOk != gotOk {
+			t.Fatalf("Expected epoch end %v, got %v\n", !wantOk, !gotOk)
+		}
+		if !wantOk {
+			break
+		}
+		if !reflect.DeepEqual(want.Data.Float64Values(), got.Data.Float64Values()) || !reflect.DeepEqual(want.Label.Float64Values(), got.Label.Float64Values()) {
+			t.Errorf("Expected batch %v, got %v\n", want.Label.Float64Values(), got.Label.Float64Values())
+		}
+	}
+	dl.Drop()
+
+	// With shuffling, the order of batches only depends on the seed and each
+	// epoch covers the whole dataset.
+	dl1, _ := ts.NewPrefetchDataLoader(xs, ys, 4, 3, 2)
+	dl2, _ := ts.NewPrefetchDataLoader(xs, ys, 4, 1, 1)
+	dl1.Shuffle(42)
+	dl2.Shuffle(42)
+	for epoch := 0; epoch < 2; epoch++ {
+		labels1 := epochLabels(dl1)
+		labels2 := epochLabels(dl2)
+		if !reflect.DeepEqual(labels1, labels2) {
+			t.Errorf("Epoch %v - Expected the same order for the same seed, got %v and %v\n", epoch, labels1, labels2)
+		}
+
+		var all []float64
+		for _, batch := range labels1 {
+			all = append(all, batch...)
+		}
+		sort.Float64s(all)
+		if want := ys.Float64Values(); !reflect.DeepEqual(want, all) {
+			t.Errorf("Epoch %v - Expected every sample once, got %v\n", epoch, all)
+		}
+	}
+	dl1.Drop()
+	dl2.Drop()
+}
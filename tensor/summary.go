@@ -0,0 +1,129 @@
+package tensor
+
+// Summary statistics to debug tensor values.
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// TensorSummary holds summary statistics of tensor values.
+//
+// Min, Max, Mean and Std are computed over finite values only. They are NaN
+// if the tensor has no finite value.
+type TensorSummary struct {
+	Numel       int64
+	Min         float64
+	Max         float64
+	Mean        float64
+	Std         float64 // unbiased (n - 1) standard deviation
+	NaNFraction float64 // fraction of NaN values
+	InfFraction float64 // fraction of +/-Inf values
+}
+
+// String implements fmt.Stringer interface for TensorSummary.
+func (s TensorSummary) String() string {
+	return fmt.Sprintf("numel: %v, min: %g, max: %g, mean: %g, std: %g, nan: %.2f%%, inf: %.2f%%",
+		s.Numel, s.Min, s.Max, s.Mean, s.Std, 100*s.NaNFraction, 100*s.InfFraction)
+}
+
+// Summary computes summary statistics of the tensor values, e.g. to
+// diagnose exploding activations.
+func (ts *Tensor) Summary() TensorSummary {
+	values := ts.Float64Values()
+
+	summary := TensorSummary{
+		Numel: int64(len(values)),
+		Min:   math.NaN(),
+		Max:   math.NaN(),
+		Mean:  math.NaN(),
+		Std:   math.NaN(),
+	}
+
+	var (
+		nans, infs int
+		finite     []float64
+		sum        float64
+	)
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			nans++
+		case math.IsInf(v, 0):
+			infs++
+		default:
+			finite = append(finite, v)
+			sum += v
+		}
+	}
+
+	if len(values) > 0 {
+		summary.NaNFraction = float64(nans) / float64(len(values))
+		summary.InfFraction = float64(infs) / float64(len(values))
+	}
+
+	if len(finite) == 0 {
+		return summary
+	}
+
+	summary.Min, summary.Max = finite[0], finite[0]
+	for _, v := range finite {
+		summary.Min = math.Min(summary.Min, v)
+		summary.Max = math.Max(summary.Max, v)
+	}
+
+	summary.Mean = sum / float64(len(finite))
+	if len(finite) > 1 {
+		var sq float64
+		for _, v := range finite {
+			sq += (v - summary.Mean) * (v - summary.Mean)
+		}
+		summary.Std = math.Sqrt(sq / float64(len(finite)-1))
+	}
+
+	return summary
+}
+
+// Histogram counts finite tensor values in bins of equal width between their
+// min and max. It returns the counts and the bins+1 bin edges. The last bin
+// includes the max value.
+//
+// NOTE. If all values are equal, bins are spread over [value - 1, value + 1].
+func (ts *Tensor) Histogram(bins int64) (counts []int64, edges []float64) {
+	if bins < 1 {
+		log.Fatalf("Histogram - Expected at least one bin, got %v\n", bins)
+	}
+
+	counts = make([]int64, bins)
+	edges = make([]float64, bins+1)
+
+	summary := ts.Summary()
+	min, max := summary.Min, summary.Max
+	if math.IsNaN(min) {
+		return counts, edges
+	}
+	if min == max {
+		min, max = min-1, max+1
+	}
+
+	width := (max - min) / float64(bins)
+	for i := range edges {
+		edges[i] = min + float64(i)*width
+	}
+	edges[bins] = max
+
+	for _, v := range ts.Float64Values() {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+
+		bin := int64((v - min) / width)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		counts[bin]++
+	}
+
+	return counts, edges
+}
@@ -0,0 +1,45 @@
+package tensor_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	ts "github.com/sugarme/gotch/tensor"
+)
+
+func TestTensor_Summary(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, math.NaN(), math.Inf(1), math.Inf(-1)}
+	tensor := ts.MustOfSlice(data)
+
+	s := tensor.Summary()
+
+	if s.Numel != 8 {
+		t.Errorf("Expected numel 8, got %v\n", s.Numel)
+	}
+	if s.Min != 1 || s.Max != 5 || s.Mean != 3 {
+		t.Errorf("Expected min 1, max 5, mean 3, got %v, %v, %v\n", s.Min, s.Max, s.Mean)
+	}
+	if want := math.Sqrt(2.5); math.Abs(s.Std-want) > 1e-12 {
+		t.Errorf("Expected std %v, got %v\n", want, s.Std)
+	}
+	if s.NaNFraction != 0.125 || s.InfFraction != 0.25 {
+		t.Errorf("Expected NaN fraction 0.125 and Inf fraction 0.25, got %v, %v\n", s.NaNFraction, s.InfFraction)
+	}
+}
+
+func TestTensor_Histogram(t *testing.T) {
+	tensor := ts.MustOfSlice([]float32{0, 0.5, 1, 1.5, 2, 2.5, 3, 4, float32(math.NaN())})
+
+	counts, edges := tensor.Histogram(4)
+
+	wantCounts := []int64{2, 2, 2, 2}
+	if !reflect.DeepEqual(wantCounts, counts) {
+		t.Errorf("Expected counts %v, got %v\n", wantCounts, counts)
+	}
+
+	wantEdges := []float64{0, 1, 2, 3, 4}
+	if !reflect.DeepEqual(wantEdges, edges) {
+		t.Errorf("Expected edges %v, got %v\n", wantEdges, edges)
+	}
+}
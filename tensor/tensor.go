@@ -457,6 +457,29 @@ func (ts *Tensor) IsSparse() (bool, error) {
 	return state, nil
 }
 
+// IsPinned returns true if the tensor is in pinned (page-locked) host
+// memory, see `PinMemory`.
+func (ts *Tensor) IsPinned() (bool, error) {
+	state := lib.AtIsPinned(ts.ctensor)
+
+	if err := TorchErr(); err != nil {
+		return false, err
+	}
+
+	return state, nil
+}
+
+// MustIsPinned returns true if the tensor is in pinned host memory. It
+// panics if error.
+func (ts *Tensor) MustIsPinned() bool {
+	state, err := ts.IsPinned()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return state
+}
+
 // ZeroGrad zeroes the gradient tensor attached to this tensor if defined.
 func (ts *Tensor) ZeroGrad() {
 	grad := ts.MustGrad(false)
@@ -713,6 +736,22 @@ func (ts *Tensor) Copy_(src *Tensor) {
 	}
 }
 
+// SetData replaces the data of the tensor with the one of the argument tensor,
+// which can have a different dtype or device. Other tensors sharing this
+// tensor (e.g. copies of this Tensor struct) see the change.
+func (ts *Tensor) SetData(data *Tensor) error {
+	lib.AtSetData(ts.ctensor, data.ctensor)
+
+	return TorchErr()
+}
+
+// MustSetData replaces the data of the tensor. It will panic if error
+func (ts *Tensor) MustSetData(data *Tensor) {
+	if err := ts.SetData(data); err != nil {
+		log.Fatal(err)
+	}
+}
+
 // Save saves a tensor to a file.
 func (ts *Tensor) Save(path string) error {
 
@@ -949,7 +988,16 @@ func MustGradSetEnabled(b bool) bool {
 	return state
 }
 
+// ManualSeed sets the seed of the libtorch random number generator.
+func ManualSeed(seed int64) {
+	lib.AtManualSeed(seed)
+}
+
 // NoGrad runs a closure without keeping track of gradients.
+//
+// Tensors computed inside the closure do not require gradients and no
+// autograd graph is recorded, which saves memory and time for inference. The
+// previous gradient mode is restored when the closure returns or panics.
 func NoGrad(fn interface{}) {
 
 	// TODO: This is weird but somehow we need to trigger C++ print
@@ -962,6 +1010,7 @@ func NoGrad(fn interface{}) {
 
 	// Switch off Grad
 	prev := MustGradSetEnabled(false)
+	defer MustGradSetEnabled(prev)
 
 	// Analyze input as function. If not, throw error
 	f, err := NewFunc(fn)
@@ -971,10 +1020,6 @@ func NoGrad(fn interface{}) {
 
 	// invokes the function
 	f.Invoke()
-
-	// Switch on Grad
-	_ = MustGradSetEnabled(prev)
-
 }
 
 func NoGrad1(fn func() interface{}) interface{} {
@@ -983,13 +1028,9 @@ func NoGrad1(fn func() interface{}) interface{} {
 
 	// Switch off Grad
 	prev := MustGradSetEnabled(false)
+	defer MustGradSetEnabled(prev)
 
-	retVal := fn()
-
-	// Switch on Grad
-	_ = MustGradSetEnabled(prev)
-
-	return retVal
+	return fn()
 }
 
 // NoGradGuard is a RAII guard that prevents gradient tracking until deallocated.
@@ -1193,3 +1234,25 @@ func SaveMultiNew(namedTensors []NamedTensor, path string) error {
 
 	return nil
 }
+
+// SavePickle saves a slice of named tensors to the given file path as a
+// dictionary in the pickle format of PyTorch, which can be read back in Python
+// with `torch.load`.
+func SavePickle(namedTensors []NamedTensor, path string) error {
+	var (
+		tensors []lib.Ctensor
+		names   []string
+	)
+
+	for _, nts := range namedTensors {
+		tensors = append(tensors, nts.Tensor.ctensor)
+		names = append(names, nts.Name)
+	}
+
+	lib.AtSavePickle(tensors, names, path)
+	if err := TorchErr(); err != nil {
+		return err
+	}
+
+	return nil
+}